@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/kenziehh/cashflow-be/config"
 	"github.com/kenziehh/cashflow-be/database/seed"
@@ -14,19 +16,36 @@ import (
 	transactionHandler "github.com/kenziehh/cashflow-be/internal/domain/transaction/handler/http"
 	transactionRepo "github.com/kenziehh/cashflow-be/internal/domain/transaction/repository"
 	transactionService "github.com/kenziehh/cashflow-be/internal/domain/transaction/service"
+	"github.com/kenziehh/cashflow-be/internal/infra/mailer"
+	"github.com/kenziehh/cashflow-be/internal/infra/notifier"
 	"github.com/kenziehh/cashflow-be/internal/infra/postgres"
 	"github.com/kenziehh/cashflow-be/internal/infra/redis"
+	"github.com/kenziehh/cashflow-be/internal/infra/scheduler"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/swagger"
+	accountHandler "github.com/kenziehh/cashflow-be/internal/domain/account/handler/http"
+	accountRepo "github.com/kenziehh/cashflow-be/internal/domain/account/repository"
+	accountService "github.com/kenziehh/cashflow-be/internal/domain/account/service"
+	apiKeyHandler "github.com/kenziehh/cashflow-be/internal/domain/api_key/handler/http"
+	apiKeyRepo "github.com/kenziehh/cashflow-be/internal/domain/api_key/repository"
+	apiKeyService "github.com/kenziehh/cashflow-be/internal/domain/api_key/service"
+	auditHandler "github.com/kenziehh/cashflow-be/internal/domain/audit/handler/http"
+	auditRepo "github.com/kenziehh/cashflow-be/internal/domain/audit/repository"
+	auditService "github.com/kenziehh/cashflow-be/internal/domain/audit/service"
 	categoryHandler "github.com/kenziehh/cashflow-be/internal/domain/category/handler/http"
 	categoryRepo "github.com/kenziehh/cashflow-be/internal/domain/category/repository"
 	categoryService "github.com/kenziehh/cashflow-be/internal/domain/category/service"
 	maximumSpendHandler "github.com/kenziehh/cashflow-be/internal/domain/maximum_spend/handler/http"
 	maximumSpendRepo "github.com/kenziehh/cashflow-be/internal/domain/maximum_spend/repository"
 	maximumSpendService "github.com/kenziehh/cashflow-be/internal/domain/maximum_spend/service"
+	recurringTransactionHandler "github.com/kenziehh/cashflow-be/internal/domain/recurring_transaction/handler/http"
+	recurringTransactionRepo "github.com/kenziehh/cashflow-be/internal/domain/recurring_transaction/repository"
+	recurringTransactionService "github.com/kenziehh/cashflow-be/internal/domain/recurring_transaction/service"
 	"github.com/kenziehh/cashflow-be/internal/middleware"
+	"github.com/kenziehh/cashflow-be/pkg/jwt"
+	"github.com/kenziehh/cashflow-be/pkg/rbac"
 )
 
 // @title Cash Flow API
@@ -41,6 +60,16 @@ func main() {
 	// Load config
 	cfg := config.LoadConfig()
 
+	// Initialize JWT signing keys
+	if cfg.JWTSecret == "" && cfg.JWTKeys == "" {
+		log.Fatal("❌ No JWT signing key configured, set JWT_SECRET or JWT_KEYS")
+	}
+	keyring, err := jwt.LoadKeyring(cfg.JWTSecret, cfg.JWTActiveKeyID, cfg.JWTKeys)
+	if err != nil {
+		log.Fatal("❌ Failed to load JWT keys:", err)
+	}
+	jwt.SetKeyring(keyring)
+
 	// Initialize database
 	db := postgres.InitDB(cfg)
 	defer db.Close()
@@ -49,11 +78,18 @@ func main() {
 	if err := seed.SeedCategoriesIfEmpty(db); err != nil {
 		log.Fatal("❌ Seeder failed:", err)
 	}
+	if err := seed.PromoteAdmins(db, cfg.AdminEmails); err != nil {
+		log.Fatal("❌ Seeder failed:", err)
+	}
 
 	// Initialize Redis
 	redis := redis.InitRedis(cfg)
 	defer redis.Close()
 
+	// Initialize mailer
+	mailer := mailer.InitMailer(cfg)
+	notifier := notifier.InitNotifier(cfg, mailer)
+
 	// Initialize Fiber
 	app := fiber.New(fiber.Config{
 		ErrorHandler: middleware.ErrorHandler,
@@ -61,6 +97,7 @@ func main() {
 
 	// Middleware
 	app.Use(middleware.Logger())
+	app.Use(middleware.AuditContext())
 
 	allowedOrigins := strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",")
 	app.Use(cors.New(cors.Config{
@@ -75,6 +112,7 @@ func main() {
 		AllowCredentials: true,
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
 		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		ExposeHeaders:    "Retry-After",
 	}))
 
 	
@@ -82,26 +120,103 @@ func main() {
 	// Swagger
 	app.Get("/docs/*", swagger.HandlerDefault)
 
+	// Public signing keys for services that verify our tokens
+	app.Get("/.well-known/jwks.json", func(c *fiber.Ctx) error {
+		return c.JSON(jwt.PublicJWKS())
+	})
+
 	// Routes
 	api := app.Group("/api/v1")
 
+	auditRepository := auditRepo.NewAuditRepository(db, redis)
+	auditSvc := auditService.NewAuditService(auditRepository)
+	auditHandler := auditHandler.NewAuditHandler(auditSvc)
+	app.Use(middleware.AuditImpersonation(auditSvc))
+
+	// New users get a default account, so accounts come before auth
+	accountRepository := accountRepo.NewAccountRepository(db, redis)
+	accountSvc := accountService.NewAccountService(accountRepository, auditSvc)
+	accountHandler := accountHandler.NewAccountHandler(accountSvc)
+
+	// Erasing an account removes its proof files, and signing out everywhere
+	// revokes its API keys, so transactions and API keys come before auth too
+	transactionRepository := transactionRepo.NewTransactionRepository(db, redis)
+	transactionSvc := transactionService.NewTransactionService(transactionRepository, accountSvc, auditSvc)
+	transactionHandler := transactionHandler.NewTransactionHandler(transactionSvc)
+
+	apiKeyRepository := apiKeyRepo.NewAPIKeyRepository(db, redis)
+	apiKeySvc := apiKeyService.NewAPIKeyService(apiKeyRepository, auditSvc)
+	apiKeyHandler := apiKeyHandler.NewAPIKeyHandler(apiKeySvc)
+
 	// Auth routes
 	authRepository := authRepo.NewAuthRepository(db, redis)
-	authSvc := authService.NewAuthService(authRepository)
+	authSvc := authService.NewAuthService(authRepository, accountSvc, apiKeySvc, transactionSvc, mailer, cfg, auditSvc, notifier)
 	authHandler := http.NewAuthHandler(authSvc)
 
+	// jwtAuth only accepts session tokens; apiAuth also accepts API keys and
+	// must be paired with RequireScope.
+	jwtAuth := middleware.JWTAuth(authRepository, nil)
+	apiAuth := middleware.JWTAuth(authRepository, apiKeySvc)
+	emailVerified := middleware.EmailVerificationPolicy(cfg.UnverifiedAccess)
+
 	auth := api.Group("/auth")
 	auth.Post("/register", authHandler.Register)
 	auth.Post("/login", authHandler.Login)
-	auth.Post("/logout", middleware.JWTAuth(), authHandler.Logout)
-	auth.Get("/me", middleware.JWTAuth(), authHandler.GetProfile)
+	auth.Post("/login/mfa", authHandler.LoginMFA)
+	auth.Post("/login/mfa/passkey/begin", authHandler.BeginPasskeyMFA)
+	auth.Post("/login/mfa/passkey/finish", authHandler.FinishPasskeyMFA)
+	auth.Post("/passkeys/login/begin", authHandler.BeginPasskeyLogin)
+	auth.Post("/passkeys/login/finish", authHandler.FinishPasskeyLogin)
+	auth.Post("/magic-link", authHandler.RequestMagicLink)
+	auth.Post("/magic-link/verify", authHandler.LoginMagicLink)
+	auth.Get("/oidc/authorize", authHandler.OIDCAuthorize)
+	auth.Post("/oidc/callback", authHandler.OIDCCallback)
+	auth.Post("/refresh", authHandler.Refresh)
+	auth.Post("/verify-email", authHandler.VerifyEmail)
+	auth.Post("/verify-email/resend", jwtAuth, authHandler.ResendVerificationEmail)
+	auth.Post("/password/forgot", authHandler.ForgotPassword)
+	auth.Post("/password/reset", authHandler.ResetPassword)
+	auth.Post("/logout", jwtAuth, authHandler.Logout)
+	auth.Post("/logout-all", jwtAuth, authHandler.LogoutAll)
+	auth.Get("/me", jwtAuth, authHandler.GetProfile)
+	auth.Put("/me", jwtAuth, authHandler.UpdateProfile)
+	auth.Delete("/me", jwtAuth, authHandler.DeleteAccount)
+	auth.Delete("/me/deletion", jwtAuth, authHandler.CancelAccountDeletion)
+	auth.Put("/me/password", jwtAuth, authHandler.ChangePassword)
+	auth.Get("/me/logins", jwtAuth, authHandler.ListLoginHistory)
+	auth.Post("/mfa/totp/enroll", jwtAuth, authHandler.EnrollTOTP)
+	auth.Post("/mfa/totp/confirm", jwtAuth, authHandler.ConfirmTOTP)
+	auth.Post("/mfa/totp/disable", jwtAuth, authHandler.DisableTOTP)
+	auth.Post("/passkeys/register/begin", jwtAuth, authHandler.BeginPasskeyRegistration)
+	auth.Post("/passkeys/register/finish", jwtAuth, authHandler.FinishPasskeyRegistration)
+	auth.Get("/passkeys", jwtAuth, authHandler.ListPasskeys)
+	auth.Delete("/passkeys/:id", jwtAuth, authHandler.DeletePasskey)
+	auth.Get("/sessions", jwtAuth, authHandler.ListSessions)
+	auth.Delete("/sessions/:id", jwtAuth, authHandler.RevokeSession)
 
-	transactionRepository := transactionRepo.NewTransactionRepository(db, redis)
-	transactionSvc := transactionService.NewTransactionService(transactionRepository)
-	transactionHandler := transactionHandler.NewTransactionHandler(transactionSvc)
+	admin := api.Group("/admin", jwtAuth, middleware.RequireRole(rbac.RoleAdmin))
+	admin.Post("/auth/unlock", middleware.RequirePermission(rbac.PermUnlockAccounts), authHandler.UnlockAccount)
+	admin.Put("/users/:id/role", middleware.RequirePermission(rbac.PermManageUsers), authHandler.UpdateUserRole)
+	admin.Post("/users/:id/impersonate", middleware.RequirePermission(rbac.PermImpersonateUsers), authHandler.Impersonate)
+	admin.Get("/audit-logs/verify", middleware.RequirePermission(rbac.PermViewAuditLogs), auditHandler.VerifyAuditLogs)
+
+	api.Get("/audit-logs", jwtAuth, auditHandler.ListAuditLogs)
 
-	transactions := api.Group("/transactions", middleware.JWTAuth())
+	apiKeys := api.Group("/api-keys", jwtAuth, emailVerified)
+	apiKeys.Post("/", apiKeyHandler.CreateAPIKey)
+	apiKeys.Get("/", apiKeyHandler.ListAPIKeys)
+	apiKeys.Delete("/:id", apiKeyHandler.RevokeAPIKey)
+
+	accounts := api.Group("/accounts", apiAuth, emailVerified, middleware.RequireScope(rbac.ScopeAccountsRead, rbac.ScopeAccountsWrite))
+	accounts.Post("/", accountHandler.CreateAccount)
+	accounts.Get("/", accountHandler.ListAccounts)
+	accounts.Get("/:id", accountHandler.GetAccountByID)
+	accounts.Put("/:id", accountHandler.UpdateAccount)
+	accounts.Delete("/:id", accountHandler.DeleteAccount)
+
+	transactions := api.Group("/transactions", apiAuth, emailVerified, middleware.RequireScope(rbac.ScopeTransactionsRead, rbac.ScopeTransactionsWrite))
 	transactions.Post("/", transactionHandler.CreateTransaction)
+	transactions.Post("/transfers", transactionHandler.CreateTransfer)
 	transactions.Get("/summary", transactionHandler.GetSummaryTransaction)
 	transactions.Get("/:id", transactionHandler.GetTransactionByID)
 	transactions.Get("/:id/proof", transactionHandler.GetProofFile)
@@ -109,21 +224,60 @@ func main() {
 	transactions.Put("/:id", transactionHandler.UpdateTransaction)
 	transactions.Delete("/:id", transactionHandler.DeleteTransaction)
 
+	recurringTransactionRepository := recurringTransactionRepo.NewRecurringTransactionRepository(db, redis)
+	recurringTransactionSvc := recurringTransactionService.NewRecurringTransactionService(recurringTransactionRepository, accountSvc, auditSvc)
+	recurringTransactionHandler := recurringTransactionHandler.NewRecurringTransactionHandler(recurringTransactionSvc)
+
+	recurringTransactions := api.Group("/recurring-transactions", apiAuth, emailVerified, middleware.RequireScope(rbac.ScopeTransactionsRead, rbac.ScopeTransactionsWrite))
+	recurringTransactions.Post("/", recurringTransactionHandler.CreateRecurringTransaction)
+	recurringTransactions.Get("/", recurringTransactionHandler.ListRecurringTransactions)
+	recurringTransactions.Get("/:id", recurringTransactionHandler.GetRecurringTransactionByID)
+	recurringTransactions.Put("/:id", recurringTransactionHandler.UpdateRecurringTransaction)
+	recurringTransactions.Post("/:id/pause", recurringTransactionHandler.PauseRecurringTransaction)
+	recurringTransactions.Post("/:id/resume", recurringTransactionHandler.ResumeRecurringTransaction)
+	recurringTransactions.Post("/:id/skip", recurringTransactionHandler.SkipNextOccurrence)
+	recurringTransactions.Delete("/:id", recurringTransactionHandler.DeleteRecurringTransaction)
+
+	reports := api.Group("/reports", apiAuth, emailVerified, middleware.RequireScope(rbac.ScopeReportsRead, ""))
+	reports.Get("/summary", transactionHandler.GetSummaryTransaction)
+	reports.Get("/categories", transactionHandler.GetCategoryBreakdown)
+
 	categoryRepository := categoryRepo.NewCategoryRepository(db, redis)
 	categorySvc := categoryService.NewCategoryService(categoryRepository)
 	categoryHandler := categoryHandler.NewCategoryHandler(categorySvc)
 
-	categories := api.Group("/categories", middleware.JWTAuth())
+	categories := api.Group("/categories", apiAuth, emailVerified, middleware.RequireScope(rbac.ScopeCategoriesRead, ""))
 	categories.Get("/", categoryHandler.GetAllCategories)
+	categories.Post("/", middleware.RequirePermission(rbac.PermManageCategories), categoryHandler.CreateCategory)
+	categories.Put("/:id", middleware.RequirePermission(rbac.PermManageCategories), categoryHandler.UpdateCategory)
+	categories.Delete("/:id", middleware.RequirePermission(rbac.PermManageCategories), categoryHandler.DeleteCategory)
 
 	maximumSpendRepository := maximumSpendRepo.NewMaximumSpendRepository(db, redis)
-	maximumSpendSvc := maximumSpendService.NewMaximumSpendService(maximumSpendRepository)
+	maximumSpendSvc := maximumSpendService.NewMaximumSpendService(maximumSpendRepository, auditSvc)
 	maximumSpendHandler := maximumSpendHandler.NewMaximumSpendHandler(maximumSpendSvc)
 
-	maximumSpends := api.Group("/maximum-spends", middleware.JWTAuth())
+	maximumSpends := api.Group("/maximum-spends", apiAuth, emailVerified, middleware.RequireScope(rbac.ScopeLimitsRead, rbac.ScopeLimitsWrite))
 	maximumSpends.Post("/", maximumSpendHandler.SetMaximumSpend)
 	maximumSpends.Get("/", maximumSpendHandler.GetMaximumSpend)
 
+	// Erase accounts whose deletion grace period is over
+	go scheduler.Every(context.Background(), "account purge", time.Hour, func(ctx context.Context) error {
+		deleted, err := authSvc.PurgeDeletedAccounts(ctx)
+		if deleted > 0 {
+			log.Printf("Deleted %d account(s)", deleted)
+		}
+		return err
+	})
+
+	// Create the occurrences of recurring transactions as they fall due
+	go scheduler.Every(context.Background(), "recurring transactions", 15*time.Minute, func(ctx context.Context) error {
+		created, err := recurringTransactionSvc.MaterializeDue(ctx)
+		if created > 0 {
+			log.Printf("Created %d recurring transaction occurrence(s)", created)
+		}
+		return err
+	})
+
 	// Start server
 	port := os.Getenv("APP_PORT")
 	if port == "" {
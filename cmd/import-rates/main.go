@@ -0,0 +1,67 @@
+// Command import-rates loads exchange rates from a file into the
+// exchange_rates table. It talks to the database directly, so rates can be
+// loaded offline and before the API is started:
+//
+//	go run ./cmd/import-rates -file eurofxref-hist.xml
+//	go run ./cmd/import-rates -file rates.csv -source bank
+//
+// Files ending in .xml are read as the ECB reference rate feed, anything else
+// as CSV (see exchange rate service ParseCSV for the accepted layouts).
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenziehh/cashflow-be/config"
+	exchangeRateRepo "github.com/kenziehh/cashflow-be/internal/domain/exchange_rate/repository"
+	exchangeRateService "github.com/kenziehh/cashflow-be/internal/domain/exchange_rate/service"
+	"github.com/kenziehh/cashflow-be/internal/infra/postgres"
+)
+
+func main() {
+	file := flag.String("file", "", "rates file to import (required)")
+	format := flag.String("format", "", `"csv" or "ecb-xml", guessed from the file extension when empty`)
+	source := flag.String("source", "", "where the rates come from, stored with each rate (default: ecb for XML files, the file name otherwise)")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *format == "" {
+		*format = exchangeRateService.FormatCSV
+		if strings.EqualFold(filepath.Ext(*file), ".xml") {
+			*format = exchangeRateService.FormatECBXML
+		}
+	}
+	if *source == "" {
+		*source = filepath.Base(*file)
+		if *format == exchangeRateService.FormatECBXML {
+			*source = "ecb"
+		}
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal("❌ ", err)
+	}
+	defer f.Close()
+
+	cfg := config.LoadConfig()
+	db := postgres.InitDB(cfg)
+	defer db.Close()
+
+	svc := exchangeRateService.NewExchangeRateService(exchangeRateRepo.NewExchangeRateRepository(db, nil))
+	count, err := svc.Import(context.Background(), f, *format, *source)
+	if err != nil {
+		log.Fatal("❌ Import failed: ", err)
+	}
+
+	log.Printf("✅ Imported %d exchange rates from %s", count, *file)
+}
@@ -0,0 +1,26 @@
+// Command mock-oidc is a throwaway OpenID Connect provider for local
+// development. It approves every authorization request without a login page:
+// the signed-in email comes from the login_hint parameter.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/kenziehh/cashflow-be/pkg/oidc/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL, must match OIDC_ISSUER")
+	flag.Parse()
+
+	provider, err := oidctest.NewProvider(*issuer)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Mock OIDC provider %s listening on %s", provider.Issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, provider))
+}
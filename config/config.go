@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
 
+	"github.com/kenziehh/cashflow-be/pkg/jwt"
 	_ "github.com/lib/pq"
 )
 
@@ -14,21 +16,100 @@ type Config struct {
 	DBName     string
 	RedisHost  string
 	RedisPort  string
-	JWTSecret  string
-	AppPort    string
+	// JWTSecret is the HS256 key jwt.DefaultKeyID and has no default; leave
+	// it empty when only JWTKeys are used.
+	JWTSecret string
+	// JWTActiveKeyID is the kid used to sign new tokens; JWTKeys lists extra
+	// keys as "kid:alg:pem-path" (see jwt.LoadKeyring).
+	JWTActiveKeyID string
+	JWTKeys        string
+	AppPort        string
+	FrontendURL    string
+	MailDriver     string
+	MailFrom       string
+	MailLogFile    string
+	// NotifierDriver delivers security notices such as new-device alerts:
+	// "mail" or "log"
+	NotifierDriver string
+	SMTPHost       string
+	SMTPPort       string
+	SMTPUsername   string
+	SMTPPassword   string
+	// UnverifiedAccess limits accounts that have not verified their email:
+	// "full", "read_only" or "none".
+	UnverifiedAccess string
+	// AdminEmails are promoted to the admin role at startup
+	AdminEmails string
+	// OIDCIssuer enables "Sign in with OpenID Connect" when set
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	// OIDCScopes is a space separated scope list, openid is always included
+	OIDCScopes string
+	// WebAuthnRPID is the passkey relying party ID (the site's domain);
+	// WebAuthnOrigins is a comma separated list and defaults to FrontendURL.
+	WebAuthnRPID    string
+	WebAuthnRPName  string
+	WebAuthnOrigins string
+	// Argon2 cost of new password hashes, memory is in KiB. Existing hashes
+	// are upgraded on the next successful login.
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	PasswordMinLength int
+	PasswordMaxLength int
+	// BreachedPasswordsFile is an optional list of SHA-1 hashes (Have I Been
+	// Pwned format) checked in addition to the built-in common passwords
+	BreachedPasswordsFile string
+	// AccountDeletionGraceDays is how long a closed account can still be
+	// restored before it is erased
+	AccountDeletionGraceDays int
 }
 
 func LoadConfig() *Config {
+	frontendURL := getEnv("FRONTEND_URL", "http://localhost:3000")
+
 	return &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5433"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "cashflow_be"),
-		RedisHost:  getEnv("REDIS_HOST", "localhost"),
-		RedisPort:  getEnv("REDIS_PORT", "6379"),
-		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
-		AppPort:    getEnv("APP_PORT", "8081"),
+		DBHost:           getEnv("DB_HOST", "localhost"),
+		DBPort:           getEnv("DB_PORT", "5433"),
+		DBUser:           getEnv("DB_USER", "postgres"),
+		DBPassword:       getEnv("DB_PASSWORD", "postgres"),
+		DBName:           getEnv("DB_NAME", "cashflow_be"),
+		RedisHost:        getEnv("REDIS_HOST", "localhost"),
+		RedisPort:        getEnv("REDIS_PORT", "6379"),
+		JWTSecret:        getEnv("JWT_SECRET", ""),
+		JWTActiveKeyID:   getEnv("JWT_ACTIVE_KEY_ID", jwt.DefaultKeyID),
+		JWTKeys:          getEnv("JWT_KEYS", ""),
+		AppPort:          getEnv("APP_PORT", "8081"),
+		FrontendURL:      frontendURL,
+		MailDriver:       getEnv("MAIL_DRIVER", "log"),
+		MailFrom:         getEnv("MAIL_FROM", "no-reply@cashflow.local"),
+		MailLogFile:      getEnv("MAIL_LOG_FILE", ""),
+		NotifierDriver:   getEnv("NOTIFIER_DRIVER", "log"),
+		SMTPHost:         getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:         getEnv("SMTP_PORT", "587"),
+		SMTPUsername:     getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:     getEnv("SMTP_PASSWORD", ""),
+		UnverifiedAccess: getEnv("UNVERIFIED_ACCESS", "read_only"),
+		AdminEmails:      getEnv("ADMIN_EMAILS", ""),
+		OIDCIssuer:       getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", "http://localhost:3000/auth/oidc/callback"),
+		OIDCScopes:       getEnv("OIDC_SCOPES", "openid email profile"),
+		WebAuthnRPID:     getEnv("WEBAUTHN_RP_ID", "localhost"),
+		WebAuthnRPName:   getEnv("WEBAUTHN_RP_NAME", "Cashflow"),
+		WebAuthnOrigins:  getEnv("WEBAUTHN_ORIGINS", frontendURL),
+
+		Argon2Memory:          uint32(getEnvInt("ARGON2_MEMORY_KIB", 19*1024)),
+		Argon2Iterations:      uint32(getEnvInt("ARGON2_ITERATIONS", 2)),
+		Argon2Parallelism:     uint8(getEnvInt("ARGON2_PARALLELISM", 1)),
+		PasswordMinLength:     getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:     getEnvInt("PASSWORD_MAX_LENGTH", 128),
+		BreachedPasswordsFile: getEnv("BREACHED_PASSWORDS_FILE", ""),
+
+		AccountDeletionGraceDays: getEnvInt("ACCOUNT_DELETION_GRACE_DAYS", 14),
 	}
 }
 
@@ -38,3 +119,10 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,14 @@
+-- Users who only sign in through an OpenID Connect provider have no local password
+ALTER TABLE users ALTER COLUMN password DROP NOT NULL;
+
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user_identities_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT uq_user_identities_subject UNIQUE (issuer, subject),
+    CONSTRAINT uq_user_identities_user_issuer UNIQUE (user_id, issuer)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT 'id-ID';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS week_start VARCHAR(10) NOT NULL DEFAULT 'monday';
+
+ALTER TABLE users ADD CONSTRAINT chk_users_week_start CHECK (week_start IN ('monday', 'sunday', 'saturday'));
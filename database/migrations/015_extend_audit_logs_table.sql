@@ -0,0 +1,23 @@
+-- Entries must outlive the user they describe, otherwise deleting an account
+-- would cascade into the log and break the hash chain
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS fk_audit_logs_user;
+ALTER TABLE audit_logs ALTER COLUMN user_id DROP NOT NULL;
+
+ALTER TABLE audit_logs
+    ADD COLUMN IF NOT EXISTS seq BIGSERIAL,
+    ADD COLUMN IF NOT EXISTS actor_id UUID,
+    ADD COLUMN IF NOT EXISTS ip VARCHAR(45),
+    ADD COLUMN IF NOT EXISTS user_agent TEXT,
+    ADD COLUMN IF NOT EXISTS target_type VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS target_id VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS changes JSONB,
+    ADD COLUMN IF NOT EXISTS metadata JSONB,
+    -- Rows written before the chain existed keep NULL hashes
+    ADD COLUMN IF NOT EXISTS prev_hash CHAR(64),
+    ADD COLUMN IF NOT EXISTS hash CHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_seq ON audit_logs(seq);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs(actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target ON audit_logs(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
@@ -0,0 +1,17 @@
+CREATE TABLE login_history (
+    id UUID PRIMARY KEY,
+    -- NULL for failed attempts on emails without an account
+    user_id UUID,
+    email VARCHAR(255),
+    success BOOLEAN NOT NULL,
+    method VARCHAR(30),
+    failure_reason VARCHAR(50),
+    ip VARCHAR(45),
+    user_agent TEXT,
+    device_id CHAR(32),
+    new_device BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_login_history_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_login_history_user_created ON login_history(user_id, created_at DESC);
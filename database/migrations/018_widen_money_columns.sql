@@ -0,0 +1,6 @@
+-- DECIMAL(12,2) tops out just under 10 billion, which large IDR totals reach
+ALTER TABLE transactions ALTER COLUMN amount TYPE NUMERIC(18,2);
+
+ALTER TABLE maximum_spends ALTER COLUMN daily_limit TYPE NUMERIC(18,2);
+ALTER TABLE maximum_spends ALTER COLUMN monthly_limit TYPE NUMERIC(18,2);
+ALTER TABLE maximum_spends ALTER COLUMN yearly_limit TYPE NUMERIC(18,2);
@@ -0,0 +1,46 @@
+-- Existing transactions were entered in the owner's base currency
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency CHAR(3);
+UPDATE transactions t SET currency = u.currency FROM users u WHERE t.user_id = u.id AND t.currency IS NULL;
+ALTER TABLE transactions ALTER COLUMN currency SET NOT NULL;
+
+-- One unit of base_currency was worth rate units of quote_currency on rate_date
+CREATE TABLE exchange_rates (
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate NUMERIC(24,10) NOT NULL,
+    source VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_exchange_rates PRIMARY KEY (quote_currency, base_currency, rate_date),
+    CONSTRAINT chk_exchange_rates_rate CHECK (rate > 0),
+    CONSTRAINT chk_exchange_rates_pair CHECK (base_currency <> quote_currency)
+);
+
+-- exchange_rate returns what one unit of from_currency was worth in
+-- to_currency on on_date, using the latest rates published on or before that
+-- day. A rate is used directly, inverted, or crossed through a shared base
+-- currency (ECB rates are all quoted against EUR). NULL when no rate is known.
+CREATE OR REPLACE FUNCTION exchange_rate(from_currency CHAR(3), to_currency CHAR(3), on_date DATE)
+RETURNS NUMERIC
+LANGUAGE sql
+STABLE
+AS $$
+    WITH latest AS (
+        SELECT DISTINCT ON (quote_currency, base_currency) base_currency, quote_currency, rate, rate_date
+        FROM exchange_rates
+        WHERE quote_currency IN (from_currency, to_currency) AND rate_date <= on_date
+        ORDER BY quote_currency, base_currency, rate_date DESC
+    ), quotes AS (
+        SELECT base_currency, quote_currency, rate, rate_date FROM latest
+        UNION ALL
+        SELECT c, c, 1, on_date FROM unnest(ARRAY[from_currency, to_currency]) AS c
+    )
+    SELECT CASE WHEN from_currency = to_currency THEN 1 ELSE (
+        SELECT t.rate / f.rate
+        FROM quotes f
+        JOIN quotes t ON t.base_currency = f.base_currency
+        WHERE f.quote_currency = from_currency AND t.quote_currency = to_currency
+        ORDER BY LEAST(f.rate_date, t.rate_date) DESC
+        LIMIT 1
+    ) END
+$$;
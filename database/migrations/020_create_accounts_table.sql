@@ -0,0 +1,41 @@
+CREATE TYPE account_type AS ENUM ('cash', 'bank', 'e_wallet', 'credit_card');
+
+-- Money sources. The live balance is opening_balance plus the account's
+-- income minus its expenses, computed when read.
+CREATE TABLE IF NOT EXISTS accounts (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    type account_type NOT NULL,
+    currency CHAR(3) NOT NULL,
+    opening_balance NUMERIC(18,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_accounts_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_accounts_user_id ON accounts(user_id, created_at);
+
+-- Every existing user starts with a cash account holding their transactions
+INSERT INTO accounts (id, user_id, name, type, currency)
+SELECT gen_random_uuid(), u.id, 'Cash', 'cash', u.currency
+FROM users u
+WHERE NOT EXISTS (SELECT 1 FROM accounts a WHERE a.user_id = u.id);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS account_id UUID;
+
+UPDATE transactions t
+SET account_id = (SELECT a.id FROM accounts a WHERE a.user_id = t.user_id ORDER BY a.created_at, a.id LIMIT 1)
+WHERE t.account_id IS NULL;
+
+-- Accounts with transactions cannot be deleted; deleting the user removes both
+ALTER TABLE transactions ALTER COLUMN account_id SET NOT NULL;
+ALTER TABLE transactions ADD CONSTRAINT fk_transactions_account FOREIGN KEY (account_id) REFERENCES accounts(id);
+
+-- Both legs of a transfer (an expense on the source account and an income on
+-- the destination) share a transfer_id and are left out of income and
+-- expense totals.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS transfer_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_account_id ON transactions(account_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_transfer_id ON transactions(transfer_id) WHERE transfer_id IS NOT NULL;
@@ -0,0 +1,39 @@
+-- Rules that create a transaction every interval_count periods. next_index
+-- counts the occurrences created or skipped so far and next_date is the date
+-- of the next one, NULL once the rule has ended.
+CREATE TABLE IF NOT EXISTS recurring_transactions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    account_id UUID NOT NULL,
+    category_id CHAR(26),
+    type transaction_type NOT NULL,
+    amount NUMERIC(18,2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    period VARCHAR(20) NOT NULL CHECK (period IN ('daily', 'weekly', 'monthly', 'yearly')),
+    interval_count INT NOT NULL DEFAULT 1 CHECK (interval_count >= 1),
+    month_end BOOLEAN NOT NULL DEFAULT FALSE,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    next_index INT NOT NULL DEFAULT 0,
+    next_date DATE,
+    paused_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_recurring_transactions_dates CHECK (end_date IS NULL OR end_date >= start_date),
+    CONSTRAINT fk_recurring_transactions_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_recurring_transactions_account FOREIGN KEY (account_id) REFERENCES accounts(id),
+    CONSTRAINT fk_recurring_transactions_category FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_transactions_user_id ON recurring_transactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_recurring_transactions_due ON recurring_transactions(next_date) WHERE paused_at IS NULL AND next_date IS NOT NULL;
+
+-- Each occurrence is created at most once, however often the scheduler runs.
+-- occurrence_date is the scheduled date and does not change when the user
+-- edits the transaction's date.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS recurring_id UUID;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS occurrence_date DATE;
+ALTER TABLE transactions ADD CONSTRAINT fk_transactions_recurring FOREIGN KEY (recurring_id) REFERENCES recurring_transactions(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_recurring_occurrence ON transactions(recurring_id, occurrence_date);
@@ -0,0 +1,16 @@
+-- A transaction split across several categories keeps its total in
+-- transactions.amount, with category_id NULL, and one row per category here.
+-- The service makes sure the splits add up to the transaction's amount.
+CREATE TABLE IF NOT EXISTS transaction_splits (
+    id UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL,
+    category_id CHAR(26),
+    amount NUMERIC(18,2) NOT NULL CHECK (amount > 0),
+    note TEXT NOT NULL DEFAULT '',
+    position INT NOT NULL,
+    CONSTRAINT fk_transaction_splits_transaction FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    CONSTRAINT fk_transaction_splits_category FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_splits_transaction_id ON transaction_splits(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_transaction_splits_category_id ON transaction_splits(category_id);
@@ -0,0 +1,4 @@
+-- Entries of a deleted account lose their IP, user agent, diffs and metadata.
+-- redacted_by is the seq of the entry that recorded the redaction and vouches
+-- for what is left, since the original hash can no longer be recomputed.
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS redacted_by BIGINT;
@@ -0,0 +1,6 @@
+-- Emails are stored lowercased and trimmed, so addresses that differ only in
+-- case are one account. Accounts that clash once lowercased make the UPDATE
+-- fail and have to be merged by hand first.
+UPDATE users SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email));
+
+ALTER TABLE users ADD CONSTRAINT chk_users_email_normalized CHECK (email = LOWER(TRIM(email)));
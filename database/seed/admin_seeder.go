@@ -0,0 +1,37 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PromoteAdmins gives the admin role to existing users whose email is listed
+// in the comma separated emails. It is how the first admin gets created.
+func PromoteAdmins(db *sql.DB, emails string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, email := range strings.Split(emails, ",") {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+
+		result, err := db.ExecContext(ctx,
+			`UPDATE users SET role = 'admin', updated_at = NOW() WHERE email = $1 AND role <> 'admin'`,
+			email,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to promote %s to admin: %w", email, err)
+		}
+
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			fmt.Printf("👑 Promoted %s to admin\n", email)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "github.com/kenziehh/cashflow-be/pkg/money"
+
+type CreateAccountRequest struct {
+	Name           string       `json:"name" validate:"required,max=100"`
+	Type           string       `json:"type" validate:"required,oneof=cash bank e_wallet credit_card"`
+	Currency       string       `json:"currency,omitempty" validate:"omitempty,iso4217" example:"SGD"` // ISO 4217, defaults to the user's base currency
+	OpeningBalance money.Amount `json:"opening_balance" swaggertype:"number" example:"1000000"`
+}
+
+// UpdateAccountRequest cannot change the currency, which every amount on the
+// account is recorded in.
+type UpdateAccountRequest struct {
+	Name           string       `json:"name" validate:"required,max=100"`
+	Type           string       `json:"type" validate:"required,oneof=cash bank e_wallet credit_card"`
+	OpeningBalance money.Amount `json:"opening_balance" swaggertype:"number" example:"1000000"`
+}
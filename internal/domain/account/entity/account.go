@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/pkg/money"
+)
+
+// Account types
+const (
+	TypeCash       = "cash"
+	TypeBank       = "bank"
+	TypeEWallet    = "e_wallet"
+	TypeCreditCard = "credit_card"
+)
+
+// Account is a source of money transactions are paid from or into. Balance
+// is OpeningBalance plus the account's income minus its expenses, in the
+// account's currency; a credit card balance is negative while money is owed.
+type Account struct {
+	ID             uuid.UUID    `json:"id"`
+	UserID         uuid.UUID    `json:"user_id"`
+	Name           string       `json:"name" example:"BCA"`
+	Type           string       `json:"type" example:"bank"`
+	Currency       string       `json:"currency" example:"IDR"` // ISO 4217
+	OpeningBalance money.Amount `json:"opening_balance" swaggertype:"number" example:"1000000"`
+	Balance        money.Amount `json:"balance" swaggertype:"number" example:"1250000.50"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
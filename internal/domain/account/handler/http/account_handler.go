@@ -0,0 +1,193 @@
+package http
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/account/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/account/service"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/response"
+)
+
+type AccountHandler struct {
+	service  service.AccountService
+	validate *validator.Validate
+}
+
+func NewAccountHandler(service service.AccountService) *AccountHandler {
+	return &AccountHandler{
+		service:  service,
+		validate: validator.New(),
+	}
+}
+
+// CreateAccount godoc
+// @Summary Create an account
+// @Description Create a cash, bank, e-wallet or credit card account with an opening balance
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateAccountRequest true "Account request"
+// @Success 201 {object} response.Response{data=entity.Account}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /accounts [post]
+func (h *AccountHandler) CreateAccount(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	var req dto.CreateAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	account, err := h.service.CreateAccount(c.Context(), userID, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response.SuccessResponse("Account created successfully", account))
+}
+
+// ListAccounts godoc
+// @Summary List accounts
+// @Description List the current user's accounts with their live balances
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]entity.Account}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /accounts [get]
+func (h *AccountHandler) ListAccounts(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	accounts, err := h.service.ListAccounts(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Accounts retrieved successfully", accounts))
+}
+
+// GetAccountByID godoc
+// @Summary Get an account
+// @Description Get one of the current user's accounts with its live balance
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Account ID"
+// @Success 200 {object} response.Response{data=entity.Account}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /accounts/{id} [get]
+func (h *AccountHandler) GetAccountByID(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid account ID format")
+	}
+
+	account, err := h.service.GetAccountByID(c.Context(), userID, id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Account retrieved successfully", account))
+}
+
+// UpdateAccount godoc
+// @Summary Update an account
+// @Description Rename an account or change its type or opening balance. The currency cannot be changed.
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Account ID"
+// @Param request body dto.UpdateAccountRequest true "Account request"
+// @Success 200 {object} response.Response{data=entity.Account}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /accounts/{id} [put]
+func (h *AccountHandler) UpdateAccount(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid account ID format")
+	}
+
+	var req dto.UpdateAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	account, err := h.service.UpdateAccount(c.Context(), userID, id, req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Account updated successfully", account))
+}
+
+// DeleteAccount godoc
+// @Summary Delete an account
+// @Description Delete an account that has no transactions
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Account ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /accounts/{id} [delete]
+func (h *AccountHandler) DeleteAccount(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid account ID format")
+	}
+
+	if err := h.service.DeleteAccount(c.Context(), userID, id); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Account deleted successfully", nil))
+}
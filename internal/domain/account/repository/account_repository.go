@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/account/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/lib/pq"
+)
+
+type AccountRepository interface {
+	CreateAccount(ctx context.Context, account *entity.Account) error
+	CreateAccountTx(ctx context.Context, tx *sql.Tx, account *entity.Account) error
+	GetAccountByID(ctx context.Context, userID, id uuid.UUID) (*entity.Account, error)
+	ListAccounts(ctx context.Context, userID uuid.UUID) ([]entity.Account, error)
+	UpdateAccount(ctx context.Context, account *entity.Account) error
+	DeleteAccount(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type accountRepository struct {
+	db    *sql.DB
+	redis *redis.Client
+}
+
+func NewAccountRepository(db *sql.DB, redis *redis.Client) AccountRepository {
+	return &accountRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// accountSelect computes live balances. Transactions in another currency
+// than their account are converted at the rate of their date; those without
+// a known rate are left out.
+const accountSelect = `
+	SELECT
+		a.id, a.user_id, a.name, a.type, a.currency, a.opening_balance,
+		a.opening_balance + COALESCE(SUM(
+			CASE t.type WHEN 'income' THEN 1 ELSE -1 END *
+			ROUND(t.amount * exchange_rate(t.currency, a.currency, t.date), 2)
+		), 0) AS balance,
+		a.created_at, a.updated_at
+	FROM accounts a
+	LEFT JOIN transactions t ON t.account_id = a.id
+`
+
+func (r *accountRepository) CreateAccount(ctx context.Context, account *entity.Account) error {
+	return insertAccount(ctx, r.db, account)
+}
+
+// CreateAccountTx creates the account as part of tx, which the caller commits.
+func (r *accountRepository) CreateAccountTx(ctx context.Context, tx *sql.Tx, account *entity.Account) error {
+	return insertAccount(ctx, tx, account)
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertAccount(ctx context.Context, db queryRower, account *entity.Account) error {
+	// Without a currency the account is in the user's base currency
+	query := `
+	INSERT INTO accounts (id, user_id, name, type, currency, opening_balance, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), (SELECT currency FROM users WHERE id = $2)), $6, $7, $8)
+	RETURNING currency
+	`
+
+	err := db.QueryRowContext(ctx, query,
+		account.ID, account.UserID, account.Name, account.Type, account.Currency,
+		account.OpeningBalance, account.CreatedAt, account.UpdatedAt,
+	).Scan(&account.Currency)
+	if err != nil {
+		log.Printf("[DB ERROR] CreateAccount failed: %v\n", err)
+		return errx.ErrDatabaseError
+	}
+
+	account.Balance = account.OpeningBalance
+	return nil
+}
+
+func (r *accountRepository) GetAccountByID(ctx context.Context, userID, id uuid.UUID) (*entity.Account, error) {
+	query := accountSelect + `
+	WHERE a.id = $1 AND a.user_id = $2
+	GROUP BY a.id
+	`
+
+	var account entity.Account
+	err := scanAccount(r.db.QueryRowContext(ctx, query, id, userID), &account)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errx.ErrAccountNotFound
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] GetAccountByID failed: %v\n", err)
+		return nil, errx.ErrDatabaseError
+	}
+
+	return &account, nil
+}
+
+func (r *accountRepository) ListAccounts(ctx context.Context, userID uuid.UUID) ([]entity.Account, error) {
+	query := accountSelect + `
+	WHERE a.user_id = $1
+	GROUP BY a.id
+	ORDER BY a.created_at, a.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		log.Printf("[DB ERROR] ListAccounts failed: %v\n", err)
+		return nil, errx.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	accounts := []entity.Account{}
+	for rows.Next() {
+		var account entity.Account
+		if err := scanAccount(rows, &account); err != nil {
+			return nil, errx.ErrDatabaseError
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	return accounts, nil
+}
+
+func (r *accountRepository) UpdateAccount(ctx context.Context, account *entity.Account) error {
+	query := `
+	UPDATE accounts
+	SET name = $1, type = $2, opening_balance = $3, updated_at = $4
+	WHERE id = $5 AND user_id = $6
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		account.Name, account.Type, account.OpeningBalance, account.UpdatedAt, account.ID, account.UserID,
+	)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	if affected == 0 {
+		return errx.ErrAccountNotFound
+	}
+
+	return nil
+}
+
+// DeleteAccount refuses to delete an account that still has transactions.
+func (r *accountRepository) DeleteAccount(ctx context.Context, userID, id uuid.UUID) error {
+	query := `
+	DELETE FROM accounts
+	WHERE id = $1 AND user_id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return errx.ErrAccountInUse
+	}
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	if affected == 0 {
+		return errx.ErrAccountNotFound
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAccount(row rowScanner, a *entity.Account) error {
+	return row.Scan(
+		&a.ID,
+		&a.UserID,
+		&a.Name,
+		&a.Type,
+		&a.Currency,
+		&a.OpeningBalance,
+		&a.Balance,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/account/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/account/entity"
+	"github.com/kenziehh/cashflow-be/internal/domain/account/repository"
+	auditService "github.com/kenziehh/cashflow-be/internal/domain/audit/service"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+)
+
+type AccountService interface {
+	CreateAccount(ctx context.Context, userID uuid.UUID, req dto.CreateAccountRequest) (*entity.Account, error)
+	CreateDefaultAccount(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error
+	GetAccountByID(ctx context.Context, userID, id uuid.UUID) (*entity.Account, error)
+	ListAccounts(ctx context.Context, userID uuid.UUID) ([]entity.Account, error)
+	ResolveAccount(ctx context.Context, userID uuid.UUID, id string) (*entity.Account, error)
+	UpdateAccount(ctx context.Context, userID, id uuid.UUID, req dto.UpdateAccountRequest) (*entity.Account, error)
+	DeleteAccount(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type accountService struct {
+	repo  repository.AccountRepository
+	audit auditService.AuditService
+}
+
+func NewAccountService(repo repository.AccountRepository, audit auditService.AuditService) AccountService {
+	return &accountService{
+		repo:  repo,
+		audit: audit,
+	}
+}
+
+func (s *accountService) CreateAccount(ctx context.Context, userID uuid.UUID, req dto.CreateAccountRequest) (*entity.Account, error) {
+	now := time.Now()
+	account := &entity.Account{
+		ID:             uuid.New(),
+		UserID:         userID,
+		Name:           req.Name,
+		Type:           req.Type,
+		Currency:       req.Currency,
+		OpeningBalance: req.OpeningBalance,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := s.repo.CreateAccount(ctx, account); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditService.ActionAccountCreated,
+		TargetType: auditService.TargetAccount,
+		TargetID:   account.ID.String(),
+		After:      account,
+	})
+
+	return account, nil
+}
+
+// CreateDefaultAccount opens the cash account in the user's currency every
+// user starts with, so transactions always have an account to go to. It runs
+// in tx, the transaction that creates the user.
+func (s *accountService) CreateDefaultAccount(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	now := time.Now()
+	account := &entity.Account{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      "Cash",
+		Type:      entity.TypeCash,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	return s.repo.CreateAccountTx(ctx, tx, account)
+}
+
+func (s *accountService) GetAccountByID(ctx context.Context, userID, id uuid.UUID) (*entity.Account, error) {
+	return s.repo.GetAccountByID(ctx, userID, id)
+}
+
+func (s *accountService) ListAccounts(ctx context.Context, userID uuid.UUID) ([]entity.Account, error) {
+	return s.repo.ListAccounts(ctx, userID)
+}
+
+// ResolveAccount returns the user's account with the given ID, or their
+// first account when id is empty.
+func (s *accountService) ResolveAccount(ctx context.Context, userID uuid.UUID, id string) (*entity.Account, error) {
+	if id != "" {
+		accountID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, errx.ErrAccountNotFound
+		}
+		return s.repo.GetAccountByID(ctx, userID, accountID)
+	}
+
+	accounts, err := s.repo.ListAccounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, errx.ErrAccountNotFound
+	}
+	return &accounts[0], nil
+}
+
+func (s *accountService) UpdateAccount(ctx context.Context, userID, id uuid.UUID, req dto.UpdateAccountRequest) (*entity.Account, error) {
+	account, err := s.repo.GetAccountByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *account
+	account.Name = req.Name
+	account.Type = req.Type
+	account.Balance += req.OpeningBalance - account.OpeningBalance
+	account.OpeningBalance = req.OpeningBalance
+	account.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateAccount(ctx, account); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditService.ActionAccountUpdated,
+		TargetType: auditService.TargetAccount,
+		TargetID:   account.ID.String(),
+		Before:     before,
+		After:      account,
+	})
+
+	return account, nil
+}
+
+func (s *accountService) DeleteAccount(ctx context.Context, userID, id uuid.UUID) error {
+	account, err := s.repo.GetAccountByID(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteAccount(ctx, userID, id); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditService.ActionAccountDeleted,
+		TargetType: auditService.TargetAccount,
+		TargetID:   account.ID.String(),
+		Before:     account,
+	})
+	return nil
+}
+
+// recordAudit writes the audit entry of a change the repository has already
+// committed, so failures are only logged rather than reported as a failed
+// change.
+func (s *accountService) recordAudit(ctx context.Context, event auditService.Event) {
+	if err := s.audit.Record(ctx, event); err != nil {
+		log.Printf("[AUDIT ERROR] %s %s: %v", event.Action, event.TargetID, err)
+	}
+}
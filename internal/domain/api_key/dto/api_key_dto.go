@@ -0,0 +1,15 @@
+package dto
+
+import "github.com/kenziehh/cashflow-be/internal/domain/api_key/entity"
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty" validate:"omitempty,min=1,max=365"`
+}
+
+// CreateAPIKeyResponse is the only time the plain key is returned.
+type CreateAPIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey *entity.APIKey `json:"api_key"`
+}
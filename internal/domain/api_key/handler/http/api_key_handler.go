@@ -0,0 +1,116 @@
+package http
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/api_key/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/api_key/service"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/response"
+)
+
+type APIKeyHandler struct {
+	service  service.APIKeyService
+	validate *validator.Validate
+}
+
+func NewAPIKeyHandler(service service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		service:  service,
+		validate: validator.New(),
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create a personal access token with the given scopes. The key is only shown once.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateAPIKeyRequest true "API key request"
+// @Success 201 {object} response.Response{data=dto.CreateAPIKeyResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	var req dto.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.CreateAPIKey(c.Context(), userID, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response.SuccessResponse("API key created successfully", result))
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List the current user's API keys
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]entity.APIKey}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	keys, err := h.service.ListAPIKeys(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("API keys retrieved successfully", keys))
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Permanently revoke one of the current user's API keys
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid API key ID format")
+	}
+
+	if err := h.service.RevokeAPIKey(c.Context(), userID, id); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("API key revoked successfully", nil))
+}
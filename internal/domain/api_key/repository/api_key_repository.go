@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/api_key/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *entity.APIKey) error
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]entity.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*entity.APIKey, error)
+	TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+	DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) error
+	DeleteAPIKeysByUser(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+type apiKeyRepository struct {
+	db    *sql.DB
+	redis *redis.Client
+}
+
+func NewAPIKeyRepository(db *sql.DB, redis *redis.Client) APIKeyRepository {
+	return &apiKeyRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+func (r *apiKeyRepository) CreateAPIKey(ctx context.Context, key *entity.APIKey) error {
+	query := `
+	INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash,
+		pq.Array(key.Scopes), key.ExpiresAt, key.CreatedAt,
+	)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]entity.APIKey, error) {
+	query := `
+	SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at
+	FROM api_keys
+	WHERE user_id = $1
+	ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	keys := []entity.APIKey{}
+	for rows.Next() {
+		var k entity.APIKey
+		if err := scanAPIKey(rows, &k); err != nil {
+			return nil, errx.ErrDatabaseError
+		}
+		keys = append(keys, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	query := `
+	SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at
+	FROM api_keys
+	WHERE key_hash = $1
+	`
+
+	var k entity.APIKey
+	err := scanAPIKey(r.db.QueryRowContext(ctx, query, keyHash), &k)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errx.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	return &k, nil
+}
+
+func (r *apiKeyRepository) TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	query := `
+	UPDATE api_keys
+	SET last_used_at = $1
+	WHERE id = $2
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, usedAt, id); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	query := `
+	DELETE FROM api_keys
+	WHERE id = $1 AND user_id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	if affected == 0 {
+		return errx.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// DeleteAPIKeysByUser returns how many keys were deleted.
+func (r *apiKeyRepository) DeleteAPIKeysByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	query := `
+	DELETE FROM api_keys
+	WHERE user_id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, errx.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errx.ErrDatabaseError
+	}
+
+	return affected, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row rowScanner, k *entity.APIKey) error {
+	return row.Scan(
+		&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.KeyHash,
+		pq.Array(&k.Scopes), &k.ExpiresAt, &k.LastUsedAt, &k.CreatedAt,
+	)
+}
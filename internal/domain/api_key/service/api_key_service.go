@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/api_key/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/api_key/entity"
+	"github.com/kenziehh/cashflow-be/internal/domain/api_key/repository"
+	auditService "github.com/kenziehh/cashflow-be/internal/domain/audit/service"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/rbac"
+	"github.com/kenziehh/cashflow-be/pkg/token"
+)
+
+const (
+	// KeyPrefix marks a bearer credential as an API key rather than a JWT.
+	KeyPrefix = "cfk_"
+
+	displayPrefixLen = 12
+	// lastUsedGranularity limits last_used_at writes for busy keys.
+	lastUsedGranularity = time.Minute
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, userID uuid.UUID, req dto.CreateAPIKeyRequest) (*dto.CreateAPIKeyResponse, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]entity.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error
+	RevokeAllAPIKeys(ctx context.Context, userID uuid.UUID) error
+	AuthenticateAPIKey(ctx context.Context, key string) (uuid.UUID, []string, error)
+}
+
+type apiKeyService struct {
+	repo  repository.APIKeyRepository
+	audit auditService.AuditService
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository, audit auditService.AuditService) APIKeyService {
+	return &apiKeyService{
+		repo:  repo,
+		audit: audit,
+	}
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, userID uuid.UUID, req dto.CreateAPIKeyRequest) (*dto.CreateAPIKeyResponse, error) {
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !rbac.IsValidScope(scope) {
+			return nil, errx.NewBadRequestError(fmt.Sprintf("Unknown scope %q", scope))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	secret, err := token.Generate()
+	if err != nil {
+		return nil, errx.ErrInternalServer
+	}
+	plain := KeyPrefix + secret
+
+	now := time.Now()
+	key := &entity.APIKey{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    plain[:displayPrefixLen],
+		KeyHash:   token.Hash(plain),
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditService.ActionAPIKeyCreated,
+		TargetType: auditService.TargetAPIKey,
+		TargetID:   key.ID.String(),
+		Metadata:   map[string]any{"name": key.Name, "prefix": key.Prefix, "scopes": key.Scopes},
+	})
+
+	return &dto.CreateAPIKeyResponse{
+		Key:    plain,
+		APIKey: key,
+	}, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]entity.APIKey, error) {
+	return s.repo.ListAPIKeys(ctx, userID)
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	if err := s.repo.DeleteAPIKey(ctx, userID, id); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditService.ActionAPIKeyRevoked,
+		TargetType: auditService.TargetAPIKey,
+		TargetID:   id.String(),
+	})
+	return nil
+}
+
+// RevokeAllAPIKeys drops every key of the user. API keys do not carry the
+// token version, so signing out everywhere has to revoke them explicitly.
+func (s *apiKeyService) RevokeAllAPIKeys(ctx context.Context, userID uuid.UUID) error {
+	revoked, err := s.repo.DeleteAPIKeysByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return nil
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditService.ActionAPIKeyRevoked,
+		TargetType: auditService.TargetAPIKey,
+		Metadata:   map[string]any{"keys": revoked},
+	})
+	return nil
+}
+
+// AuthenticateAPIKey resolves a plain API key to its owner and scopes,
+// rejecting unknown and expired keys.
+func (s *apiKeyService) AuthenticateAPIKey(ctx context.Context, key string) (uuid.UUID, []string, error) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return uuid.Nil, nil, errx.ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.repo.GetAPIKeyByHash(ctx, token.Hash(key))
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt) {
+		return uuid.Nil, nil, errx.ErrInvalidAPIKey
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedGranularity {
+		if err := s.repo.TouchAPIKey(ctx, apiKey.ID, now); err != nil {
+			return uuid.Nil, nil, err
+		}
+	}
+
+	return apiKey.UserID, apiKey.Scopes, nil
+}
+
+// recordAudit only logs failures: the key change is already stored.
+func (s *apiKeyService) recordAudit(ctx context.Context, event auditService.Event) {
+	if err := s.audit.Record(ctx, event); err != nil {
+		log.Printf("[AUDIT ERROR] %s %s: %v", event.Action, event.TargetID, err)
+	}
+}
@@ -0,0 +1,36 @@
+package dto
+
+import "github.com/kenziehh/cashflow-be/internal/domain/audit/entity"
+
+type AuditLogListParams struct {
+	Page       int    `query:"page" validate:"min=1"`
+	Limit      int    `query:"limit" validate:"min=1,max=100"`
+	Action     string `query:"action"`
+	UserID     string `query:"user_id" validate:"omitempty,uuid"`
+	ActorID    string `query:"actor_id" validate:"omitempty,uuid"`
+	TargetType string `query:"target_type"`
+	TargetID   string `query:"target_id"`
+	StartDate  string `query:"start_date" validate:"omitempty,datetime=2006-01-02"`
+	EndDate    string `query:"end_date" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type PaginatedAuditLogsResponse struct {
+	Data        []*entity.AuditLog `json:"data"`
+	CurrentPage int                `json:"current_page"`
+	Limit       int                `json:"limit"`
+	TotalPage   int                `json:"total_page"`
+}
+
+// VerifyAuditLogResponse reports the result of walking the hash chain.
+// HeadHash can be stored elsewhere: a later verification that does not reach
+// it means entries were cut off the end.
+type VerifyAuditLogResponse struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	HeadSeq  int64  `json:"head_seq"`
+	HeadHash string `json:"head_hash"`
+	// Redacted counts the checked entries whose personal data was removed
+	Redacted int64 `json:"redacted"`
+	// BrokenAtSeq is the first entry whose hash does not match
+	BrokenAtSeq int64 `json:"broken_at_seq,omitempty"`
+}
@@ -0,0 +1,144 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditLog is one entry of the append-only audit trail. UserID is the account
+// the event concerns, ActorID whoever caused it (the same user, an admin, or
+// nobody for anonymous requests such as a failed login). RedactedBy is the
+// seq of the entry that removed this entry's personal data, see Redact.
+type AuditLog struct {
+	Seq        int64           `json:"seq"`
+	ID         uuid.UUID       `json:"id"`
+	UserID     *uuid.UUID      `json:"user_id"`
+	ActorID    *uuid.UUID      `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type,omitempty"`
+	TargetID   string          `json:"target_id,omitempty"`
+	IP         string          `json:"ip,omitempty"`
+	UserAgent  string          `json:"user_agent,omitempty"`
+	Changes    json.RawMessage `json:"changes,omitempty" swaggertype:"object"`
+	Metadata   json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+	RedactedBy int64           `json:"redacted_by,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Change is the before and after value of one field.
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// ComputeHash returns the SHA-256 over the previous entry's hash and every
+// field of this entry, so editing, removing or reordering rows breaks the
+// chain. JSON columns are canonicalized because PostgreSQL does not keep
+// their original formatting.
+func (l *AuditLog) ComputeHash(prevHash string) string {
+	payload := struct {
+		PrevHash   string          `json:"prev_hash"`
+		ID         uuid.UUID       `json:"id"`
+		UserID     *uuid.UUID      `json:"user_id"`
+		ActorID    *uuid.UUID      `json:"actor_id"`
+		Action     string          `json:"action"`
+		TargetType string          `json:"target_type"`
+		TargetID   string          `json:"target_id"`
+		IP         string          `json:"ip"`
+		UserAgent  string          `json:"user_agent"`
+		Changes    json.RawMessage `json:"changes"`
+		Metadata   json.RawMessage `json:"metadata"`
+		CreatedAt  string          `json:"created_at"`
+	}{
+		PrevHash:   prevHash,
+		ID:         l.ID,
+		UserID:     l.UserID,
+		ActorID:    l.ActorID,
+		Action:     l.Action,
+		TargetType: l.TargetType,
+		TargetID:   l.TargetID,
+		IP:         l.IP,
+		UserAgent:  l.UserAgent,
+		Changes:    canonicalJSON(l.Changes),
+		Metadata:   canonicalJSON(l.Metadata),
+		CreatedAt:  l.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	b, _ := json.Marshal(payload)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func canonicalJSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage("null")
+	}
+
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return raw
+	}
+	b, _ := json.Marshal(v)
+	return b
+}
+
+// Redaction is the metadata of the entry that records a redaction. Digest
+// commits to every redacted entry, so the chain stays verifiable without the
+// data that was removed.
+type Redaction struct {
+	Entries int    `json:"entries"`
+	Digest  string `json:"digest"`
+}
+
+// Redact removes the personal data of userID from entries: the IP and user
+// agent of every entry, and the diffs and metadata of entries about the user
+// or about nobody (a failed login naming their email). It describes the
+// removal in redaction's metadata; the caller sets RedactedBy once the
+// redaction entry has its seq.
+func Redact(entries []*AuditLog, userID uuid.UUID, redaction *AuditLog) error {
+	digest := NewRedactionDigest()
+	for _, entry := range entries {
+		entry.IP = ""
+		entry.UserAgent = ""
+		if entry.UserID == nil || *entry.UserID == userID {
+			entry.Changes = nil
+			entry.Metadata = nil
+		}
+		digest.Add(entry)
+	}
+
+	metadata, err := json.Marshal(Redaction{Entries: digest.Entries, Digest: digest.Sum()})
+	if err != nil {
+		return err
+	}
+	redaction.Metadata = metadata
+	return nil
+}
+
+// RedactionDigest hashes the position, original hash and remaining fields of
+// redacted entries, in chain order.
+type RedactionDigest struct {
+	Entries int
+	h       hash.Hash
+}
+
+func NewRedactionDigest() *RedactionDigest {
+	return &RedactionDigest{h: sha256.New()}
+}
+
+func (d *RedactionDigest) Add(entry *AuditLog) {
+	fmt.Fprintf(d.h, "%d:%s:%s\n", entry.Seq, entry.Hash, entry.ComputeHash(entry.PrevHash))
+	d.Entries++
+}
+
+func (d *RedactionDigest) Sum() string {
+	return hex.EncodeToString(d.h.Sum(nil))
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/audit/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/audit/service"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/response"
+)
+
+type AuditHandler struct {
+	service  service.AuditService
+	validate *validator.Validate
+}
+
+func NewAuditHandler(service service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		service:  service,
+		validate: validator.New(),
+	}
+}
+
+// ListAuditLogs godoc
+// @Summary List audit log entries
+// @Description List audit log entries, newest first. Users only see entries about their own account; admins see every entry and may filter by user_id.
+// @Tags audit-logs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(20)
+// @Param action query string false "Action, e.g. login_failed"
+// @Param user_id query string false "Account the entry concerns (admins only)"
+// @Param actor_id query string false "User who caused the event"
+// @Param target_type query string false "Target type, e.g. transaction"
+// @Param target_id query string false "Target ID"
+// @Param start_date query string false "From date (YYYY-MM-DD)"
+// @Param end_date query string false "Until date, inclusive (YYYY-MM-DD)"
+// @Success 200 {object} response.Response{data=dto.PaginatedAuditLogsResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /audit-logs [get]
+func (h *AuditHandler) ListAuditLogs(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+	role, _ := c.Locals("role").(string)
+
+	var params dto.AuditLogListParams
+	if err := c.QueryParser(&params); err != nil {
+		return errx.NewBadRequestError("Invalid query parameters")
+	}
+
+	if params.Page == 0 {
+		params.Page = 1
+	}
+	if params.Limit == 0 {
+		params.Limit = 20
+	}
+
+	if err := h.validate.Struct(params); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.ListAuditLogs(c.Context(), userID, role, params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Audit logs retrieved successfully", result))
+}
+
+// VerifyAuditLogs godoc
+// @Summary Verify the audit log hash chain
+// @Description Recompute every entry hash and report the first entry that was modified, removed or reordered
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.VerifyAuditLogResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/audit-logs/verify [get]
+func (h *AuditHandler) VerifyAuditLogs(c *fiber.Ctx) error {
+	result, err := h.service.VerifyChain(c.Context())
+	if err != nil {
+		return err
+	}
+
+	message := "Audit log chain is intact"
+	if !result.Valid {
+		message = "Audit log chain is broken"
+	}
+
+	return c.JSON(response.SuccessResponse(message, result))
+}
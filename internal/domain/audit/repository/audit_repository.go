@@ -0,0 +1,324 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/audit/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/audit/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+)
+
+// chainLockID serializes appends so every entry links to the one before it
+const chainLockID = 0x617564697403
+
+type AuditRepository interface {
+	Append(ctx context.Context, entry *entity.AuditLog) error
+	List(ctx context.Context, params dto.AuditLogListParams) (dto.PaginatedAuditLogsResponse, error)
+	ListChain(ctx context.Context, afterSeq int64, limit int) ([]*entity.AuditLog, error)
+	Redact(ctx context.Context, userID uuid.UUID, email string, redaction *entity.AuditLog) (int, error)
+}
+
+type auditRepository struct {
+	db    *sql.DB
+	redis *redis.Client
+}
+
+func NewAuditRepository(db *sql.DB, redis *redis.Client) AuditRepository {
+	return &auditRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+const auditLogColumns = `
+	seq, id, user_id, actor_id, action, COALESCE(target_type, ''), COALESCE(target_id, ''),
+	COALESCE(ip, ''), COALESCE(user_agent, ''), changes, metadata,
+	COALESCE(prev_hash, ''), COALESCE(hash, ''), COALESCE(redacted_by, 0), created_at
+`
+
+// Append links the entry to the current head of the chain and stores it. It
+// fills in PrevHash, Hash and Seq.
+func (r *auditRepository) Append(ctx context.Context, entry *entity.AuditLog) error {
+	tx, err := r.lockChain(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.append(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// Redact removes the personal data of a deleted account from its chained
+// entries (see entity.Redact) and appends redaction, which records the
+// removal, in the same transaction. Entries naming the account's email
+// without an account, such as failed logins, are redacted too. It returns how
+// many entries were redacted and appends nothing when there were none.
+func (r *auditRepository) Redact(ctx context.Context, userID uuid.UUID, email string, redaction *entity.AuditLog) (int, error) {
+	tx, err := r.lockChain(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Earlier redaction entries are left alone, they vouch for what they redacted
+	query := `SELECT ` + auditLogColumns + `
+		FROM audit_logs
+		WHERE hash IS NOT NULL AND redacted_by IS NULL AND action <> $3
+			AND (user_id = $1 OR actor_id = $1 OR (user_id IS NULL AND lower(metadata->>'email') = lower($2)))
+		ORDER BY seq
+	`
+	entries, err := r.query(ctx, tx, query, userID, email, redaction.Action)
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	if err := entity.Redact(entries, userID, redaction); err != nil {
+		return 0, errx.ErrInternalServer
+	}
+	if err := r.append(ctx, tx, redaction); err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		UPDATE audit_logs
+		SET ip = NULLIF($2, ''), user_agent = NULLIF($3, ''), changes = $4, metadata = $5, redacted_by = $6
+		WHERE seq = $1
+	`)
+	if err != nil {
+		return 0, errx.ErrDatabaseError
+	}
+	defer stmt.Close()
+
+	for _, entry := range entries {
+		entry.RedactedBy = redaction.Seq
+		_, err := stmt.ExecContext(ctx, entry.Seq, entry.IP, entry.UserAgent, nullJSON(entry.Changes), nullJSON(entry.Metadata), entry.RedactedBy)
+		if err != nil {
+			log.Printf("[DB ERROR] Redact audit log %d failed: %v\n", entry.Seq, err)
+			return 0, errx.ErrDatabaseError
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errx.ErrDatabaseError
+	}
+
+	return len(entries), nil
+}
+
+// lockChain starts a transaction holding the chain lock, which serializes
+// every change to the chain.
+func (r *auditRepository) lockChain(ctx context.Context) (*sql.Tx, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, chainLockID); err != nil {
+		tx.Rollback()
+		return nil, errx.ErrDatabaseError
+	}
+
+	return tx, nil
+}
+
+// append stores entry after the current head of the chain; tx must hold the
+// chain lock.
+func (r *auditRepository) append(ctx context.Context, tx *sql.Tx, entry *entity.AuditLog) error {
+	var prevHash string
+	err := tx.QueryRowContext(ctx, `
+		SELECT hash FROM audit_logs
+		WHERE hash IS NOT NULL
+		ORDER BY seq DESC
+		LIMIT 1
+	`).Scan(&prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return errx.ErrDatabaseError
+	}
+
+	entry.PrevHash = prevHash
+	entry.Hash = entry.ComputeHash(prevHash)
+
+	query := `
+		INSERT INTO audit_logs (id, user_id, actor_id, action, target_type, target_id, ip, user_agent, changes, metadata, prev_hash, hash, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11, $12, $13)
+		RETURNING seq
+	`
+
+	err = tx.QueryRowContext(ctx, query,
+		entry.ID,
+		nullUUID(entry.UserID),
+		nullUUID(entry.ActorID),
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID,
+		entry.IP,
+		entry.UserAgent,
+		nullJSON(entry.Changes),
+		nullJSON(entry.Metadata),
+		entry.PrevHash,
+		entry.Hash,
+		entry.CreatedAt,
+	).Scan(&entry.Seq)
+	if err != nil {
+		log.Printf("[DB ERROR] Append audit log failed: %v\n", err)
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *auditRepository) List(ctx context.Context, params dto.AuditLogListParams) (dto.PaginatedAuditLogsResponse, error) {
+	where := " WHERE 1 = 1"
+	args := []interface{}{}
+	paramIndex := 1
+
+	addFilter := func(clause string, value interface{}) {
+		where += fmt.Sprintf(" AND "+clause, paramIndex)
+		args = append(args, value)
+		paramIndex++
+	}
+
+	if params.UserID != "" {
+		addFilter("user_id = $%d", params.UserID)
+	}
+	if params.ActorID != "" {
+		addFilter("actor_id = $%d", params.ActorID)
+	}
+	if params.Action != "" {
+		addFilter("action = $%d", params.Action)
+	}
+	if params.TargetType != "" {
+		addFilter("target_type = $%d", params.TargetType)
+	}
+	if params.TargetID != "" {
+		addFilter("target_id = $%d", params.TargetID)
+	}
+	if params.StartDate != "" {
+		addFilter("created_at >= $%d::date", params.StartDate)
+	}
+	if params.EndDate != "" {
+		addFilter("created_at < $%d::date + 1", params.EndDate)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_logs`+where, args...).Scan(&total); err != nil {
+		log.Printf("[DB ERROR] Count audit logs failed: %v\n", err)
+		return dto.PaginatedAuditLogsResponse{}, errx.ErrDatabaseError
+	}
+
+	offset := (params.Page - 1) * params.Limit
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs` + where +
+		fmt.Sprintf(" ORDER BY seq DESC LIMIT $%d OFFSET $%d", paramIndex, paramIndex+1)
+	args = append(args, params.Limit, offset)
+
+	logs, err := r.query(ctx, r.db, query, args...)
+	if err != nil {
+		return dto.PaginatedAuditLogsResponse{}, err
+	}
+
+	return dto.PaginatedAuditLogsResponse{
+		Data:        logs,
+		CurrentPage: params.Page,
+		Limit:       params.Limit,
+		TotalPage:   (total + params.Limit - 1) / params.Limit,
+	}, nil
+}
+
+// ListChain returns chained entries (those with a hash) after afterSeq, in
+// chain order.
+func (r *auditRepository) ListChain(ctx context.Context, afterSeq int64, limit int) ([]*entity.AuditLog, error) {
+	query := `SELECT ` + auditLogColumns + `
+		FROM audit_logs
+		WHERE hash IS NOT NULL AND seq > $1
+		ORDER BY seq
+		LIMIT $2
+	`
+	return r.query(ctx, r.db, query, afterSeq, limit)
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (r *auditRepository) query(ctx context.Context, q querier, query string, args ...interface{}) ([]*entity.AuditLog, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("[DB ERROR] Query audit logs failed: %v\n", err)
+		return nil, errx.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	logs := []*entity.AuditLog{}
+	for rows.Next() {
+		entry := &entity.AuditLog{}
+		var userID, actorID uuid.NullUUID
+		var changes, metadata []byte
+		err := rows.Scan(
+			&entry.Seq,
+			&entry.ID,
+			&userID,
+			&actorID,
+			&entry.Action,
+			&entry.TargetType,
+			&entry.TargetID,
+			&entry.IP,
+			&entry.UserAgent,
+			&changes,
+			&metadata,
+			&entry.PrevHash,
+			&entry.Hash,
+			&entry.RedactedBy,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			log.Printf("[DB ERROR] Scan audit log failed: %v\n", err)
+			return nil, errx.ErrDatabaseError
+		}
+
+		if userID.Valid {
+			entry.UserID = &userID.UUID
+		}
+		if actorID.Valid {
+			entry.ActorID = &actorID.UUID
+		}
+		entry.Changes = changes
+		entry.Metadata = metadata
+		logs = append(logs, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	return logs, nil
+}
+
+func nullUUID(id *uuid.UUID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
+
+func nullJSON(raw []byte) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
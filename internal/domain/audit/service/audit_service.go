@@ -0,0 +1,301 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/audit/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/audit/entity"
+	"github.com/kenziehh/cashflow-be/internal/domain/audit/repository"
+	"github.com/kenziehh/cashflow-be/pkg/audit"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/rbac"
+)
+
+// Actions recorded by the application. Other packages may use their own for
+// rarer events.
+const (
+	ActionRegister           = "register"
+	ActionLogin              = "login"
+	ActionLoginFailed        = "login_failed"
+	ActionLogout             = "logout"
+	ActionLogoutAll          = "logout_all"
+	ActionTransactionCreated = "transaction_created"
+	ActionTransactionUpdated = "transaction_updated"
+	ActionTransactionDeleted = "transaction_deleted"
+	ActionTransferCreated    = "transfer_created"
+	ActionTransferDeleted    = "transfer_deleted"
+	ActionRecurringCreated   = "recurring_transaction_created"
+	ActionRecurringUpdated   = "recurring_transaction_updated"
+	ActionRecurringDeleted   = "recurring_transaction_deleted"
+	ActionLimitsUpdated      = "limits_updated"
+	// Money accounts; "account_deleted" is the erasure of a user account
+	ActionAccountCreated = "money_account_created"
+	ActionAccountUpdated = "money_account_updated"
+	ActionAccountDeleted = "money_account_deleted"
+	ActionAPIKeyCreated  = "api_key_created"
+	ActionAPIKeyRevoked  = "api_key_revoked"
+	// ActionImpersonatedRequest is recorded for every request made with an
+	// impersonation token, under the impersonated user
+	ActionImpersonatedRequest = "impersonated_request"
+	// ActionAuditRedacted records that a deleted account's entries lost their
+	// personal data, see RedactUser
+	ActionAuditRedacted = "audit_redacted"
+)
+
+const (
+	TargetUser         = "user"
+	TargetTransaction  = "transaction"
+	TargetTransfer     = "transfer"
+	TargetRecurring    = "recurring_transaction"
+	TargetAccount      = "money_account"
+	TargetMaximumSpend = "maximum_spend"
+	TargetAPIKey       = "api_key"
+)
+
+const verifyBatchSize = 500
+
+// Event describes something worth recording. UserID defaults to the
+// authenticated user (the impersonated one for impersonation tokens);
+// Before and After are any JSON serializable values (nil for creations and
+// deletions) and are stored as a per-field diff.
+type Event struct {
+	UserID     uuid.UUID
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     any
+	After      any
+	Metadata   map[string]any
+}
+
+type AuditService interface {
+	Record(ctx context.Context, event Event) error
+	ListAuditLogs(ctx context.Context, requesterID uuid.UUID, role string, params dto.AuditLogListParams) (dto.PaginatedAuditLogsResponse, error)
+	VerifyChain(ctx context.Context) (*dto.VerifyAuditLogResponse, error)
+	RedactUser(ctx context.Context, userID uuid.UUID, email string) (int, error)
+}
+
+type auditService struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) AuditService {
+	return &auditService{
+		repo: repo,
+	}
+}
+
+// Record appends an entry. The actor, IP and user agent come from the request
+// context (see middleware.AuditContext).
+func (s *auditService) Record(ctx context.Context, event Event) error {
+	changes, err := diff(event.Before, event.After)
+	if err != nil {
+		log.Printf("[AUDIT ERROR] diff for %s: %v", event.Action, err)
+		return errx.ErrInternalServer
+	}
+
+	var metadata json.RawMessage
+	if len(event.Metadata) > 0 {
+		if metadata, err = json.Marshal(event.Metadata); err != nil {
+			return errx.ErrInternalServer
+		}
+	}
+
+	client := audit.ClientFromContext(ctx)
+	entry := &entity.AuditLog{
+		ID:         uuid.New(),
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		Changes:    changes,
+		Metadata:   metadata,
+		// PostgreSQL keeps microseconds; the hash must match what is read back
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	if actorID, ok := audit.ActorFromContext(ctx); ok {
+		entry.ActorID = &actorID
+	}
+	if event.UserID != uuid.Nil {
+		entry.UserID = &event.UserID
+	} else if userID, ok := audit.UserFromContext(ctx); ok {
+		entry.UserID = &userID
+	}
+
+	return s.repo.Append(ctx, entry)
+}
+
+// ListAuditLogs returns the requester's own entries; roles allowed to view
+// the audit log may see and filter every entry.
+func (s *auditService) ListAuditLogs(ctx context.Context, requesterID uuid.UUID, role string, params dto.AuditLogListParams) (dto.PaginatedAuditLogsResponse, error) {
+	if !rbac.HasPermission(role, rbac.PermViewAuditLogs) {
+		params.UserID = requesterID.String()
+	}
+	return s.repo.List(ctx, params)
+}
+
+// RedactUser removes the IP addresses, user agents, diffs and metadata left
+// in the audit trail by an account that is being deleted, including failed
+// logins that named its email. The entries keep their place in the chain and
+// an audit_redacted entry vouches for what is left of them.
+func (s *auditService) RedactUser(ctx context.Context, userID uuid.UUID, email string) (int, error) {
+	redaction := &entity.AuditLog{
+		ID:         uuid.New(),
+		UserID:     &userID,
+		Action:     ActionAuditRedacted,
+		TargetType: TargetUser,
+		TargetID:   userID.String(),
+		CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+	}
+	return s.repo.Redact(ctx, userID, email, redaction)
+}
+
+// VerifyChain recomputes every hash from the oldest chained entry to the
+// newest and stops at the first mismatch. The hash of a redacted entry covers
+// data that is gone, so what is left of it is checked against the digest in
+// its redaction entry instead.
+func (s *auditService) VerifyChain(ctx context.Context) (*dto.VerifyAuditLogResponse, error) {
+	result := &dto.VerifyAuditLogResponse{Valid: true}
+	broken := func(seq int64) (*dto.VerifyAuditLogResponse, error) {
+		result.Valid = false
+		result.BrokenAtSeq = seq
+		return result, nil
+	}
+
+	// Redacted entries by the seq of the redaction entry that covers them
+	type pendingRedaction struct {
+		firstSeq int64
+		digest   *entity.RedactionDigest
+	}
+	pending := map[int64]*pendingRedaction{}
+
+	var afterSeq int64
+	prevHash := ""
+	for {
+		entries, err := s.repo.ListChain(ctx, afterSeq, verifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if entry.PrevHash != prevHash {
+				return broken(entry.Seq)
+			}
+
+			if entry.RedactedBy != 0 {
+				if entry.RedactedBy <= entry.Seq {
+					return broken(entry.Seq)
+				}
+				p, ok := pending[entry.RedactedBy]
+				if !ok {
+					p = &pendingRedaction{firstSeq: entry.Seq, digest: entity.NewRedactionDigest()}
+					pending[entry.RedactedBy] = p
+				}
+				p.digest.Add(entry)
+				result.Redacted++
+			} else if entry.ComputeHash(prevHash) != entry.Hash {
+				return broken(entry.Seq)
+			}
+
+			if entry.Action == ActionAuditRedacted && entry.RedactedBy == 0 {
+				var redaction entity.Redaction
+				if err := json.Unmarshal(entry.Metadata, &redaction); err != nil {
+					return broken(entry.Seq)
+				}
+				digest := entity.NewRedactionDigest()
+				if p, ok := pending[entry.Seq]; ok {
+					digest = p.digest
+				}
+				if digest.Entries != redaction.Entries || digest.Sum() != redaction.Digest {
+					return broken(entry.Seq)
+				}
+				delete(pending, entry.Seq)
+			}
+
+			prevHash = entry.Hash
+			result.Checked++
+			result.HeadSeq = entry.Seq
+			result.HeadHash = entry.Hash
+		}
+
+		if len(entries) < verifyBatchSize {
+			break
+		}
+		afterSeq = entries[len(entries)-1].Seq
+	}
+
+	// A redacted entry whose redaction entry never came was tampered with
+	if len(pending) > 0 {
+		first := int64(0)
+		for _, p := range pending {
+			if first == 0 || p.firstSeq < first {
+				first = p.firstSeq
+			}
+		}
+		return broken(first)
+	}
+
+	return result, nil
+}
+
+// diff returns the fields that differ between before and after, keyed by
+// their JSON name, or nil when there is nothing to compare.
+func diff(before, after any) (json.RawMessage, error) {
+	if before == nil && after == nil {
+		return nil, nil
+	}
+
+	beforeFields, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]entity.Change{}
+	for key, value := range beforeFields {
+		if other, ok := afterFields[key]; !ok || !reflect.DeepEqual(value, other) {
+			changes[key] = entity.Change{Before: value, After: afterFields[key]}
+		}
+	}
+	for key, value := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			changes[key] = entity.Change{After: value}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(changes)
+}
+
+func toFields(v any) (map[string]any, error) {
+	fields := map[string]any{}
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil() {
+		return fields, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers stay as written so money amounts keep every digit
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
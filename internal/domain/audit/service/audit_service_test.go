@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/audit/entity"
+	"github.com/kenziehh/cashflow-be/internal/domain/audit/repository"
+	"github.com/kenziehh/cashflow-be/pkg/audit"
+)
+
+// fakeRepository keeps the chain in memory and hands out copies, as reading
+// rows back from the database would.
+type fakeRepository struct {
+	repository.AuditRepository
+
+	entries []*entity.AuditLog
+}
+
+func (r *fakeRepository) Append(_ context.Context, entry *entity.AuditLog) error {
+	entry.PrevHash = ""
+	if len(r.entries) > 0 {
+		entry.PrevHash = r.entries[len(r.entries)-1].Hash
+	}
+	entry.Hash = entry.ComputeHash(entry.PrevHash)
+	entry.Seq = int64(len(r.entries) + 1)
+
+	stored := *entry
+	r.entries = append(r.entries, &stored)
+	return nil
+}
+
+func (r *fakeRepository) ListChain(_ context.Context, afterSeq int64, limit int) ([]*entity.AuditLog, error) {
+	entries := []*entity.AuditLog{}
+	for _, entry := range r.entries {
+		if entry.Seq > afterSeq && len(entries) < limit {
+			found := *entry
+			entries = append(entries, &found)
+		}
+	}
+	return entries, nil
+}
+
+func (r *fakeRepository) Redact(ctx context.Context, userID uuid.UUID, email string, redaction *entity.AuditLog) (int, error) {
+	var entries []*entity.AuditLog
+	for _, entry := range r.entries {
+		if entry.RedactedBy != 0 || entry.Action == redaction.Action {
+			continue
+		}
+		var metadata struct {
+			Email string `json:"email"`
+		}
+		json.Unmarshal(entry.Metadata, &metadata)
+
+		if entry.UserID != nil && *entry.UserID == userID ||
+			entry.ActorID != nil && *entry.ActorID == userID ||
+			entry.UserID == nil && strings.EqualFold(metadata.Email, email) {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	if err := entity.Redact(entries, userID, redaction); err != nil {
+		return 0, err
+	}
+	if err := r.Append(ctx, redaction); err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		entry.RedactedBy = redaction.Seq
+	}
+	return len(entries), nil
+}
+
+type auditFixture struct {
+	service *auditService
+	repo    *fakeRepository
+	user    uuid.UUID
+	other   uuid.UUID
+}
+
+// newAuditFixture records a history for user, including a change they made
+// to another account as an admin, and entries that belong to somebody else.
+func newAuditFixture(t *testing.T) *auditFixture {
+	t.Helper()
+
+	repo := &fakeRepository{}
+	f := &auditFixture{
+		service: NewAuditService(repo).(*auditService),
+		repo:    repo,
+		user:    uuid.New(),
+		other:   uuid.New(),
+	}
+
+	client := audit.Client{IP: "203.0.113.7", UserAgent: "Mozilla/5.0"}
+	ctx := audit.WithClient(context.Background(), client)
+	userCtx := context.WithValue(ctx, "userID", f.user)
+	otherCtx := context.WithValue(ctx, "userID", f.other)
+
+	type limits struct {
+		Daily string `json:"daily"`
+	}
+	events := []struct {
+		ctx   context.Context
+		event Event
+	}{
+		{ctx, Event{Action: ActionLoginFailed, Metadata: map[string]any{"email": "Ada@Example.com"}}},
+		{ctx, Event{Action: ActionLoginFailed, Metadata: map[string]any{"email": "grace@example.com"}}},
+		{userCtx, Event{Action: ActionRegister}},
+		{userCtx, Event{Action: ActionLogin, Metadata: map[string]any{"method": "password"}}},
+		{otherCtx, Event{Action: ActionLogin, Metadata: map[string]any{"method": "password"}}},
+		{userCtx, Event{Action: ActionLimitsUpdated, Before: limits{Daily: "10.00"}, After: limits{Daily: "25.00"}}},
+		// The user, an admin, changes somebody else's limits
+		{userCtx, Event{UserID: f.other, Action: ActionLimitsUpdated, Before: limits{Daily: "1.00"}, After: limits{Daily: "2.00"}}},
+		{otherCtx, Event{Action: ActionLogout}},
+	}
+	for _, e := range events {
+		if err := f.service.Record(e.ctx, e.event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+func (f *auditFixture) verify(t *testing.T) (valid bool, brokenAt int64) {
+	t.Helper()
+
+	result, err := f.service.VerifyChain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.Valid, result.BrokenAtSeq
+}
+
+func TestRedactUser(t *testing.T) {
+	f := newAuditFixture(t)
+	ctx := context.Background()
+
+	redacted, err := f.service.RedactUser(ctx, f.user, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The failed login naming the email, the user's three entries and the
+	// one they made as an admin
+	if redacted != 5 {
+		t.Fatalf("RedactUser redacted %d entries, want 5", redacted)
+	}
+
+	redaction := f.repo.entries[len(f.repo.entries)-1]
+	if redaction.Action != ActionAuditRedacted || *redaction.UserID != f.user {
+		t.Fatalf("last entry = %+v, want the redaction", redaction)
+	}
+
+	for _, entry := range f.repo.entries[:len(f.repo.entries)-1] {
+		mine := entry.UserID != nil && *entry.UserID == f.user
+		byMe := entry.ActorID != nil && *entry.ActorID == f.user
+		if !mine && !byMe && entry.UserID != nil {
+			if entry.RedactedBy != 0 || entry.IP == "" {
+				t.Errorf("entry %d of another user was redacted", entry.Seq)
+			}
+			continue
+		}
+
+		if entry.UserID == nil && !strings.Contains(string(entry.Metadata), "grace") && entry.RedactedBy == 0 {
+			t.Errorf("failed login %d naming the email was not redacted", entry.Seq)
+		}
+		if entry.RedactedBy == 0 {
+			continue
+		}
+		if entry.RedactedBy != redaction.Seq {
+			t.Errorf("entry %d RedactedBy = %d, want %d", entry.Seq, entry.RedactedBy, redaction.Seq)
+		}
+		if entry.IP != "" || entry.UserAgent != "" {
+			t.Errorf("entry %d kept its client: %q %q", entry.Seq, entry.IP, entry.UserAgent)
+		}
+		if mine && (entry.Changes != nil || entry.Metadata != nil) {
+			t.Errorf("entry %d kept its diff or metadata", entry.Seq)
+		}
+		if !mine && entry.UserID != nil && entry.Changes == nil {
+			t.Errorf("entry %d about another user lost its diff", entry.Seq)
+		}
+	}
+
+	valid, brokenAt := f.verify(t)
+	if !valid {
+		t.Fatalf("chain broken at %d after a redaction", brokenAt)
+	}
+
+	// Redacting again finds nothing and leaves the chain intact
+	if redacted, err := f.service.RedactUser(ctx, f.user, "ada@example.com"); err != nil || redacted != 0 {
+		t.Fatalf("second RedactUser = %d, %v", redacted, err)
+	}
+	result, err := f.service.VerifyChain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Redacted != 5 || result.Checked != int64(len(f.repo.entries)) {
+		t.Fatalf("VerifyChain = %+v", result)
+	}
+}
+
+func TestVerifyChainDetectsTamperingAfterRedaction(t *testing.T) {
+	// Entry 3 is the user's registration, 5 another user's login, and 9 the
+	// redaction entry
+	tests := []struct {
+		name   string
+		tamper func(r *fakeRepository)
+		want   int64
+	}{
+		{
+			name:   "redaction entry cut off the end",
+			tamper: func(r *fakeRepository) { r.entries = r.entries[:8] },
+			want:   1,
+		},
+		{
+			name:   "redacted entry edited",
+			tamper: func(r *fakeRepository) { r.entries[2].Action = ActionLogin },
+			want:   9,
+		},
+		{
+			name:   "redaction mark removed",
+			tamper: func(r *fakeRepository) { r.entries[2].RedactedBy = 0 },
+			want:   3,
+		},
+		{
+			name: "edited entry marked as redacted",
+			tamper: func(r *fakeRepository) {
+				r.entries[4].Metadata = json.RawMessage(`{"method":"passkey"}`)
+				r.entries[4].RedactedBy = 9
+			},
+			want: 9,
+		},
+		{
+			name:   "redacted entry moved to another redaction",
+			tamper: func(r *fakeRepository) { r.entries[2].RedactedBy = 42 },
+			want:   9,
+		},
+		{
+			name:   "redaction entry edited",
+			tamper: func(r *fakeRepository) { r.entries[8].Metadata = json.RawMessage(`{"entries":0,"digest":""}`) },
+			want:   9,
+		},
+		{
+			name:   "redacted entry points backwards",
+			tamper: func(r *fakeRepository) { r.entries[2].RedactedBy = 2 },
+			want:   3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAuditFixture(t)
+			if _, err := f.service.RedactUser(context.Background(), f.user, "ada@example.com"); err != nil {
+				t.Fatal(err)
+			}
+			if len(f.repo.entries) != 9 {
+				t.Fatalf("fixture has %d entries, want 9", len(f.repo.entries))
+			}
+
+			tt.tamper(f.repo)
+
+			valid, brokenAt := f.verify(t)
+			if valid {
+				t.Fatal("tampering went unnoticed")
+			}
+			if brokenAt != tt.want {
+				t.Errorf("BrokenAtSeq = %d, want %d", brokenAt, tt.want)
+			}
+		})
+	}
+}
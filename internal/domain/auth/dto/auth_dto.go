@@ -1,8 +1,16 @@
 package dto
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
+)
+
 type RegisterRequest struct {
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required"`
 	Name     string `json:"name" validate:"required"`
 }
 
@@ -11,17 +19,210 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// AuthResponse carries either a token pair or, when the account has two-factor
+// authentication enabled, only a challenge token for POST /auth/login/mfa or
+// the passkey second factor endpoints. MFAMethods lists the usable factors.
 type AuthResponse struct {
-	Token string      `json:"access_token"`
-	User  UserProfile `json:"user"`
+	Token        string       `json:"access_token,omitempty"`
+	RefreshToken string       `json:"refresh_token,omitempty"`
+	ExpiresIn    int64        `json:"expires_in,omitempty"`
+	MFARequired  bool         `json:"mfa_required,omitempty"`
+	MFAToken     string       `json:"mfa_token,omitempty"`
+	MFAMethods   []string     `json:"mfa_methods,omitempty"`
+	User         *UserProfile `json:"user,omitempty"`
 }
 
 type UserProfile struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-	Name  string `json:"name"`
+	ID            string             `json:"id"`
+	Email         string             `json:"email"`
+	Name          string             `json:"name"`
+	Role          string             `json:"role"`
+	EmailVerified bool               `json:"email_verified"`
+	Preferences   entity.Preferences `json:"preferences"`
+	// DeletionScheduledAt is set while the account is waiting to be deleted
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
 }
 
+// UpdateProfileRequest only changes the fields that are set.
 type UpdateProfileRequest struct {
-	Name string `json:"name" validate:"required"`
-}
\ No newline at end of file
+	Name        string                    `json:"name" validate:"omitempty,max=100"`
+	Preferences *UpdatePreferencesRequest `json:"preferences"`
+}
+
+type UpdatePreferencesRequest struct {
+	Timezone  string `json:"timezone" validate:"omitempty,timezone" example:"Asia/Jakarta"`
+	Currency  string `json:"currency" validate:"omitempty,iso4217" example:"IDR"`
+	Locale    string `json:"locale" validate:"omitempty,bcp47_language_tag" example:"id-ID"`
+	WeekStart string `json:"week_start" validate:"omitempty,oneof=monday sunday saturday"`
+}
+
+// DeleteAccountRequest needs the password; accounts without one (OIDC or
+// passkey only) confirm by repeating their email address instead.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+type DeleteAccountResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	// Code is either a current TOTP code or an unused recovery code
+	Code string `json:"code" validate:"required"`
+}
+
+type TOTPEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TOTPConfirmRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type TOTPConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TOTPDisableRequest needs the password unless the account has none (OIDC
+// only accounts).
+type TOTPDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type MagicLinkLoginRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+type UnlockAccountRequest struct {
+	Email string `json:"email" validate:"required_without=IP,omitempty,email"`
+	IP    string `json:"ip" validate:"required_without=Email,omitempty,ip"`
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
+}
+
+// ImpersonateRequest asks for a read-only token acting as another user.
+// Reason is shown to that user in their audit log.
+type ImpersonateRequest struct {
+	Reason          string `json:"reason" validate:"required,max=500"`
+	DurationMinutes int    `json:"duration_minutes" validate:"omitempty,min=1,max=60"`
+}
+
+// ImpersonationResponse has no refresh token: the access token cannot be
+// renewed once it expires.
+type ImpersonationResponse struct {
+	Token     string       `json:"access_token"`
+	ExpiresIn int64        `json:"expires_in"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	User      *UserProfile `json:"user"`
+}
+
+// ClientInfo describes the device a request comes from.
+type ClientInfo struct {
+	IP             string
+	UserAgent      string
+	AcceptLanguage string
+}
+
+type LoginHistoryParams struct {
+	Page  int `query:"page" validate:"min=1"`
+	Limit int `query:"limit" validate:"min=1,max=100"`
+}
+
+type LoginHistoryResponse struct {
+	Data        []*entity.LoginEvent `json:"data"`
+	CurrentPage int                  `json:"current_page"`
+	Limit       int                  `json:"limit"`
+	TotalPage   int                  `json:"total_page"`
+}
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+type OIDCAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+}
+
+// PasskeyRegisterBeginRequest confirms who is adding a passkey: the password
+// when the account has one, and a TOTP or recovery code when 2FA is on.
+// Accounts with neither must have signed in within the last few minutes.
+type PasskeyRegisterBeginRequest struct {
+	Name     string `json:"name" validate:"omitempty,max=100"`
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// PasskeyDeleteRequest confirms who is removing a passkey, in the same way as
+// PasskeyRegisterBeginRequest.
+type PasskeyDeleteRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// PasskeyCredentialRequest wraps the PublicKeyCredential returned by
+// navigator.credentials.create() or .get(), serialized as JSON.
+type PasskeyCredentialRequest struct {
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+type PasskeyLoginBeginResponse struct {
+	SessionID string                        `json:"session_id"`
+	Options   *protocol.CredentialAssertion `json:"options"`
+}
+
+type PasskeyLoginFinishRequest struct {
+	SessionID  string          `json:"session_id" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+type PasskeyMFABeginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+}
+
+type PasskeyMFAFinishRequest struct {
+	MFAToken   string          `json:"mfa_token" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginEvent is one sign-in attempt. DeviceID is a fingerprint of the browser
+// or app, not of the network, so it stays the same when the IP changes.
+type LoginEvent struct {
+	ID            uuid.UUID  `json:"id"`
+	UserID        *uuid.UUID `json:"-"`
+	Email         string     `json:"-"`
+	Success       bool       `json:"success"`
+	Method        string     `json:"method,omitempty"`
+	FailureReason string     `json:"failure_reason,omitempty"`
+	IP            string     `json:"ip"`
+	UserAgent     string     `json:"user_agent"`
+	DeviceID      string     `json:"device_id"`
+	NewDevice     bool       `json:"new_device"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
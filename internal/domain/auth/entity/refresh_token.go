@@ -0,0 +1,15 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is the Redis record behind an opaque refresh token. Every token
+// issued by rotating another one shares the FamilyID of the original login.
+type RefreshToken struct {
+	UserID    uuid.UUID `json:"user_id"`
+	FamilyID  string    `json:"family_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
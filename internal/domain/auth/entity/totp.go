@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserTOTP holds a user's authenticator secret. Two-factor login is only
+// required once EnabledAt is set, i.e. after the user confirmed a code.
+type UserTOTP struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	Secret    string     `json:"-"`
+	EnabledAt *time.Time `json:"enabled_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -1,16 +1,48 @@
 package entity
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-}
\ No newline at end of file
+	ID              uuid.UUID   `json:"id"`
+	Email           string      `json:"email"`
+	Password        string      `json:"-"`
+	Name            string      `json:"name"`
+	Role            string      `json:"role"`
+	EmailVerifiedAt *time.Time  `json:"email_verified_at"`
+	Preferences     Preferences `json:"preferences"`
+	// DeletionScheduledAt is when the account will be erased, nil unless the
+	// user asked to close it
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// NormalizeEmail returns the form emails are stored and looked up in, so
+// addresses that differ only in case or surrounding spaces are one account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Preferences control how dates and amounts are presented to the user.
+// Timezone is an IANA name and decides what "today" means for summaries.
+type Preferences struct {
+	Timezone  string `json:"timezone"`
+	Currency  string `json:"currency"`
+	Locale    string `json:"locale"`
+	WeekStart string `json:"week_start"`
+}
+
+// DefaultPreferences mirror the column defaults of the users table.
+func DefaultPreferences() Preferences {
+	return Preferences{
+		Timezone:  "UTC",
+		Currency:  "IDR",
+		Locale:    "id-ID",
+		WeekStart: "monday",
+	}
+}
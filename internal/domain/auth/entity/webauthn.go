@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// WebAuthnCredential is a registered passkey. SignCount is the last
+// signature counter reported by the authenticator and is used to detect
+// cloned authenticators.
+type WebAuthnCredential struct {
+	ID              uuid.UUID  `json:"id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	CredentialID    []byte     `json:"-"`
+	Name            string     `json:"name"`
+	PublicKey       []byte     `json:"-"`
+	AttestationType string     `json:"-"`
+	AAGUID          []byte     `json:"-"`
+	SignCount       uint32     `json:"-"`
+	Transports      []string   `json:"transports"`
+	BackupEligible  bool       `json:"backup_eligible"`
+	BackupState     bool       `json:"backup_state"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// WebAuthnSession is the server side state of a registration or login
+// ceremony.
+type WebAuthnSession struct {
+	Data webauthn.SessionData `json:"data"`
+	Name string               `json:"name,omitempty"`
+}
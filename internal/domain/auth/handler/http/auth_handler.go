@@ -46,7 +46,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		return errx.NewBadRequestError(err.Error())
 	}
 
-	result, err := h.service.Register(c.Context(), &req)
+	result, err := h.service.Register(c.Context(), &req, clientInfo(c))
 	if err != nil {
 		return err
 	}
@@ -76,7 +76,42 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return errx.NewBadRequestError(err.Error())
 	}
 
-	result, err := h.service.Login(c.Context(), &req)
+	result, err := h.service.Login(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	if result.MFARequired {
+		return c.JSON(response.SuccessResponse("Two-factor authentication required", result))
+	}
+
+	return c.JSON(response.SuccessResponse("Login successful", result))
+}
+
+// LoginMFA godoc
+// @Summary Complete two-factor login
+// @Description Exchange the mfa_token from login and a TOTP or recovery code for a token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.LoginMFARequest true "Login MFA request"
+// @Success 200 {object} response.Response{data=dto.AuthResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/login/mfa [post]
+func (h *AuthHandler) LoginMFA(c *fiber.Ctx) error {
+	var req dto.LoginMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.LoginMFA(c.Context(), &req, clientInfo(c))
 	if err != nil {
 		return err
 	}
@@ -84,9 +119,39 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	return c.JSON(response.SuccessResponse("Login successful", result))
 }
 
+// Refresh godoc
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token and a rotated refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.RefreshRequest true "Refresh request"
+// @Success 200 {object} response.Response{data=dto.AuthResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req dto.RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.Refresh(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Token refreshed successfully", result))
+}
+
 // Logout godoc
 // @Summary User logout
-// @Description Logout and invalidate token
+// @Description Logout, invalidate the access token and revoke its refresh token
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -98,14 +163,141 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 	authHeader := c.Get("Authorization")
 	token := strings.TrimPrefix(authHeader, "Bearer ")
+	sessionID, _ := c.Locals("sessionID").(string)
 
-	if err := h.service.Logout(c.Context(), token); err != nil {
+	if err := h.service.Logout(c.Context(), token, sessionID); err != nil {
 		return err
 	}
 
 	return c.JSON(response.SuccessResponse("Logout successful", nil))
 }
 
+// LogoutAll godoc
+// @Summary Logout from all devices
+// @Description Invalidate every access and refresh token and revoke every API key of the current user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	if err := h.service.LogoutAll(c.Context(), userID); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Logged out from all devices", nil))
+}
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description List the devices the current user is signed in on
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]dto.SessionResponse}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+	sessionID, _ := c.Locals("sessionID").(string)
+
+	sessions, err := h.service.ListSessions(c.Context(), userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Sessions retrieved successfully", sessions))
+}
+
+// ListLoginHistory godoc
+// @Summary List recent sign-ins
+// @Description List the current user's successful and failed sign-in attempts, newest first
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.LoginHistoryResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/me/logins [get]
+func (h *AuthHandler) ListLoginHistory(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	var params dto.LoginHistoryParams
+	if err := c.QueryParser(&params); err != nil {
+		return errx.NewBadRequestError("Invalid query parameters")
+	}
+
+	if params.Page == 0 {
+		params.Page = 1
+	}
+	if params.Limit == 0 {
+		params.Limit = 20
+	}
+
+	if err := h.validate.Struct(params); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.ListLoginHistory(c.Context(), userID, params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Login history retrieved successfully", result))
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Sign out a single device of the current user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	sessionID := c.Params("id")
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return errx.NewBadRequestError("Invalid session ID format")
+	}
+
+	if err := h.service.RevokeSession(c.Context(), userID, sessionID); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Session revoked successfully", nil))
+}
+
 // GetProfile godoc
 // @Summary Get user profile
 // @Description Get current user profile
@@ -134,13 +326,13 @@ func (h *AuthHandler) GetProfile(c *fiber.Ctx) error {
 
 // UpdateProfile godoc
 // @Summary Update user profile
-// @Description Update current user profile
+// @Description Update the current user's name and preferences (timezone, currency, locale, week start); omitted fields are kept
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param request body dto.UpdateProfileRequest true "Update profile request"
-// @Success 200 {object} response.Response
+// @Success 200 {object} response.Response{data=dto.UserProfile}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 500 {object} response.Response
@@ -162,10 +354,815 @@ func (h *AuthHandler) UpdateProfile(c *fiber.Ctx) error {
 			return errx.NewBadRequestError(err.Error())
 		}
 
-		if err := h.service.UpdateProfile(c.Context(), id, &req); err != nil {
+		profile, err := h.service.UpdateProfile(c.Context(), id, &req)
+		if err != nil {
 			return err
 		}
 
-		return c.JSON(response.SuccessResponse("Profile updated successfully", nil))
+		return c.JSON(response.SuccessResponse("Profile updated successfully", profile))
+	}
+}
+
+// DeleteAccount godoc
+// @Summary Delete account
+// @Description Schedule the current user's account for deletion after a grace period and sign out everywhere. Requires the password; accounts without one confirm with their email address.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.DeleteAccountRequest true "Delete account request"
+// @Success 202 {object} response.Response{data=dto.DeleteAccountResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/me [delete]
+func (h *AuthHandler) DeleteAccount(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	var req dto.DeleteAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.DeleteAccount(c.Context(), userID, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(response.SuccessResponse("Account scheduled for deletion", result))
+}
+
+// CancelAccountDeletion godoc
+// @Summary Cancel account deletion
+// @Description Keep the current user's account when its deletion is still pending
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/me/deletion [delete]
+func (h *AuthHandler) CancelAccountDeletion(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	if err := h.service.CancelAccountDeletion(c.Context(), userID); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Account deletion cancelled", nil))
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Change the current user's password, sign out every other session and revoke every API key
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.ChangePasswordRequest true "Change password request"
+// @Success 200 {object} response.Response{data=dto.AuthResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/me/password [put]
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+	sessionID, _ := c.Locals("sessionID").(string)
+
+	var req dto.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.ChangePassword(c.Context(), userID, sessionID, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Password changed successfully", result))
+}
+
+// VerifyEmail godoc
+// @Summary Verify email address
+// @Description Confirm the email address using the token from the verification link
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.VerifyEmailRequest true "Verify email request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req dto.VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	if err := h.service.VerifyEmail(c.Context(), &req); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Email verified successfully", nil))
+}
+
+// ResendVerificationEmail godoc
+// @Summary Resend verification email
+// @Description Send a new verification link to the current user's email address
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/verify-email/resend [post]
+func (h *AuthHandler) ResendVerificationEmail(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	if err := h.service.ResendVerificationEmail(c.Context(), userID); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Verification email sent", nil))
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Email a single-use password reset link. The response is the same whether or not the email is registered.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ForgotPasswordRequest true "Forgot password request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/password/forgot [post]
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req dto.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	if err := h.service.ForgotPassword(c.Context(), &req); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("If the email is registered, a reset link has been sent", nil))
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Set a new password using a reset token, sign out every session and revoke every API key
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ResetPasswordRequest true "Reset password request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req dto.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	if err := h.service.ResetPassword(c.Context(), &req); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Password reset successfully", nil))
+}
+
+// EnrollTOTP godoc
+// @Summary Start TOTP enrollment
+// @Description Generate a new authenticator secret and otpauth URI. 2FA is enabled after confirmation.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.TOTPEnrollResponse}
+// @Failure 401 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/mfa/totp/enroll [post]
+func (h *AuthHandler) EnrollTOTP(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	result, err := h.service.EnrollTOTP(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Scan the QR code and confirm with a code", result))
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm TOTP enrollment
+// @Description Enable 2FA with a code from the authenticator app and receive recovery codes
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.TOTPConfirmRequest true "TOTP confirm request"
+// @Success 200 {object} response.Response{data=dto.TOTPConfirmResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/mfa/totp/confirm [post]
+func (h *AuthHandler) ConfirmTOTP(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	var req dto.TOTPConfirmRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.ConfirmTOTP(c.Context(), userID, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Two-factor authentication enabled", result))
+}
+
+// DisableTOTP godoc
+// @Summary Disable TOTP
+// @Description Turn off 2FA. Requires the password and a TOTP or recovery code.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.TOTPDisableRequest true "TOTP disable request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/mfa/totp/disable [post]
+func (h *AuthHandler) DisableTOTP(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	var req dto.TOTPDisableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	if err := h.service.DisableTOTP(c.Context(), userID, &req); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Two-factor authentication disabled", nil))
+}
+
+// UnlockAccount godoc
+// @Summary Unlock a locked-out login
+// @Description Clear failed login attempts and lockouts for an email and/or IP address
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.UnlockAccountRequest true "Unlock request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/auth/unlock [post]
+func (h *AuthHandler) UnlockAccount(c *fiber.Ctx) error {
+	var req dto.UnlockAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	if err := h.service.UnlockAccount(c.Context(), &req); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Login lockout cleared", nil))
+}
+
+// UpdateUserRole godoc
+// @Summary Change a user's role
+// @Description Grant or revoke the admin role. The user's current tokens are revoked.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body dto.UpdateRoleRequest true "Update role request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/users/{id}/role [put]
+func (h *AuthHandler) UpdateUserRole(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid user ID format")
+	}
+
+	var req dto.UpdateRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	if err := h.service.UpdateUserRole(c.Context(), actorID, userID, &req); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Role updated successfully", nil))
+}
+
+// Impersonate godoc
+// @Summary Impersonate a user
+// @Description Get a read-only access token that acts as the user, for support. It expires after duration_minutes (default 15, at most 60). Every request made with it is recorded in the user's audit log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body dto.ImpersonateRequest true "Impersonate request"
+// @Success 200 {object} response.Response{data=dto.ImpersonationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/users/{id}/impersonate [post]
+func (h *AuthHandler) Impersonate(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid user ID format")
+	}
+
+	var req dto.ImpersonateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.Impersonate(c.Context(), actorID, userID, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Impersonation token issued", result))
+}
+
+// BeginPasskeyRegistration godoc
+// @Summary Start passkey registration
+// @Description Get the options for navigator.credentials.create(). Requires the password and, with 2FA on, a TOTP or recovery code; accounts with neither must have signed in within the last 10 minutes.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.PasskeyRegisterBeginRequest true "PasskeyRegisterBeginRequest"
+// @Success 200 {object} response.Response{data=protocol.CredentialCreation}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/passkeys/register/begin [post]
+func (h *AuthHandler) BeginPasskeyRegistration(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+	sessionID, _ := c.Locals("sessionID").(string)
+
+	var req dto.PasskeyRegisterBeginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.BeginPasskeyRegistration(c.Context(), userID, sessionID, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Passkey registration started", result))
+}
+
+// FinishPasskeyRegistration godoc
+// @Summary Finish passkey registration
+// @Description Verify the credential from navigator.credentials.create() and save the passkey
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.PasskeyCredentialRequest true "PasskeyCredentialRequest"
+// @Success 201 {object} response.Response{data=entity.WebAuthnCredential}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/passkeys/register/finish [post]
+func (h *AuthHandler) FinishPasskeyRegistration(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	var req dto.PasskeyCredentialRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.FinishPasskeyRegistration(c.Context(), userID, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response.SuccessResponse("Passkey registered successfully", result))
+}
+
+// ListPasskeys godoc
+// @Summary List passkeys
+// @Description List the current user's passkeys
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]entity.WebAuthnCredential}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/passkeys [get]
+func (h *AuthHandler) ListPasskeys(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	passkeys, err := h.service.ListPasskeys(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Passkeys retrieved successfully", passkeys))
+}
+
+// DeletePasskey godoc
+// @Summary Delete a passkey
+// @Description Remove one of the current user's passkeys. Requires the same confirmation as registering one.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Passkey ID"
+// @Param request body dto.PasskeyDeleteRequest true "PasskeyDeleteRequest"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/passkeys/{id} [delete]
+func (h *AuthHandler) DeletePasskey(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+	sessionID, _ := c.Locals("sessionID").(string)
+
+	passkeyID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid passkey ID format")
+	}
+
+	var req dto.PasskeyDeleteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.service.DeletePasskey(c.Context(), userID, sessionID, passkeyID, &req); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Passkey deleted successfully", nil))
+}
+
+// BeginPasskeyLogin godoc
+// @Summary Start passkey login
+// @Description Get the options for navigator.credentials.get() to sign in with a passkey
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} response.Response{data=dto.PasskeyLoginBeginResponse}
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/passkeys/login/begin [post]
+func (h *AuthHandler) BeginPasskeyLogin(c *fiber.Ctx) error {
+	result, err := h.service.BeginPasskeyLogin(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Passkey login started", result))
+}
+
+// FinishPasskeyLogin godoc
+// @Summary Finish passkey login
+// @Description Exchange the assertion from navigator.credentials.get() for a token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.PasskeyLoginFinishRequest true "PasskeyLoginFinishRequest"
+// @Success 200 {object} response.Response{data=dto.AuthResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/passkeys/login/finish [post]
+func (h *AuthHandler) FinishPasskeyLogin(c *fiber.Ctx) error {
+	var req dto.PasskeyLoginFinishRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.FinishPasskeyLogin(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Login successful", result))
+}
+
+// BeginPasskeyMFA godoc
+// @Summary Start passkey second factor
+// @Description Get assertion options to answer the mfa_token from login with a passkey
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.PasskeyMFABeginRequest true "PasskeyMFABeginRequest"
+// @Success 200 {object} response.Response{data=protocol.CredentialAssertion}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/login/mfa/passkey/begin [post]
+func (h *AuthHandler) BeginPasskeyMFA(c *fiber.Ctx) error {
+	var req dto.PasskeyMFABeginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.BeginPasskeyMFA(c.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Passkey challenge created", result))
+}
+
+// FinishPasskeyMFA godoc
+// @Summary Finish passkey second factor
+// @Description Exchange the mfa_token and a passkey assertion for a token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.PasskeyMFAFinishRequest true "PasskeyMFAFinishRequest"
+// @Success 200 {object} response.Response{data=dto.AuthResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/login/mfa/passkey/finish [post]
+func (h *AuthHandler) FinishPasskeyMFA(c *fiber.Ctx) error {
+	var req dto.PasskeyMFAFinishRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.FinishPasskeyMFA(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Login successful", result))
+}
+
+// RequestMagicLink godoc
+// @Summary Request a magic login link
+// @Description Email a single-use sign-in link. The response is the same whether or not the email is registered.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.MagicLinkRequest true "Magic link request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/magic-link [post]
+func (h *AuthHandler) RequestMagicLink(c *fiber.Ctx) error {
+	var req dto.MagicLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	if err := h.service.RequestMagicLink(c.Context(), &req); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("If the email is registered, a sign-in link has been sent", nil))
+}
+
+// LoginMagicLink godoc
+// @Summary Login with a magic link
+// @Description Exchange the token from a magic link email for a token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.MagicLinkLoginRequest true "Magic link login request"
+// @Success 200 {object} response.Response{data=dto.AuthResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/magic-link/verify [post]
+func (h *AuthHandler) LoginMagicLink(c *fiber.Ctx) error {
+	var req dto.MagicLinkLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.LoginMagicLink(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	if result.MFARequired {
+		return c.JSON(response.SuccessResponse("Two-factor authentication required", result))
+	}
+
+	return c.JSON(response.SuccessResponse("Login successful", result))
+}
+
+// OIDCAuthorize godoc
+// @Summary Start OpenID Connect login
+// @Description Get the provider authorization URL (authorization code flow with PKCE). The frontend redirects the browser there.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} response.Response{data=dto.OIDCAuthorizeResponse}
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/oidc/authorize [get]
+func (h *AuthHandler) OIDCAuthorize(c *fiber.Ctx) error {
+	result, err := h.service.OIDCAuthorize(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Authorization URL created successfully", result))
+}
+
+// OIDCCallback godoc
+// @Summary Complete OpenID Connect login
+// @Description Exchange the code and state from the provider redirect for a token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.OIDCCallbackRequest true "OIDC callback request"
+// @Success 200 {object} response.Response{data=dto.AuthResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/oidc/callback [post]
+func (h *AuthHandler) OIDCCallback(c *fiber.Ctx) error {
+	var req dto.OIDCCallbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.OIDCCallback(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	if result.MFARequired {
+		return c.JSON(response.SuccessResponse("Two-factor authentication required", result))
+	}
+
+	return c.JSON(response.SuccessResponse("Login successful", result))
+}
+
+func clientInfo(c *fiber.Ctx) dto.ClientInfo {
+	return dto.ClientInfo{
+		IP:             c.IP(),
+		UserAgent:      c.Get(fiber.HeaderUserAgent),
+		AcceptLanguage: c.Get(fiber.HeaderAcceptLanguage),
 	}
 }
@@ -3,24 +3,77 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"time"
 
-	"github.com/kenziehh/cashflow-be/internal/domain/auth/dto"
 	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
 	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/jwt"
+	"github.com/kenziehh/cashflow-be/pkg/token"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const sessionTimeLayout = time.RFC3339
+
 type AuthRepository interface {
-	CreateUser(ctx context.Context, user *entity.User) error
+	CreateUser(ctx context.Context, user *entity.User, setup func(tx *sql.Tx) error) error
 	GetUserByEmail(ctx context.Context, email string) (*entity.User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (*entity.User, error)
-	StoreToken(ctx context.Context, userID uuid.UUID, token string, expiration time.Duration) error
+	StoreRefreshToken(ctx context.Context, token string, rt *entity.RefreshToken) error
+	ConsumeRefreshToken(ctx context.Context, token string) (*entity.RefreshToken, error)
+	GetRotatedRefreshTokenFamily(ctx context.Context, token string) (string, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeAllRefreshTokenFamilies(ctx context.Context, userID uuid.UUID) error
+	CreateSession(ctx context.Context, session *entity.Session, expiration time.Duration) error
+	GetSession(ctx context.Context, sessionID string) (*entity.Session, error)
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]*entity.Session, error)
+	TouchSession(ctx context.Context, sessionID string, ip string) (bool, error)
+	StorePasswordResetToken(ctx context.Context, userID uuid.UUID, resetToken string, expiration time.Duration) error
+	ConsumePasswordResetToken(ctx context.Context, resetToken string) (uuid.UUID, error)
+	StoreMagicLinkToken(ctx context.Context, userID uuid.UUID, linkToken string, expiration time.Duration) error
+	ConsumeMagicLinkToken(ctx context.Context, linkToken string) (uuid.UUID, error)
+	GetTokenVersion(ctx context.Context, userID uuid.UUID) (int64, error)
+	IncrementTokenVersion(ctx context.Context, userID uuid.UUID) (int64, error)
 	DeleteToken(ctx context.Context, token string) error
 	IsTokenBlacklisted(ctx context.Context, token string) (bool, error)
-	UpdateProfile(ctx context.Context, userID uuid.UUID, req *dto.UpdateProfileRequest) error
+	UpdateProfile(ctx context.Context, userID uuid.UUID, name string, prefs entity.Preferences) error
+	UpdatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error
+	ReplacePasswordHash(ctx context.Context, userID uuid.UUID, oldHash, newHash string) error
+	MarkEmailVerified(ctx context.Context, userID uuid.UUID) error
+	UpdateRole(ctx context.Context, userID uuid.UUID, role string) error
+	AcquireCooldown(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	ReleaseCooldown(ctx context.Context, key string) error
+	IncrementCounter(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	RecordLoginFailure(ctx context.Context, subject string, window time.Duration) (int64, error)
+	GetLoginLock(ctx context.Context, subject string) (time.Duration, error)
+	LockLogin(ctx context.Context, subject string, duration time.Duration) error
+	ClearLoginFailures(ctx context.Context, subject string) error
+	GetTOTP(ctx context.Context, userID uuid.UUID) (*entity.UserTOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error
+	EnableTOTP(ctx context.Context, userID uuid.UUID, recoveryCodeHashes []string) error
+	DeleteTOTP(ctx context.Context, userID uuid.UUID) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	CreateWebAuthnCredential(ctx context.Context, cred *entity.WebAuthnCredential) error
+	ListWebAuthnCredentials(ctx context.Context, userID uuid.UUID) ([]*entity.WebAuthnCredential, error)
+	UpdateWebAuthnCredentialUsage(ctx context.Context, credentialID []byte, signCount uint32, backupState bool) error
+	DeleteWebAuthnCredential(ctx context.Context, userID, id uuid.UUID) error
+	StoreWebAuthnSession(ctx context.Context, key string, session *entity.WebAuthnSession, expiration time.Duration) error
+	ConsumeWebAuthnSession(ctx context.Context, key string) (*entity.WebAuthnSession, error)
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (*entity.User, error)
+	CreateUserIdentity(ctx context.Context, identity *entity.UserIdentity) error
+	CreateLoginEvent(ctx context.Context, event *entity.LoginEvent) error
+	ListLoginEvents(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*entity.LoginEvent, int, error)
+	RememberDevice(ctx context.Context, userID uuid.UUID, deviceID string, ttl time.Duration) (isNew bool, firstDevice bool, err error)
+	ScheduleAccountDeletion(ctx context.Context, userID uuid.UUID, deleteAt time.Time) error
+	CancelAccountDeletion(ctx context.Context, userID uuid.UUID) (bool, error)
+	ListAccountsDueForDeletion(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error)
+	DeleteUser(ctx context.Context, userID uuid.UUID, now time.Time) (bool, error)
+	StoreOIDCState(ctx context.Context, state string, st *entity.OIDCState, expiration time.Duration) error
+	ConsumeOIDCState(ctx context.Context, state string) (*entity.OIDCState, error)
 }
 
 type authRepository struct {
@@ -35,17 +88,32 @@ func NewAuthRepository(db *sql.DB, redis *redis.Client) AuthRepository {
 	}
 }
 
-func (r *authRepository) CreateUser(ctx context.Context, user *entity.User) error {
+// CreateUser stores the user and runs setup, when given, in the same
+// transaction, so the user never exists without what setup creates.
+func (r *authRepository) CreateUser(ctx context.Context, user *entity.User, setup func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	defer tx.Rollback()
+
+	user.Email = entity.NormalizeEmail(user.Email)
+
 	query := `
-		INSERT INTO users (id, email, password, name, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO users (id, email, password, name, role, timezone, currency, locale, week_start, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		user.ID,
 		user.Email,
 		user.Password,
 		user.Name,
+		user.Role,
+		user.Preferences.Timezone,
+		user.Preferences.Currency,
+		user.Preferences.Locale,
+		user.Preferences.WeekStart,
 		user.CreatedAt,
 		user.UpdatedAt,
 	)
@@ -54,22 +122,40 @@ func (r *authRepository) CreateUser(ctx context.Context, user *entity.User) erro
 		return errx.ErrDatabaseError
 	}
 
+	if setup != nil {
+		if err := setup(tx); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errx.ErrDatabaseError
+	}
+
 	return nil
 }
 
 func (r *authRepository) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
 	query := `
-		SELECT id, email, password, name, created_at, updated_at
+		SELECT id, email, COALESCE(password, ''), name, role, email_verified_at,
+			timezone, currency, locale, week_start, deletion_scheduled_at, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
 
 	user := &entity.User{}
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
+	err := r.db.QueryRowContext(ctx, query, entity.NormalizeEmail(email)).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Password,
 		&user.Name,
+		&user.Role,
+		&user.EmailVerifiedAt,
+		&user.Preferences.Timezone,
+		&user.Preferences.Currency,
+		&user.Preferences.Locale,
+		&user.Preferences.WeekStart,
+		&user.DeletionScheduledAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -87,7 +173,8 @@ func (r *authRepository) GetUserByEmail(ctx context.Context, email string) (*ent
 
 func (r *authRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
 	query := `
-		SELECT id, email, password, name, created_at, updated_at
+		SELECT id, email, COALESCE(password, ''), name, role, email_verified_at,
+			timezone, currency, locale, week_start, deletion_scheduled_at, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -98,6 +185,13 @@ func (r *authRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*entity
 		&user.Email,
 		&user.Password,
 		&user.Name,
+		&user.Role,
+		&user.EmailVerifiedAt,
+		&user.Preferences.Timezone,
+		&user.Preferences.Currency,
+		&user.Preferences.Locale,
+		&user.Preferences.WeekStart,
+		&user.DeletionScheduledAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -113,14 +207,694 @@ func (r *authRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*entity
 	return user, nil
 }
 
-func (r *authRepository) UpdateProfile(ctx context.Context, userID uuid.UUID, req *dto.UpdateProfileRequest) error {
+func (r *authRepository) UpdateProfile(ctx context.Context, userID uuid.UUID, name string, prefs entity.Preferences) error {
+	query := `
+		UPDATE users
+		SET name = $1, timezone = $2, currency = $3, locale = $4, week_start = $5, updated_at = $6
+		WHERE id = $7
+	`
+
+	_, err := r.db.ExecContext(ctx, query, name, prefs.Timezone, prefs.Currency, prefs.Locale, prefs.WeekStart, time.Now(), userID)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *authRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error {
+	query := `
+		UPDATE users
+		SET password = NULLIF($1, ''), updated_at = $2
+		WHERE id = $3
+	`
+
+	_, err := r.db.ExecContext(ctx, query, hashedPassword, time.Now(), userID)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// ReplacePasswordHash swaps the stored hash for an equivalent one (same
+// password, newer algorithm). It does nothing if the password changed since
+// oldHash was read.
+func (r *authRepository) ReplacePasswordHash(ctx context.Context, userID uuid.UUID, oldHash, newHash string) error {
+	query := `
+		UPDATE users
+		SET password = $1
+		WHERE id = $2 AND password = $3
+	`
+
+	_, err := r.db.ExecContext(ctx, query, newHash, userID, oldHash)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *authRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = $1, updated_at = $1
+		WHERE id = $2 AND email_verified_at IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), userID)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *authRepository) UpdateRole(ctx context.Context, userID uuid.UUID, role string) error {
+	query := `
+		UPDATE users
+		SET role = $1, updated_at = $2
+		WHERE id = $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, role, time.Now(), userID)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	if affected == 0 {
+		return errx.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// AcquireCooldown returns false while a previous call with the same key is
+// still within ttl.
+func (r *authRepository) AcquireCooldown(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ok, err := r.redis.SetNX(ctx, "cooldown:"+key, "1", ttl).Result()
+	if err != nil {
+		return false, errx.ErrRedisError
+	}
+	return ok, nil
+}
+
+// ReleaseCooldown ends a cooldown early, so the next AcquireCooldown for key
+// succeeds.
+func (r *authRepository) ReleaseCooldown(ctx context.Context, key string) error {
+	if err := r.redis.Del(ctx, "cooldown:"+key).Err(); err != nil {
+		return errx.ErrRedisError
+	}
+	return nil
+}
+
+func (r *authRepository) CreateLoginEvent(ctx context.Context, event *entity.LoginEvent) error {
+	query := `
+		INSERT INTO login_history (id, user_id, email, success, method, failure_reason, ip, user_agent, device_id, new_device, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9, $10, $11)
+	`
+
+	var userID interface{}
+	if event.UserID != nil {
+		userID = *event.UserID
+	}
+
+	_, err := r.db.ExecContext(ctx, query,
+		event.ID,
+		userID,
+		event.Email,
+		event.Success,
+		event.Method,
+		event.FailureReason,
+		event.IP,
+		event.UserAgent,
+		event.DeviceID,
+		event.NewDevice,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *authRepository) ListLoginEvents(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*entity.LoginEvent, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM login_history WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, errx.ErrDatabaseError
+	}
+
+	query := `
+		SELECT id, success, COALESCE(method, ''), COALESCE(failure_reason, ''), ip, user_agent, device_id, new_device, created_at
+		FROM login_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, errx.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	events := []*entity.LoginEvent{}
+	for rows.Next() {
+		event := &entity.LoginEvent{}
+		err := rows.Scan(
+			&event.ID,
+			&event.Success,
+			&event.Method,
+			&event.FailureReason,
+			&event.IP,
+			&event.UserAgent,
+			&event.DeviceID,
+			&event.NewDevice,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, errx.ErrDatabaseError
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, errx.ErrDatabaseError
+	}
+
+	return events, total, nil
+}
+
+// RememberDevice adds the device to the user's known devices and reports
+// whether it was unknown, and whether it is the first device at all. The set
+// expires ttl after the last login.
+func (r *authRepository) RememberDevice(ctx context.Context, userID uuid.UUID, deviceID string, ttl time.Duration) (bool, bool, error) {
+	key := "known_devices:" + userID.String()
+
+	pipe := r.redis.TxPipeline()
+	known := pipe.SCard(ctx, key)
+	added := pipe.SAdd(ctx, key, deviceID)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, false, errx.ErrRedisError
+	}
+
+	return added.Val() == 1, known.Val() == 0, nil
+}
+
+// ScheduleAccountDeletion marks the account for deletion at deleteAt.
+func (r *authRepository) ScheduleAccountDeletion(ctx context.Context, userID uuid.UUID, deleteAt time.Time) error {
 	query := `
 		UPDATE users
-		SET name = $1, updated_at = $2
+		SET deletion_scheduled_at = $1, updated_at = $2
 		WHERE id = $3
 	`
 
-	_, err := r.db.ExecContext(ctx, query, req.Name, time.Now(), userID)
+	if _, err := r.db.ExecContext(ctx, query, deleteAt, time.Now(), userID); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// CancelAccountDeletion reports whether a deletion was pending.
+func (r *authRepository) CancelAccountDeletion(ctx context.Context, userID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE users
+		SET deletion_scheduled_at = NULL, updated_at = $1
+		WHERE id = $2 AND deletion_scheduled_at IS NOT NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), userID)
+	if err != nil {
+		return false, errx.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errx.ErrDatabaseError
+	}
+
+	return affected > 0, nil
+}
+
+// ListAccountsDueForDeletion returns accounts whose grace period ended
+// before now, oldest first.
+func (r *authRepository) ListAccountsDueForDeletion(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM users
+		WHERE deletion_scheduled_at <= $1
+		ORDER BY deletion_scheduled_at
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, errx.ErrDatabaseError
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	return ids, nil
+}
+
+// DeleteUser erases an account whose deletion is due, together with every
+// row that cascades from it and the failed logins recorded against its email
+// before it was known. It reports false when the deletion was cancelled in
+// the meantime.
+func (r *authRepository) DeleteUser(ctx context.Context, userID uuid.UUID, now time.Time) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, errx.ErrDatabaseError
+	}
+	defer tx.Rollback()
+
+	var email string
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM users
+		WHERE id = $1 AND deletion_scheduled_at <= $2
+		RETURNING email
+	`, userID, now).Scan(&email)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errx.ErrDatabaseError
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM login_history WHERE user_id IS NULL AND email = $1`, email); err != nil {
+		return false, errx.ErrDatabaseError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errx.ErrDatabaseError
+	}
+
+	// The set expires on its own if this fails
+	r.redis.Del(ctx, "known_devices:"+userID.String())
+
+	return true, nil
+}
+
+// IncrementCounter increments key and starts its ttl on the first hit.
+func (r *authRepository) IncrementCounter(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	key = "counter:" + key
+	count, err := r.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, errx.ErrRedisError
+	}
+	if count == 1 {
+		if err := r.redis.Expire(ctx, key, ttl).Err(); err != nil {
+			return 0, errx.ErrRedisError
+		}
+	}
+	return count, nil
+}
+
+// RecordLoginFailure counts a failed login for subject (an email or IP key)
+// and returns the number of failures within the window.
+func (r *authRepository) RecordLoginFailure(ctx context.Context, subject string, window time.Duration) (int64, error) {
+	key := "login_fail:" + subject
+	count, err := r.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, errx.ErrRedisError
+	}
+	if err := r.redis.Expire(ctx, key, window).Err(); err != nil {
+		return 0, errx.ErrRedisError
+	}
+	return count, nil
+}
+
+// GetLoginLock returns how long subject stays locked out, or zero.
+func (r *authRepository) GetLoginLock(ctx context.Context, subject string) (time.Duration, error) {
+	ttl, err := r.redis.PTTL(ctx, "login_lock:"+subject).Result()
+	if err != nil {
+		return 0, errx.ErrRedisError
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (r *authRepository) LockLogin(ctx context.Context, subject string, duration time.Duration) error {
+	if err := r.redis.Set(ctx, "login_lock:"+subject, "1", duration).Err(); err != nil {
+		return errx.ErrRedisError
+	}
+	return nil
+}
+
+func (r *authRepository) ClearLoginFailures(ctx context.Context, subject string) error {
+	if err := r.redis.Del(ctx, "login_fail:"+subject, "login_lock:"+subject).Err(); err != nil {
+		return errx.ErrRedisError
+	}
+	return nil
+}
+
+func (r *authRepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*entity.UserTOTP, error) {
+	query := `
+		SELECT user_id, secret, enabled_at, created_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+
+	t := &entity.UserTOTP{}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&t.UserID,
+		&t.Secret,
+		&t.EnabledAt,
+		&t.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, errx.ErrTOTPNotEnrolled
+	}
+
+	if err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	return t, nil
+}
+
+// SaveTOTPSecret stores a new, not yet enabled secret for the user.
+func (r *authRepository) SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret, enabled_at, created_at)
+		VALUES ($1, $2, NULL, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret,
+			enabled_at = NULL,
+			created_at = EXCLUDED.created_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, secret, time.Now())
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// EnableTOTP turns on two-factor login and replaces the recovery codes.
+func (r *authRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, recoveryCodeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `UPDATE user_totp SET enabled_at = $1 WHERE user_id = $2`, now, userID); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO user_recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)`,
+			uuid.New(), userID, hash, now,
+		)
+		if err != nil {
+			return errx.ErrDatabaseError
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *authRepository) DeleteTOTP(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// UseRecoveryCode redeems an unused recovery code and reports whether it was
+// valid.
+func (r *authRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE user_recovery_codes
+		SET used_at = $1
+		WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), userID, codeHash)
+	if err != nil {
+		return false, errx.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errx.ErrDatabaseError
+	}
+
+	return affected > 0, nil
+}
+
+func (r *authRepository) CreateWebAuthnCredential(ctx context.Context, cred *entity.WebAuthnCredential) error {
+	query := `
+		INSERT INTO webauthn_credentials (id, user_id, credential_id, name, public_key, attestation_type, aaguid,
+			sign_count, transports, backup_eligible, backup_state, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		cred.ID,
+		cred.UserID,
+		cred.CredentialID,
+		cred.Name,
+		cred.PublicKey,
+		cred.AttestationType,
+		cred.AAGUID,
+		int64(cred.SignCount),
+		pq.Array(cred.Transports),
+		cred.BackupEligible,
+		cred.BackupState,
+		cred.CreatedAt,
+	)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return errx.ErrPasskeyAlreadyRegistered
+	}
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *authRepository) ListWebAuthnCredentials(ctx context.Context, userID uuid.UUID) ([]*entity.WebAuthnCredential, error) {
+	query := `
+		SELECT id, user_id, credential_id, name, public_key, attestation_type, aaguid,
+			sign_count, transports, backup_eligible, backup_state, last_used_at, created_at
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	creds := []*entity.WebAuthnCredential{}
+	for rows.Next() {
+		c := &entity.WebAuthnCredential{}
+		var signCount int64
+		if err := rows.Scan(
+			&c.ID,
+			&c.UserID,
+			&c.CredentialID,
+			&c.Name,
+			&c.PublicKey,
+			&c.AttestationType,
+			&c.AAGUID,
+			&signCount,
+			pq.Array(&c.Transports),
+			&c.BackupEligible,
+			&c.BackupState,
+			&c.LastUsedAt,
+			&c.CreatedAt,
+		); err != nil {
+			return nil, errx.ErrDatabaseError
+		}
+		c.SignCount = uint32(signCount)
+		creds = append(creds, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	return creds, nil
+}
+
+func (r *authRepository) UpdateWebAuthnCredentialUsage(ctx context.Context, credentialID []byte, signCount uint32, backupState bool) error {
+	query := `
+		UPDATE webauthn_credentials
+		SET sign_count = $1, backup_state = $2, last_used_at = $3
+		WHERE credential_id = $4
+	`
+
+	_, err := r.db.ExecContext(ctx, query, int64(signCount), backupState, time.Now(), credentialID)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *authRepository) DeleteWebAuthnCredential(ctx context.Context, userID, id uuid.UUID) error {
+	query := `
+		DELETE FROM webauthn_credentials
+		WHERE id = $1 AND user_id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	if affected == 0 {
+		return errx.ErrPasskeyNotFound
+	}
+
+	return nil
+}
+
+func (r *authRepository) StoreWebAuthnSession(ctx context.Context, key string, session *entity.WebAuthnSession, expiration time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return errx.ErrInternalServer
+	}
+
+	if err := r.redis.Set(ctx, "webauthn_session:"+key, data, expiration).Err(); err != nil {
+		return errx.ErrRedisError
+	}
+	return nil
+}
+
+// ConsumeWebAuthnSession returns and deletes a ceremony so its challenge can
+// only be answered once.
+func (r *authRepository) ConsumeWebAuthnSession(ctx context.Context, key string) (*entity.WebAuthnSession, error) {
+	data, err := r.redis.GetDel(ctx, "webauthn_session:"+key).Bytes()
+	if err == redis.Nil {
+		return nil, errx.ErrInvalidPasskeySession
+	}
+	if err != nil {
+		return nil, errx.ErrRedisError
+	}
+
+	session := &entity.WebAuthnSession{}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, errx.ErrInvalidPasskeySession
+	}
+	return session, nil
+}
+
+func (r *authRepository) GetUserByIdentity(ctx context.Context, issuer, subject string) (*entity.User, error) {
+	query := `
+		SELECT u.id, u.email, COALESCE(u.password, ''), u.name, u.role, u.email_verified_at,
+			u.timezone, u.currency, u.locale, u.week_start, u.deletion_scheduled_at, u.created_at, u.updated_at
+		FROM users u
+		JOIN user_identities i ON i.user_id = u.id
+		WHERE i.issuer = $1 AND i.subject = $2
+	`
+
+	user := &entity.User{}
+	err := r.db.QueryRowContext(ctx, query, issuer, subject).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Password,
+		&user.Name,
+		&user.Role,
+		&user.EmailVerifiedAt,
+		&user.Preferences.Timezone,
+		&user.Preferences.Currency,
+		&user.Preferences.Locale,
+		&user.Preferences.WeekStart,
+		&user.DeletionScheduledAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, errx.ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	return user, nil
+}
+
+func (r *authRepository) CreateUserIdentity(ctx context.Context, identity *entity.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (id, user_id, issuer, subject, email, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		identity.ID,
+		identity.UserID,
+		identity.Issuer,
+		identity.Subject,
+		identity.Email,
+		identity.CreatedAt,
+	)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return errx.ErrIdentityAlreadyLinked
+	}
 	if err != nil {
 		return errx.ErrDatabaseError
 	}
@@ -128,18 +902,352 @@ func (r *authRepository) UpdateProfile(ctx context.Context, userID uuid.UUID, re
 	return nil
 }
 
-func (r *authRepository) StoreToken(ctx context.Context, userID uuid.UUID, token string, expiration time.Duration) error {
-	key := "token:" + token
-	err := r.redis.Set(ctx, key, userID.String(), expiration).Err()
+func (r *authRepository) StoreOIDCState(ctx context.Context, state string, st *entity.OIDCState, expiration time.Duration) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return errx.ErrInternalServer
+	}
+
+	if err := r.redis.Set(ctx, "oidc_state:"+token.Hash(state), data, expiration).Err(); err != nil {
+		return errx.ErrRedisError
+	}
+	return nil
+}
+
+// ConsumeOIDCState returns and deletes the state so a callback can only be
+// redeemed once.
+func (r *authRepository) ConsumeOIDCState(ctx context.Context, state string) (*entity.OIDCState, error) {
+	data, err := r.redis.GetDel(ctx, "oidc_state:"+token.Hash(state)).Bytes()
+	if err == redis.Nil {
+		return nil, errx.ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, errx.ErrRedisError
+	}
+
+	st := &entity.OIDCState{}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, errx.ErrInvalidOIDCState
+	}
+	return st, nil
+}
+
+// storeRefreshTokenScript swaps the family's current token in one step, so
+// two concurrent rotations of the same family cannot both leave a live token.
+//
+// KEYS: refresh:<hash>, refresh_family:<family>, session:<family>,
+// user_sessions:<user>. ARGV: hash, payload, family ID, TTL in milliseconds.
+var storeRefreshTokenScript = redis.NewScript(`
+local previous = redis.call("GET", KEYS[2])
+if previous then
+	redis.call("DEL", "refresh:" .. previous)
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[4])
+redis.call("SET", KEYS[2], ARGV[1], "PX", ARGV[4])
+redis.call("PEXPIRE", KEYS[3], ARGV[4])
+redis.call("SADD", KEYS[4], ARGV[3])
+redis.call("PEXPIRE", KEYS[4], ARGV[4])
+return 1
+`)
+
+// StoreRefreshToken makes refreshToken the only live token of its family; any
+// token previously issued for the family stops working.
+func (r *authRepository) StoreRefreshToken(ctx context.Context, refreshToken string, rt *entity.RefreshToken) error {
+	payload, err := json.Marshal(rt)
+	if err != nil {
+		return errx.ErrInternalServer
+	}
+
+	hash := token.Hash(refreshToken)
+	ttl := time.Until(rt.ExpiresAt).Milliseconds()
+	if ttl <= 0 {
+		return errx.ErrInvalidRefreshToken
+	}
+
+	keys := []string{
+		"refresh:" + hash,
+		"refresh_family:" + rt.FamilyID,
+		"session:" + rt.FamilyID,
+		"user_sessions:" + rt.UserID.String(),
+	}
+	if err := storeRefreshTokenScript.Run(ctx, r.redis, keys, hash, payload, rt.FamilyID, ttl).Err(); err != nil {
+		return errx.ErrRedisError
+	}
+	return nil
+}
+
+// ConsumeRefreshToken atomically removes an active refresh token and remembers
+// it as rotated, so that presenting it a second time can be detected.
+func (r *authRepository) ConsumeRefreshToken(ctx context.Context, refreshToken string) (*entity.RefreshToken, error) {
+	hash := token.Hash(refreshToken)
+
+	val, err := r.redis.GetDel(ctx, "refresh:"+hash).Result()
+	if err == redis.Nil {
+		return nil, errx.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, errx.ErrRedisError
+	}
+
+	rt := &entity.RefreshToken{}
+	if err := json.Unmarshal([]byte(val), rt); err != nil {
+		return nil, errx.ErrInternalServer
+	}
+
+	if err := r.redis.Set(ctx, "refresh_used:"+hash, rt.FamilyID, time.Until(rt.ExpiresAt)).Err(); err != nil {
+		return nil, errx.ErrRedisError
+	}
+
+	return rt, nil
+}
+
+func (r *authRepository) GetRotatedRefreshTokenFamily(ctx context.Context, refreshToken string) (string, error) {
+	familyID, err := r.redis.Get(ctx, "refresh_used:"+token.Hash(refreshToken)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", errx.ErrRedisError
+	}
+	return familyID, nil
+}
+
+// RevokeRefreshTokenFamily ends a login session: its current refresh token and
+// session metadata are removed.
+func (r *authRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	hash, err := r.redis.GetDel(ctx, "refresh_family:"+familyID).Result()
+	if err != nil && err != redis.Nil {
+		return errx.ErrRedisError
+	}
+
+	keys := []string{"session:" + familyID}
+	if hash != "" {
+		keys = append(keys, "refresh:"+hash)
+	}
+	if err := r.redis.Del(ctx, keys...).Err(); err != nil {
+		return errx.ErrRedisError
+	}
+	return nil
+}
+
+func (r *authRepository) RevokeAllRefreshTokenFamilies(ctx context.Context, userID uuid.UUID) error {
+	key := "user_sessions:" + userID.String()
+	families, err := r.redis.SMembers(ctx, key).Result()
+	if err != nil {
+		return errx.ErrRedisError
+	}
+
+	for _, familyID := range families {
+		if err := r.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+			return err
+		}
+	}
+
+	if err := r.redis.Del(ctx, key).Err(); err != nil {
+		return errx.ErrRedisError
+	}
+	return nil
+}
+
+func (r *authRepository) CreateSession(ctx context.Context, session *entity.Session, expiration time.Duration) error {
+	key := "session:" + session.ID
+	_, err := r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, map[string]interface{}{
+			"user_id":      session.UserID.String(),
+			"user_agent":   session.UserAgent,
+			"ip":           session.IP,
+			"created_at":   session.CreatedAt.Format(sessionTimeLayout),
+			"last_seen_at": session.LastSeenAt.Format(sessionTimeLayout),
+		})
+		pipe.Expire(ctx, key, expiration)
+		return nil
+	})
+	if err != nil {
+		return errx.ErrRedisError
+	}
+	return nil
+}
+
+func (r *authRepository) GetSession(ctx context.Context, sessionID string) (*entity.Session, error) {
+	fields, err := r.redis.HGetAll(ctx, "session:"+sessionID).Result()
+	if err != nil {
+		return nil, errx.ErrRedisError
+	}
+	if len(fields) == 0 {
+		return nil, errx.ErrSessionNotFound
+	}
+
+	userID, err := uuid.Parse(fields["user_id"])
+	if err != nil {
+		return nil, errx.ErrInternalServer
+	}
+	createdAt, _ := time.Parse(sessionTimeLayout, fields["created_at"])
+	lastSeenAt, _ := time.Parse(sessionTimeLayout, fields["last_seen_at"])
+
+	return &entity.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		UserAgent:  fields["user_agent"],
+		IP:         fields["ip"],
+		CreatedAt:  createdAt,
+		LastSeenAt: lastSeenAt,
+	}, nil
+}
+
+func (r *authRepository) ListSessions(ctx context.Context, userID uuid.UUID) ([]*entity.Session, error) {
+	key := "user_sessions:" + userID.String()
+	ids, err := r.redis.SMembers(ctx, key).Result()
+	if err != nil {
+		return nil, errx.ErrRedisError
+	}
+
+	var sessions []*entity.Session
+	for _, id := range ids {
+		session, err := r.GetSession(ctx, id)
+		if err == errx.ErrSessionNotFound {
+			// Expired or revoked, drop the stale reference
+			r.redis.SRem(ctx, key, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// TouchSession records activity on a session and reports whether it is still
+// active. A revoked or expired session is never recreated.
+func (r *authRepository) TouchSession(ctx context.Context, sessionID string, ip string) (bool, error) {
+	key := "session:" + sessionID
+	exists, err := r.redis.Exists(ctx, key).Result()
+	if err != nil {
+		return false, errx.ErrRedisError
+	}
+	if exists == 0 {
+		return false, nil
+	}
+
+	err = r.redis.HSet(ctx, key,
+		"ip", ip,
+		"last_seen_at", time.Now().Format(sessionTimeLayout),
+	).Err()
+	if err != nil {
+		return false, errx.ErrRedisError
+	}
+	return true, nil
+}
+
+// StorePasswordResetToken keeps a single outstanding reset token per user;
+// requesting a new one invalidates the previous link.
+func (r *authRepository) StorePasswordResetToken(ctx context.Context, userID uuid.UUID, resetToken string, expiration time.Duration) error {
+	hash := token.Hash(resetToken)
+	userKey := "password_reset_user:" + userID.String()
+
+	previous, err := r.redis.Get(ctx, userKey).Result()
+	if err != nil && err != redis.Nil {
+		return errx.ErrRedisError
+	}
+
+	_, err = r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previous != "" {
+			pipe.Del(ctx, "password_reset:"+previous)
+		}
+		pipe.Set(ctx, "password_reset:"+hash, userID.String(), expiration)
+		pipe.Set(ctx, userKey, hash, expiration)
+		return nil
+	})
+	if err != nil {
+		return errx.ErrRedisError
+	}
+	return nil
+}
+
+func (r *authRepository) ConsumePasswordResetToken(ctx context.Context, resetToken string) (uuid.UUID, error) {
+	val, err := r.redis.GetDel(ctx, "password_reset:"+token.Hash(resetToken)).Result()
+	if err == redis.Nil {
+		return uuid.Nil, errx.ErrInvalidResetToken
+	}
+	if err != nil {
+		return uuid.Nil, errx.ErrRedisError
+	}
+
+	userID, err := uuid.Parse(val)
+	if err != nil {
+		return uuid.Nil, errx.ErrInvalidResetToken
+	}
+
+	r.redis.Del(ctx, "password_reset_user:"+userID.String())
+	return userID, nil
+}
+
+// StoreMagicLinkToken keeps one outstanding login link per user; requesting a
+// new one invalidates the previous link.
+func (r *authRepository) StoreMagicLinkToken(ctx context.Context, userID uuid.UUID, linkToken string, expiration time.Duration) error {
+	hash := token.Hash(linkToken)
+	userKey := "magic_link_user:" + userID.String()
+
+	previous, err := r.redis.Get(ctx, userKey).Result()
+	if err != nil && err != redis.Nil {
+		return errx.ErrRedisError
+	}
+
+	_, err = r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previous != "" {
+			pipe.Del(ctx, "magic_link:"+previous)
+		}
+		pipe.Set(ctx, "magic_link:"+hash, userID.String(), expiration)
+		pipe.Set(ctx, userKey, hash, expiration)
+		return nil
+	})
 	if err != nil {
 		return errx.ErrRedisError
 	}
 	return nil
 }
 
+func (r *authRepository) ConsumeMagicLinkToken(ctx context.Context, linkToken string) (uuid.UUID, error) {
+	val, err := r.redis.GetDel(ctx, "magic_link:"+token.Hash(linkToken)).Result()
+	if err == redis.Nil {
+		return uuid.Nil, errx.ErrInvalidMagicLink
+	}
+	if err != nil {
+		return uuid.Nil, errx.ErrRedisError
+	}
+
+	userID, err := uuid.Parse(val)
+	if err != nil {
+		return uuid.Nil, errx.ErrInvalidMagicLink
+	}
+
+	r.redis.Del(ctx, "magic_link_user:"+userID.String())
+	return userID, nil
+}
+
+func (r *authRepository) GetTokenVersion(ctx context.Context, userID uuid.UUID) (int64, error) {
+	version, err := r.redis.Get(ctx, "token_version:"+userID.String()).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errx.ErrRedisError
+	}
+	return version, nil
+}
+
+func (r *authRepository) IncrementTokenVersion(ctx context.Context, userID uuid.UUID) (int64, error) {
+	version, err := r.redis.Incr(ctx, "token_version:"+userID.String()).Result()
+	if err != nil {
+		return 0, errx.ErrRedisError
+	}
+	return version, nil
+}
+
 func (r *authRepository) DeleteToken(ctx context.Context, token string) error {
 	key := "blacklist:" + token
-	err := r.redis.Set(ctx, key, "1", 24*time.Hour).Err()
+	err := r.redis.Set(ctx, key, "1", jwt.AccessTokenTTL).Err()
 	if err != nil {
 		return errx.ErrRedisError
 	}
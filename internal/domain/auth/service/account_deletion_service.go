@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	auditService "github.com/kenziehh/cashflow-be/internal/domain/audit/service"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
+	"github.com/kenziehh/cashflow-be/internal/infra/notifier"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+)
+
+const (
+	auditAccountDeletionScheduled = "account_deletion_scheduled"
+	auditAccountDeletionCancelled = "account_deletion_cancelled"
+	auditAccountDeleted           = "account_deleted"
+
+	// purgeBatchSize bounds how many accounts a single purge run erases
+	purgeBatchSize = 100
+
+	// proofUploadDir is where the transaction handler stores proof files
+	proofUploadDir = "uploads/proofs"
+)
+
+// DeleteAccount schedules the account to be erased once the grace period is
+// over. Every session and API key is revoked right away; the user can sign in
+// again and cancel until then.
+func (s *authService) DeleteAccount(ctx context.Context, userID uuid.UUID, req *dto.DeleteAccountRequest) (*dto.DeleteAccountResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DeletionScheduledAt != nil {
+		return nil, errx.ErrAccountDeletionScheduled
+	}
+
+	if user.Password != "" {
+		if !s.hasher.Verify(req.Password, user.Password) {
+			return nil, errx.ErrIncorrectPassword
+		}
+	} else if !strings.EqualFold(strings.TrimSpace(req.Email), user.Email) {
+		return nil, errx.ErrEmailConfirmationMismatch
+	}
+
+	deleteAt := time.Now().Add(time.Duration(s.cfg.AccountDeletionGraceDays) * 24 * time.Hour).Truncate(time.Second)
+	if err := s.repo.ScheduleAccountDeletion(ctx, userID, deleteAt); err != nil {
+		return nil, err
+	}
+
+	if err := s.LogoutAll(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditAccountDeletionScheduled,
+		TargetType: auditService.TargetUser,
+		TargetID:   userID.String(),
+		Metadata:   map[string]any{"delete_at": deleteAt.UTC().Format(time.RFC3339)},
+	})
+
+	s.notifyDeletionScheduled(user, deleteAt)
+
+	return &dto.DeleteAccountResponse{DeletionScheduledAt: deleteAt}, nil
+}
+
+func (s *authService) CancelAccountDeletion(ctx context.Context, userID uuid.UUID) error {
+	cancelled, err := s.repo.CancelAccountDeletion(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return errx.ErrAccountDeletionNotScheduled
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditAccountDeletionCancelled,
+		TargetType: auditService.TargetUser,
+		TargetID:   userID.String(),
+	})
+	return nil
+}
+
+// PurgeDeletedAccounts erases the accounts whose grace period is over and
+// returns how many were deleted. The database cascades to everything the user
+// owned; proof files are removed afterwards so a cancelled deletion never
+// loses them. The audit trail keeps only the account ID, which no longer
+// resolves to a person: IP addresses, user agents, diffs and metadata are
+// redacted first, so a failed redaction leaves the account due for the next
+// run.
+func (s *authService) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	userIDs, err := s.repo.ListAccountsDueForDeletion(ctx, now, purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, userID := range userIDs {
+		user, err := s.repo.GetUserByID(ctx, userID)
+		if err != nil {
+			return deleted, err
+		}
+
+		if _, err := s.audit.RedactUser(ctx, userID, user.Email); err != nil {
+			return deleted, err
+		}
+
+		files, err := s.transactions.ListProofFiles(ctx, userID)
+		if err != nil {
+			return deleted, err
+		}
+
+		ok, err := s.repo.DeleteUser(ctx, userID, now)
+		if err != nil {
+			return deleted, err
+		}
+		if !ok {
+			continue
+		}
+		deleted++
+
+		removeProofFiles(userID, files)
+
+		s.recordAudit(ctx, auditService.Event{
+			UserID:     userID,
+			Action:     auditAccountDeleted,
+			TargetType: auditService.TargetUser,
+			TargetID:   userID.String(),
+			Metadata:   map[string]any{"proof_files": len(files)},
+		})
+	}
+
+	return deleted, nil
+}
+
+// removeProofFiles only touches files inside the upload directory, whatever
+// path was stored.
+func removeProofFiles(userID uuid.UUID, files []string) {
+	for _, file := range files {
+		path := filepath.Join(proofUploadDir, filepath.Base(file))
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("[ACCOUNT DELETION ERROR] remove proof file of %s: %v", userID, err)
+		}
+	}
+}
+
+func (s *authService) notifyDeletionScheduled(user *entity.User, deleteAt time.Time) {
+	n := notifier.Notification{
+		UserID:  user.ID,
+		Email:   user.Email,
+		Subject: "Your account is scheduled for deletion",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account and all of its data will be permanently deleted on %s.\n\nIf you change your mind, sign in and cancel the deletion before then. If you did not ask for this, sign in, cancel the deletion and change your password right away.",
+			user.Name, deleteAt.UTC().Format(time.RFC1123)),
+	}
+
+	go func() {
+		if err := s.notifier.Notify(context.Background(), n); err != nil {
+			log.Printf("[NOTIFY ERROR] deletion notice for %s: %v", n.UserID, err)
+		}
+	}()
+}
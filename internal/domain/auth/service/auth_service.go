@@ -2,38 +2,132 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/kenziehh/cashflow-be/config"
+	accountService "github.com/kenziehh/cashflow-be/internal/domain/account/service"
+	apiKeyService "github.com/kenziehh/cashflow-be/internal/domain/api_key/service"
+	auditService "github.com/kenziehh/cashflow-be/internal/domain/audit/service"
 	"github.com/kenziehh/cashflow-be/internal/domain/auth/dto"
 	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
 	"github.com/kenziehh/cashflow-be/internal/domain/auth/repository"
+	transactionService "github.com/kenziehh/cashflow-be/internal/domain/transaction/service"
+	"github.com/kenziehh/cashflow-be/internal/infra/mailer"
+	"github.com/kenziehh/cashflow-be/internal/infra/notifier"
 	"github.com/kenziehh/cashflow-be/pkg/errx"
 
-	"github.com/kenziehh/cashflow-be/pkg/bcrypt"
 	"github.com/kenziehh/cashflow-be/pkg/jwt"
+	"github.com/kenziehh/cashflow-be/pkg/oidc"
+	"github.com/kenziehh/cashflow-be/pkg/password"
+	"github.com/kenziehh/cashflow-be/pkg/rbac"
+	"github.com/kenziehh/cashflow-be/pkg/token"
 
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/google/uuid"
 )
 
 type AuthService interface {
-	Register(ctx context.Context, req *dto.RegisterRequest) (*dto.AuthResponse, error)
-	Login(ctx context.Context, req *dto.LoginRequest) (*dto.AuthResponse, error)
-	Logout(ctx context.Context, token string) error
+	Register(ctx context.Context, req *dto.RegisterRequest, client dto.ClientInfo) (*dto.AuthResponse, error)
+	Login(ctx context.Context, req *dto.LoginRequest, client dto.ClientInfo) (*dto.AuthResponse, error)
+	LoginMFA(ctx context.Context, req *dto.LoginMFARequest, client dto.ClientInfo) (*dto.AuthResponse, error)
+	Refresh(ctx context.Context, req *dto.RefreshRequest, client dto.ClientInfo) (*dto.AuthResponse, error)
+	Logout(ctx context.Context, accessToken string, sessionID string) error
+	LogoutAll(ctx context.Context, userID uuid.UUID) error
+	ListSessions(ctx context.Context, userID uuid.UUID, currentSessionID string) ([]dto.SessionResponse, error)
+	ListLoginHistory(ctx context.Context, userID uuid.UUID, params dto.LoginHistoryParams) (*dto.LoginHistoryResponse, error)
+	RevokeSession(ctx context.Context, userID uuid.UUID, sessionID string) error
 	GetProfile(ctx context.Context, userID uuid.UUID) (*dto.UserProfile, error)
-	UpdateProfile(ctx context.Context, userID uuid.UUID, req *dto.UpdateProfileRequest) error
+	UpdateProfile(ctx context.Context, userID uuid.UUID, req *dto.UpdateProfileRequest) (*dto.UserProfile, error)
+	DeleteAccount(ctx context.Context, userID uuid.UUID, req *dto.DeleteAccountRequest) (*dto.DeleteAccountResponse, error)
+	CancelAccountDeletion(ctx context.Context, userID uuid.UUID) error
+	PurgeDeletedAccounts(ctx context.Context) (int, error)
+	ChangePassword(ctx context.Context, userID uuid.UUID, sessionID string, req *dto.ChangePasswordRequest) (*dto.AuthResponse, error)
+	VerifyEmail(ctx context.Context, req *dto.VerifyEmailRequest) error
+	ResendVerificationEmail(ctx context.Context, userID uuid.UUID) error
+	ForgotPassword(ctx context.Context, req *dto.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req *dto.ResetPasswordRequest) error
+	EnrollTOTP(ctx context.Context, userID uuid.UUID) (*dto.TOTPEnrollResponse, error)
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, req *dto.TOTPConfirmRequest) (*dto.TOTPConfirmResponse, error)
+	DisableTOTP(ctx context.Context, userID uuid.UUID, req *dto.TOTPDisableRequest) error
+	UnlockAccount(ctx context.Context, req *dto.UnlockAccountRequest) error
+	UpdateUserRole(ctx context.Context, actorID, userID uuid.UUID, req *dto.UpdateRoleRequest) error
+	Impersonate(ctx context.Context, actorID, userID uuid.UUID, req *dto.ImpersonateRequest) (*dto.ImpersonationResponse, error)
+	RequestMagicLink(ctx context.Context, req *dto.MagicLinkRequest) error
+	LoginMagicLink(ctx context.Context, req *dto.MagicLinkLoginRequest, client dto.ClientInfo) (*dto.AuthResponse, error)
+	BeginPasskeyRegistration(ctx context.Context, userID uuid.UUID, sessionID string, req *dto.PasskeyRegisterBeginRequest) (*protocol.CredentialCreation, error)
+	FinishPasskeyRegistration(ctx context.Context, userID uuid.UUID, req *dto.PasskeyCredentialRequest) (*entity.WebAuthnCredential, error)
+	ListPasskeys(ctx context.Context, userID uuid.UUID) ([]*entity.WebAuthnCredential, error)
+	DeletePasskey(ctx context.Context, userID uuid.UUID, sessionID string, passkeyID uuid.UUID, req *dto.PasskeyDeleteRequest) error
+	BeginPasskeyLogin(ctx context.Context) (*dto.PasskeyLoginBeginResponse, error)
+	FinishPasskeyLogin(ctx context.Context, req *dto.PasskeyLoginFinishRequest, client dto.ClientInfo) (*dto.AuthResponse, error)
+	BeginPasskeyMFA(ctx context.Context, req *dto.PasskeyMFABeginRequest) (*protocol.CredentialAssertion, error)
+	FinishPasskeyMFA(ctx context.Context, req *dto.PasskeyMFAFinishRequest, client dto.ClientInfo) (*dto.AuthResponse, error)
+	OIDCAuthorize(ctx context.Context) (*dto.OIDCAuthorizeResponse, error)
+	OIDCCallback(ctx context.Context, req *dto.OIDCCallbackRequest, client dto.ClientInfo) (*dto.AuthResponse, error)
 }
 
+const (
+	refreshTokenTTL       = 30 * 24 * time.Hour
+	passwordResetTokenTTL = 30 * time.Minute
+	emailVerificationTTL  = 24 * time.Hour
+	verificationResendGap = time.Minute
+	mfaChallengeTTL       = 5 * time.Minute
+	mfaMaxAttempts        = 5
+	recentLoginWindow     = 10 * time.Minute
+
+	purposeEmailVerification = "email-verification"
+	purposeMFAChallenge      = "mfa-challenge"
+
+	auditRoleChanged = "role_changed"
+)
+
 type authService struct {
-	repo repository.AuthRepository
+	repo           repository.AuthRepository
+	accounts       accountService.AccountService
+	apiKeys        apiKeyService.APIKeyService
+	transactions   transactionService.TransactionService
+	mailer         mailer.Mailer
+	cfg            *config.Config
+	oidc           *oidc.Provider
+	webauthn       *webauthn.WebAuthn
+	hasher         password.Hasher
+	passwordPolicy *password.Policy
+	audit          auditService.AuditService
+	notifier       notifier.Notifier
 }
 
-func NewAuthService(repo repository.AuthRepository) AuthService {
+func NewAuthService(repo repository.AuthRepository, accounts accountService.AccountService, apiKeys apiKeyService.APIKeyService, transactions transactionService.TransactionService, mailer mailer.Mailer, cfg *config.Config, audit auditService.AuditService, notifier notifier.Notifier) AuthService {
 	return &authService{
-		repo: repo,
+		repo:           repo,
+		accounts:       accounts,
+		apiKeys:        apiKeys,
+		transactions:   transactions,
+		mailer:         mailer,
+		cfg:            cfg,
+		oidc:           newOIDCProvider(cfg),
+		webauthn:       newWebAuthn(cfg),
+		hasher:         newPasswordHasher(cfg),
+		passwordPolicy: newPasswordPolicy(cfg),
+		audit:          audit,
+		notifier:       notifier,
 	}
 }
 
-func (s *authService) Register(ctx context.Context, req *dto.RegisterRequest) (*dto.AuthResponse, error) {
+// createUser stores the user together with their default money account.
+func (s *authService) createUser(ctx context.Context, user *entity.User) error {
+	return s.repo.CreateUser(ctx, user, func(tx *sql.Tx) error {
+		return s.accounts.CreateDefaultAccount(ctx, tx, user.ID)
+	})
+}
+
+func (s *authService) Register(ctx context.Context, req *dto.RegisterRequest, client dto.ClientInfo) (*dto.AuthResponse, error) {
 	// Check if user exists
 	existingUser, _ := s.repo.GetUserByEmail(ctx, req.Email)
 	if existingUser != nil {
@@ -41,81 +135,205 @@ func (s *authService) Register(ctx context.Context, req *dto.RegisterRequest) (*
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.HashPassword(req.Password)
+	hashedPassword, err := s.hashNewPassword(req.Password)
 	if err != nil {
-		return nil, errx.ErrInternalServer
+		return nil, err
 	}
 
 	// Create user
 	user := &entity.User{
-		ID:        uuid.New(),
-		Email:     req.Email,
-		Password:  hashedPassword,
-		Name:      req.Name,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:          uuid.New(),
+		Email:       req.Email,
+		Password:    hashedPassword,
+		Name:        req.Name,
+		Role:        rbac.RoleUser,
+		Preferences: entity.DefaultPreferences(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 
-	if err := s.repo.CreateUser(ctx, user); err != nil {
+	if err := s.createUser(ctx, user); err != nil {
 		return nil, err
 	}
 
-	// Generate token
-	token, err := jwt.GenerateToken(user.ID.String())
-	if err != nil {
-		return nil, errx.ErrInternalServer
-	}
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     user.ID,
+		Action:     auditService.ActionRegister,
+		TargetType: auditService.TargetUser,
+		TargetID:   user.ID.String(),
+	})
 
-	// Store token in Redis
-	if err := s.repo.StoreToken(ctx, user.ID, token, 24*time.Hour); err != nil {
+	if err := s.sendVerificationEmail(user); err != nil {
 		return nil, err
 	}
 
-	return &dto.AuthResponse{
-		Token: token,
-		User: dto.UserProfile{
-			ID:    user.ID.String(),
-			Email: user.Email,
-			Name:  user.Name,
-		},
-	}, nil
+	return s.startSession(ctx, user, client, loginMethodRegister)
 }
 
-func (s *authService) Login(ctx context.Context, req *dto.LoginRequest) (*dto.AuthResponse, error) {
+func (s *authService) Login(ctx context.Context, req *dto.LoginRequest, client dto.ClientInfo) (*dto.AuthResponse, error) {
+	if err := s.checkLoginLock(ctx, req.Email, client.IP); err != nil {
+		user, _ := s.repo.GetUserByEmail(ctx, req.Email)
+		s.recordLoginFailureEvent(ctx, req.Email, user, client, loginFailureLocked)
+		return nil, err
+	}
+
 	// Get user by email
 	user, err := s.repo.GetUserByEmail(ctx, req.Email)
+	if err == errx.ErrUserNotFound {
+		if err := s.recordLoginFailure(ctx, req.Email, client, nil, loginFailureCredentials); err != nil {
+			return nil, err
+		}
+		return nil, errx.ErrInvalidCredentials
+	}
 	if err != nil {
 		return nil, errx.ErrInvalidCredentials
 	}
 
 	// Verify password
-	if !bcrypt.CheckPassword(req.Password, user.Password) {
+	if user.Password == "" || !s.hasher.Verify(req.Password, user.Password) {
+		if err := s.recordLoginFailure(ctx, req.Email, client, user, loginFailureCredentials); err != nil {
+			return nil, err
+		}
 		return nil, errx.ErrInvalidCredentials
 	}
 
-	// Generate token
-	token, err := jwt.GenerateToken(user.ID.String())
+	// Failures are only cleared by startSession, once a second factor has
+	// been answered too
+	s.upgradePasswordHash(ctx, user, req.Password)
+
+	return s.completeLogin(ctx, user, client, loginMethodPassword)
+}
+
+// completeLogin finishes a login once the first factor has been verified.
+// Accounts with a second factor get a challenge instead of tokens.
+func (s *authService) completeLogin(ctx context.Context, user *entity.User, client dto.ClientInfo, method string) (*dto.AuthResponse, error) {
+	methods, err := s.mfaMethods(ctx, user.ID)
 	if err != nil {
-		return nil, errx.ErrInternalServer
+		return nil, err
+	}
+	if len(methods) > 0 {
+		mfaToken, err := jwt.GeneratePurposeToken(purposeMFAChallenge, user.ID.String(), mfaChallengeTTL)
+		if err != nil {
+			return nil, errx.ErrInternalServer
+		}
+		return &dto.AuthResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			MFAMethods:  methods,
+		}, nil
+	}
+
+	return s.startSession(ctx, user, client, method)
+}
+
+func (s *authService) Refresh(ctx context.Context, req *dto.RefreshRequest, client dto.ClientInfo) (*dto.AuthResponse, error) {
+	rt, err := s.repo.ConsumeRefreshToken(ctx, req.RefreshToken)
+	if err == errx.ErrInvalidRefreshToken {
+		// A token that was already rotated is being replayed: revoke the whole family
+		familyID, lookupErr := s.repo.GetRotatedRefreshTokenFamily(ctx, req.RefreshToken)
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+		if familyID != "" {
+			if err := s.repo.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+				return nil, err
+			}
+			return nil, errx.ErrRefreshTokenReused
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// Store token in Redis
-	if err := s.repo.StoreToken(ctx, user.ID, token, 24*time.Hour); err != nil {
+	active, err := s.repo.TouchSession(ctx, rt.FamilyID, client.IP)
+	if err != nil {
 		return nil, err
 	}
+	if !active {
+		return nil, errx.ErrInvalidRefreshToken
+	}
 
-	return &dto.AuthResponse{
-		Token: token,
-		User: dto.UserProfile{
-			ID:    user.ID.String(),
-			Email: user.Email,
-			Name:  user.Name,
-		},
-	}, nil
+	user, err := s.repo.GetUserByID(ctx, rt.UserID)
+	if err != nil {
+		return nil, errx.ErrInvalidRefreshToken
+	}
+
+	return s.issueTokens(ctx, user, rt.FamilyID)
 }
 
-func (s *authService) Logout(ctx context.Context, token string) error {
-	return s.repo.DeleteToken(ctx, token)
+func (s *authService) Logout(ctx context.Context, accessToken string, sessionID string) error {
+	if err := s.repo.DeleteToken(ctx, accessToken); err != nil {
+		return err
+	}
+
+	if sessionID != "" {
+		if err := s.repo.RevokeRefreshTokenFamily(ctx, sessionID); err != nil {
+			return err
+		}
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		Action:     auditService.ActionLogout,
+		TargetType: "session",
+		TargetID:   sessionID,
+	})
+	return nil
+}
+
+// LogoutAll invalidates every access and refresh token the user holds by
+// bumping their token version and dropping all refresh token families, and
+// revokes their API keys.
+func (s *authService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	if _, err := s.repo.IncrementTokenVersion(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeAllRefreshTokenFamilies(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.apiKeys.RevokeAllAPIKeys(ctx, userID); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, auditService.Event{UserID: userID, Action: auditService.ActionLogoutAll})
+	return nil
+}
+
+func (s *authService) ListSessions(ctx context.Context, userID uuid.UUID, currentSessionID string) ([]dto.SessionResponse, error) {
+	sessions, err := s.repo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, dto.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].LastSeenAt.After(result[j].LastSeenAt)
+	})
+
+	return result, nil
+}
+
+func (s *authService) RevokeSession(ctx context.Context, userID uuid.UUID, sessionID string) error {
+	session, err := s.repo.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return errx.ErrSessionNotFound
+	}
+
+	return s.repo.RevokeRefreshTokenFamily(ctx, sessionID)
 }
 
 func (s *authService) GetProfile(ctx context.Context, userID uuid.UUID) (*dto.UserProfile, error) {
@@ -124,14 +342,402 @@ func (s *authService) GetProfile(ctx context.Context, userID uuid.UUID) (*dto.Us
 		return nil, err
 	}
 
-	return &dto.UserProfile{
-		ID:    user.ID.String(),
-		Email: user.Email,
-		Name:  user.Name,
+	return toUserProfile(user), nil
+}
+
+func (s *authService) UpdateProfile(ctx context.Context, userID uuid.UUID, req *dto.UpdateProfileRequest) (*dto.UserProfile, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		user.Name = name
+	}
+	if p := req.Preferences; p != nil {
+		// "Local" would mean the server's zone, which is what this replaces
+		if p.Timezone == "Local" {
+			return nil, errx.NewBadRequestError("Invalid timezone")
+		}
+		if p.Timezone != "" {
+			user.Preferences.Timezone = p.Timezone
+		}
+		if p.Currency != "" {
+			user.Preferences.Currency = p.Currency
+		}
+		if p.Locale != "" {
+			user.Preferences.Locale = p.Locale
+		}
+		if p.WeekStart != "" {
+			user.Preferences.WeekStart = p.WeekStart
+		}
+	}
+
+	if err := s.repo.UpdateProfile(ctx, user.ID, user.Name, user.Preferences); err != nil {
+		return nil, err
+	}
+
+	return toUserProfile(user), nil
+}
+
+// UpdateUserRole changes a user's role. Their existing tokens are revoked so the
+// new role applies immediately.
+func (s *authService) UpdateUserRole(ctx context.Context, actorID, userID uuid.UUID, req *dto.UpdateRoleRequest) error {
+	if actorID == userID {
+		return errx.NewBadRequestError("You cannot change your own role")
+	}
+	if !rbac.IsValidRole(req.Role) {
+		return errx.NewBadRequestError("Invalid role")
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateRole(ctx, userID, req.Role); err != nil {
+		return err
+	}
+
+	if _, err := s.repo.IncrementTokenVersion(ctx, userID); err != nil {
+		return err
+	}
+
+	// The role has changed by now, so a failed audit write is only logged
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditRoleChanged,
+		TargetType: auditService.TargetUser,
+		TargetID:   userID.String(),
+		Before:     map[string]string{"role": user.Role},
+		After:      map[string]string{"role": req.Role},
+	})
+	return nil
+}
+
+// ChangePassword replaces the user's password and revokes every other session,
+// token and API key. The current session is kept alive with a fresh token
+// pair.
+func (s *authService) ChangePassword(ctx context.Context, userID uuid.UUID, sessionID string, req *dto.ChangePasswordRequest) (*dto.AuthResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Password == "" {
+		return nil, errx.ErrNoLocalPassword
+	}
+	if !s.hasher.Verify(req.CurrentPassword, user.Password) {
+		return nil, errx.ErrIncorrectPassword
+	}
+	if req.CurrentPassword == req.NewPassword {
+		return nil, errx.ErrPasswordUnchanged
+	}
+
+	hashedPassword, err := s.hashNewPassword(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return nil, err
+	}
+
+	if err := s.revokeOtherSessions(ctx, user.ID, sessionID); err != nil {
+		return nil, err
+	}
+	if err := s.apiKeys.RevokeAllAPIKeys(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user, sessionID)
+}
+
+// VerifyEmail marks the address in a signed verification link as confirmed.
+// The client has to refresh its access token to pick up the new status.
+func (s *authService) VerifyEmail(ctx context.Context, req *dto.VerifyEmailRequest) error {
+	claims, err := jwt.ValidatePurposeToken(req.Token, purposeEmailVerification)
+	if err != nil {
+		return errx.ErrInvalidVerifyToken
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return errx.ErrInvalidVerifyToken
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return errx.ErrInvalidVerifyToken
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	return s.repo.MarkEmailVerified(ctx, user.ID)
+}
+
+func (s *authService) ResendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return errx.ErrEmailAlreadyVerified
+	}
+
+	ok, err := s.repo.AcquireCooldown(ctx, "verify_email:"+user.ID.String(), verificationResendGap)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errx.ErrTooManyRequests
+	}
+
+	return s.sendVerificationEmail(user)
+}
+
+func (s *authService) sendVerificationEmail(user *entity.User) error {
+	verifyToken, err := jwt.GeneratePurposeToken(purposeEmailVerification, user.ID.String(), emailVerificationTTL)
+	if err != nil {
+		return errx.ErrInternalServer
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.cfg.FrontendURL, url.QueryEscape(verifyToken))
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %d hours.\n\n%s",
+			user.Name, int(emailVerificationTTL.Hours()), link),
+	}
+
+	s.sendMailAsync(msg)
+	return nil
+}
+
+// ForgotPassword emails a reset link when the address belongs to an account.
+// It behaves identically whether or not the email exists.
+func (s *authService) ForgotPassword(ctx context.Context, req *dto.ForgotPasswordRequest) error {
+	user, err := s.repo.GetUserByEmail(ctx, req.Email)
+	if err == errx.ErrUserNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	resetToken, err := token.Generate()
+	if err != nil {
+		return errx.ErrInternalServer
+	}
+
+	if err := s.repo.StorePasswordResetToken(ctx, user.ID, resetToken, passwordResetTokenTTL); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.cfg.FrontendURL, url.QueryEscape(resetToken))
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to reset your password. It expires in %d minutes and can only be used once.\n\n%s\n\nIf you did not request this, you can ignore this email.",
+			user.Name, int(passwordResetTokenTTL.Minutes()), link),
+	}
+
+	// Sent in the background so the response time does not reveal whether the
+	// account exists.
+	s.sendMailAsync(msg)
+	return nil
+}
+
+// ResetPassword redeems a reset token and signs the user out everywhere.
+func (s *authService) ResetPassword(ctx context.Context, req *dto.ResetPasswordRequest) error {
+	// Checked first so a rejected password does not use up the token
+	hashedPassword, err := s.hashNewPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	userID, err := s.repo.ConsumePasswordResetToken(ctx, req.Token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+		return err
+	}
+
+	// Proving ownership of the mailbox also lifts a login lockout
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.ClearLoginFailures(ctx, emailSubject(user.Email)); err != nil {
+		return err
+	}
+
+	return s.LogoutAll(ctx, userID)
+}
+
+// revokeOtherSessions invalidates all access tokens of the user and ends every
+// session except keepSessionID.
+func (s *authService) revokeOtherSessions(ctx context.Context, userID uuid.UUID, keepSessionID string) error {
+	if _, err := s.repo.IncrementTokenVersion(ctx, userID); err != nil {
+		return err
+	}
+
+	sessions, err := s.repo.ListSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.ID == keepSessionID {
+			continue
+		}
+		if err := s.repo.RevokeRefreshTokenFamily(ctx, session.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reauthenticate confirms that whoever holds the user's access token is the
+// user, before a change that outlives the token. It takes the password when
+// the account has one and a TOTP or recovery code when 2FA is on. Accounts
+// with neither must have signed in on this session within recentLoginWindow.
+func (s *authService) reauthenticate(ctx context.Context, user *entity.User, sessionID, password, code string) error {
+	if user.Password != "" && !s.hasher.Verify(password, user.Password) {
+		return errx.ErrIncorrectPassword
+	}
+
+	userTOTP, err := s.repo.GetTOTP(ctx, user.ID)
+	if err != nil && err != errx.ErrTOTPNotEnrolled {
+		return err
+	}
+	totpEnabled := err == nil && userTOTP.EnabledAt != nil
+	if totpEnabled {
+		ok, err := s.checkSecondFactor(ctx, user.ID, userTOTP.Secret, code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errx.ErrInvalidMFACode
+		}
+	}
+
+	if user.Password != "" || totpEnabled {
+		return nil
+	}
+
+	session, err := s.repo.GetSession(ctx, sessionID)
+	if err != nil || session.UserID != user.ID || time.Since(session.CreatedAt) > recentLoginWindow {
+		return errx.ErrRecentLoginRequired
+	}
+	return nil
+}
+
+// startSession records a new signed-in device and issues its first token pair.
+// method is how the user proved who they are, for the login history. The user
+// is fully authenticated by now, so their failed logins are forgotten.
+func (s *authService) startSession(ctx context.Context, user *entity.User, client dto.ClientInfo, method string) (*dto.AuthResponse, error) {
+	if err := s.repo.ClearLoginFailures(ctx, emailSubject(user.Email)); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &entity.Session{
+		ID:         uuid.NewString(),
+		UserID:     user.ID,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := s.repo.CreateSession(ctx, session, refreshTokenTTL); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     user.ID,
+		Action:     auditService.ActionLogin,
+		TargetType: "session",
+		TargetID:   session.ID,
+		Metadata:   map[string]any{"method": method},
+	})
+	s.recordLoginSuccess(ctx, user, client, method)
+
+	return s.issueTokens(ctx, user, session.ID)
+}
+
+// issueTokens signs a new access token and stores a fresh refresh token for
+// the given token family (one family per session).
+func (s *authService) issueTokens(ctx context.Context, user *entity.User, familyID string) (*dto.AuthResponse, error) {
+	version, err := s.repo.GetTokenVersion(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := jwt.GenerateToken(&jwt.Claims{
+		UserID:        user.ID.String(),
+		SessionID:     familyID,
+		Generation:    version,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Role:          user.Role,
+	})
+	if err != nil {
+		return nil, errx.ErrInternalServer
+	}
+
+	refreshToken, err := token.Generate()
+	if err != nil {
+		return nil, errx.ErrInternalServer
+	}
+
+	rt := &entity.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}
+	if err := s.repo.StoreRefreshToken(ctx, refreshToken, rt); err != nil {
+		return nil, err
+	}
+
+	return &dto.AuthResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(jwt.AccessTokenTTL.Seconds()),
+		User:         toUserProfile(user),
 	}, nil
 }
 
+// recordAudit writes an audit entry for an event that must not fail the
+// request, such as a login; failures are only logged.
+func (s *authService) recordAudit(ctx context.Context, event auditService.Event) {
+	if err := s.audit.Record(ctx, event); err != nil {
+		log.Printf("[AUDIT ERROR] %s: %v", event.Action, err)
+	}
+}
+
+// sendMailAsync delivers mail without blocking the request; failures are only
+// logged.
+func (s *authService) sendMailAsync(msg mailer.Message) {
+	go func() {
+		if err := s.mailer.Send(context.Background(), msg); err != nil {
+			log.Printf("[MAIL ERROR] %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
 
-func (s *authService) UpdateProfile(ctx context.Context, userID uuid.UUID, req *dto.UpdateProfileRequest) error {
-	return s.repo.UpdateProfile(ctx, userID, req)
-}
\ No newline at end of file
+func toUserProfile(user *entity.User) *dto.UserProfile {
+	return &dto.UserProfile{
+		ID:            user.ID.String(),
+		Email:         user.Email,
+		Name:          user.Name,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Preferences:   user.Preferences,
+
+		DeletionScheduledAt: user.DeletionScheduledAt,
+	}
+}
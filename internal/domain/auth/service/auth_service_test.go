@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginChallengesSecondFactor(t *testing.T) {
+	tests := []struct {
+		name    string
+		totp    bool
+		passkey bool
+		want    []string
+	}{
+		{name: "no second factor"},
+		{name: "totp", totp: true, want: []string{mfaMethodTOTP}},
+		{name: "passkey only", passkey: true, want: []string{mfaMethodPasskey}},
+		{name: "totp and passkey", totp: true, passkey: true, want: []string{mfaMethodTOTP, mfaMethodPasskey}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo, _ := newTestService(t, testConfig())
+			user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+
+			now := time.Now()
+			if tt.totp {
+				repo.totp[user.ID] = &entity.UserTOTP{UserID: user.ID, Secret: "JBSWY3DPEHPK3PXP", EnabledAt: &now}
+			}
+			if tt.passkey {
+				repo.credentials = append(repo.credentials, &entity.WebAuthnCredential{
+					ID:           uuid.New(),
+					UserID:       user.ID,
+					CredentialID: []byte("credential"),
+					CreatedAt:    now,
+				})
+			}
+
+			resp, err := s.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: "correct horse battery"}, testClient)
+			if err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+
+			if tt.want == nil {
+				if resp.MFARequired || resp.Token == "" {
+					t.Fatalf("expected tokens without a challenge, got %+v", resp)
+				}
+				return
+			}
+			if !resp.MFARequired || resp.MFAToken == "" || resp.Token != "" {
+				t.Fatalf("expected an MFA challenge, got %+v", resp)
+			}
+			if !reflect.DeepEqual(resp.MFAMethods, tt.want) {
+				t.Errorf("MFAMethods = %v, want %v", resp.MFAMethods, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginRehashesBcryptPassword(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "")
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse battery"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.users[user.ID].Password = string(legacy)
+	ctx := context.Background()
+
+	// A failed login leaves the hash alone
+	_, err = s.Login(ctx, &dto.LoginRequest{Email: user.Email, Password: "wrong password"}, testClient)
+	if !errors.Is(err, errx.ErrInvalidCredentials) {
+		t.Fatalf("Login error = %v, want %v", err, errx.ErrInvalidCredentials)
+	}
+	if repo.storedPassword(user.ID) != string(legacy) {
+		t.Fatal("failed login replaced the hash")
+	}
+
+	if _, err := s.Login(ctx, &dto.LoginRequest{Email: user.Email, Password: "correct horse battery"}, testClient); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	upgraded := repo.storedPassword(user.ID)
+	if !strings.HasPrefix(upgraded, "$argon2id$") {
+		t.Fatalf("stored hash %q was not upgraded to argon2id", upgraded)
+	}
+
+	if _, err := s.Login(ctx, &dto.LoginRequest{Email: user.Email, Password: "correct horse battery"}, testClient); err != nil {
+		t.Fatalf("Login with the upgraded hash: %v", err)
+	}
+	if repo.storedPassword(user.ID) != upgraded {
+		t.Error("a current hash was rehashed")
+	}
+}
+
+func TestRegisterOpensDefaultAccount(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	accounts := s.accounts.(*fakeAccounts)
+
+	req := &dto.RegisterRequest{Email: "ada@example.com", Password: "correct horse battery", Name: "Ada"}
+	resp, err := s.Register(context.Background(), req, testClient)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	if len(accounts.created) != 1 || accounts.created[0].String() != resp.User.ID {
+		t.Fatalf("default accounts opened for %v, want [%s]", accounts.created, resp.User.ID)
+	}
+	if _, err := repo.GetUserByEmail(context.Background(), "ada@example.com"); err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+}
+
+func TestRegisterWithoutDefaultAccount(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	s.accounts.(*fakeAccounts).err = errx.ErrDatabaseError
+
+	req := &dto.RegisterRequest{Email: "ada@example.com", Password: "correct horse battery", Name: "Ada"}
+	if _, err := s.Register(context.Background(), req, testClient); !errors.Is(err, errx.ErrDatabaseError) {
+		t.Fatalf("Register error = %v, want %v", err, errx.ErrDatabaseError)
+	}
+
+	// The user is not kept without their account
+	if _, err := repo.GetUserByEmail(context.Background(), "ada@example.com"); !errors.Is(err, errx.ErrUserNotFound) {
+		t.Fatalf("GetUserByEmail error = %v, want %v", err, errx.ErrUserNotFound)
+	}
+}
+
+func TestEmailsAreCaseInsensitive(t *testing.T) {
+	s, _, _ := newTestService(t, testConfig())
+	ctx := context.Background()
+
+	req := &dto.RegisterRequest{Email: " Ada@Example.com", Password: "correct horse battery", Name: "Ada"}
+	resp, err := s.Register(ctx, req, testClient)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if resp.User.Email != "ada@example.com" {
+		t.Errorf("stored email = %q, want it lowercased", resp.User.Email)
+	}
+
+	req = &dto.RegisterRequest{Email: "ADA@example.com", Password: "correct horse battery", Name: "Ada"}
+	if _, err := s.Register(ctx, req, testClient); !errors.Is(err, errx.ErrEmailAlreadyExists) {
+		t.Fatalf("Register with another case error = %v, want %v", err, errx.ErrEmailAlreadyExists)
+	}
+
+	login, err := s.Login(ctx, &dto.LoginRequest{Email: "ada@EXAMPLE.com", Password: "correct horse battery"}, testClient)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if login.User.ID != resp.User.ID {
+		t.Fatalf("signed in %s, want %s", login.User.ID, resp.User.ID)
+	}
+}
+
+func TestSigningOutEverywhereRevokesAPIKeys(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	apiKeys := s.apiKeys.(*fakeAPIKeys)
+	ctx := context.Background()
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+
+	if _, err := s.Login(ctx, &dto.LoginRequest{Email: "ada@example.com", Password: "correct horse battery"}, testClient); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	sessionID := repo.sessions[0].ID
+
+	req := &dto.ChangePasswordRequest{CurrentPassword: "correct horse battery", NewPassword: "another horse battery"}
+	if _, err := s.ChangePassword(ctx, user.ID, sessionID, req); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if got := apiKeys.revokedFor(user.ID); got != 1 {
+		t.Fatalf("API keys revoked %d times after ChangePassword, want 1", got)
+	}
+
+	if err := s.LogoutAll(ctx, user.ID); err != nil {
+		t.Fatalf("LogoutAll: %v", err)
+	}
+	if got := apiKeys.revokedFor(user.ID); got != 2 {
+		t.Fatalf("API keys revoked %d times after LogoutAll, want 2", got)
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
+	"github.com/kenziehh/cashflow-be/internal/infra/notifier"
+	"github.com/kenziehh/cashflow-be/pkg/token"
+)
+
+// Login methods recorded in the login history
+const (
+	loginMethodPassword   = "password"
+	loginMethodRegister   = "register"
+	loginMethodTOTP       = "totp"
+	loginMethodPasskey    = "passkey"
+	loginMethodPasskeyMFA = "passkey_mfa"
+	loginMethodOIDC       = "oidc"
+	loginMethodMagicLink  = "magic_link"
+)
+
+const (
+	loginFailureCredentials = "invalid_credentials"
+	loginFailureLocked      = "locked"
+	loginFailureMFACode     = "invalid_mfa_code"
+	loginFailureMFAPasskey  = "invalid_mfa_passkey"
+
+	// knownDeviceTTL is how long a device stays known without a login
+	knownDeviceTTL = 180 * 24 * time.Hour
+)
+
+// deviceFingerprint identifies a browser or app by the headers it sends on
+// every request. It deliberately ignores the IP address.
+func deviceFingerprint(client dto.ClientInfo) string {
+	return token.Hash(client.UserAgent + "|" + client.AcceptLanguage)[:32]
+}
+
+// recordLoginSuccess writes the login history and alerts the user when the
+// device has not been seen before. The very first device of an account never
+// triggers an alert. Failures are only logged so they cannot block a login.
+func (s *authService) recordLoginSuccess(ctx context.Context, user *entity.User, client dto.ClientInfo, method string) {
+	deviceID := deviceFingerprint(client)
+
+	isNew, firstDevice, err := s.repo.RememberDevice(ctx, user.ID, deviceID, knownDeviceTTL)
+	if err != nil {
+		log.Printf("[LOGIN HISTORY ERROR] remember device for %s: %v", user.ID, err)
+	}
+
+	event := &entity.LoginEvent{
+		ID:        uuid.New(),
+		UserID:    &user.ID,
+		Email:     user.Email,
+		Success:   true,
+		Method:    method,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		DeviceID:  deviceID,
+		NewDevice: isNew && !firstDevice,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateLoginEvent(ctx, event); err != nil {
+		log.Printf("[LOGIN HISTORY ERROR] %s: %v", user.ID, err)
+	}
+
+	if event.NewDevice {
+		s.notifyNewDevice(user, event)
+	}
+}
+
+// recordLoginFailureEvent writes a failed attempt. user is nil when the email
+// does not belong to an account.
+func (s *authService) recordLoginFailureEvent(ctx context.Context, email string, user *entity.User, client dto.ClientInfo, reason string) {
+	event := &entity.LoginEvent{
+		ID:            uuid.New(),
+		Email:         entity.NormalizeEmail(email),
+		Success:       false,
+		FailureReason: reason,
+		IP:            client.IP,
+		UserAgent:     client.UserAgent,
+		DeviceID:      deviceFingerprint(client),
+		CreatedAt:     time.Now(),
+	}
+	if user != nil {
+		event.UserID = &user.ID
+		event.Email = user.Email
+	}
+
+	if err := s.repo.CreateLoginEvent(ctx, event); err != nil {
+		log.Printf("[LOGIN HISTORY ERROR] failed attempt for %s: %v", event.Email, err)
+	}
+}
+
+func (s *authService) notifyNewDevice(user *entity.User, event *entity.LoginEvent) {
+	n := notifier.Notification{
+		UserID:  user.ID,
+		Email:   user.Email,
+		Subject: "New sign-in to your account",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account was just signed in to from a new device.\n\nTime: %s\nIP address: %s\nDevice: %s\n\nIf this was you, you can ignore this message. Otherwise change your password and sign out of all sessions right away.",
+			user.Name, event.CreatedAt.UTC().Format(time.RFC1123), event.IP, event.UserAgent),
+	}
+
+	go func() {
+		if err := s.notifier.Notify(context.Background(), n); err != nil {
+			log.Printf("[NOTIFY ERROR] new device alert for %s: %v", n.UserID, err)
+		}
+	}()
+}
+
+func (s *authService) ListLoginHistory(ctx context.Context, userID uuid.UUID, params dto.LoginHistoryParams) (*dto.LoginHistoryResponse, error) {
+	events, total, err := s.repo.ListLoginEvents(ctx, userID, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginHistoryResponse{
+		Data:        events,
+		CurrentPage: params.Page,
+		Limit:       params.Limit,
+		TotalPage:   (total + params.Limit - 1) / params.Limit,
+	}, nil
+}
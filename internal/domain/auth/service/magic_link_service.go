@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/dto"
+	"github.com/kenziehh/cashflow-be/internal/infra/mailer"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/token"
+)
+
+const (
+	magicLinkTTL        = 15 * time.Minute
+	magicLinkRequestGap = time.Minute
+)
+
+// RequestMagicLink emails a single-use login link. Like ForgotPassword it
+// behaves the same whether or not the email belongs to an account.
+func (s *authService) RequestMagicLink(ctx context.Context, req *dto.MagicLinkRequest) error {
+	ok, err := s.repo.AcquireCooldown(ctx, "magic_link:"+emailSubject(req.Email), magicLinkRequestGap)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errx.ErrTooManyRequests
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, req.Email)
+	if err == errx.ErrUserNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	linkToken, err := token.Generate()
+	if err != nil {
+		return errx.ErrInternalServer
+	}
+
+	if err := s.repo.StoreMagicLinkToken(ctx, user.ID, linkToken, magicLinkTTL); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/magic-link?token=%s", s.cfg.FrontendURL, url.QueryEscape(linkToken))
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to sign in. It expires in %d minutes and can only be used once.\n\n%s\n\nIf you did not request this, you can ignore this email.",
+			user.Name, int(magicLinkTTL.Minutes()), link),
+	}
+
+	s.sendMailAsync(msg)
+	return nil
+}
+
+// LoginMagicLink redeems a login link. Opening it proves ownership of the
+// mailbox, so it also verifies the email. Two-factor authentication still
+// applies, and a login lockout is only lifted once it has been answered.
+func (s *authService) LoginMagicLink(ctx context.Context, req *dto.MagicLinkLoginRequest, client dto.ClientInfo) (*dto.AuthResponse, error) {
+	userID, err := s.repo.ConsumeMagicLinkToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errx.ErrInvalidMagicLink
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := s.repo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	return s.completeLogin(ctx, user, client, loginMethodMagicLink)
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/config"
+	auditService "github.com/kenziehh/cashflow-be/internal/domain/audit/service"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/oidc"
+	"github.com/kenziehh/cashflow-be/pkg/rbac"
+	"github.com/kenziehh/cashflow-be/pkg/token"
+)
+
+const (
+	oidcStateTTL = 10 * time.Minute
+
+	auditOIDCLinked = "oidc_linked"
+)
+
+// newOIDCProvider returns nil when no issuer is configured, which disables
+// OpenID Connect login.
+func newOIDCProvider(cfg *config.Config) *oidc.Provider {
+	if cfg.OIDCIssuer == "" {
+		return nil
+	}
+
+	scopes := strings.Fields(cfg.OIDCScopes)
+	if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	return oidc.NewProvider(oidc.Config{
+		Issuer:       cfg.OIDCIssuer,
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  cfg.OIDCRedirectURL,
+		Scopes:       scopes,
+	}, nil)
+}
+
+// OIDCAuthorize starts an authorization code flow with PKCE. The state,
+// nonce and code verifier stay in Redis until the callback.
+func (s *authService) OIDCAuthorize(ctx context.Context) (*dto.OIDCAuthorizeResponse, error) {
+	if s.oidc == nil {
+		return nil, errx.ErrOIDCNotConfigured
+	}
+
+	state, err := token.Generate()
+	if err != nil {
+		return nil, errx.ErrInternalServer
+	}
+	nonce, err := token.Generate()
+	if err != nil {
+		return nil, errx.ErrInternalServer
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return nil, errx.ErrInternalServer
+	}
+
+	authURL, err := s.oidc.AuthCodeURL(ctx, state, nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		log.Printf("[OIDC ERROR] %v", err)
+		return nil, errx.ErrInternalServer
+	}
+
+	st := &entity.OIDCState{CodeVerifier: verifier, Nonce: nonce}
+	if err := s.repo.StoreOIDCState(ctx, state, st, oidcStateTTL); err != nil {
+		return nil, err
+	}
+
+	return &dto.OIDCAuthorizeResponse{
+		AuthorizationURL: authURL,
+		State:            state,
+	}, nil
+}
+
+// OIDCCallback redeems the authorization code, validates the ID token and
+// signs the matching user in.
+func (s *authService) OIDCCallback(ctx context.Context, req *dto.OIDCCallbackRequest, client dto.ClientInfo) (*dto.AuthResponse, error) {
+	if s.oidc == nil {
+		return nil, errx.ErrOIDCNotConfigured
+	}
+
+	st, err := s.repo.ConsumeOIDCState(ctx, req.State)
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := s.oidc.Exchange(ctx, req.Code, st.CodeVerifier)
+	if err != nil {
+		log.Printf("[OIDC ERROR] %v", err)
+		return nil, errx.ErrOIDCLoginFailed
+	}
+
+	idToken, err := s.oidc.VerifyIDToken(ctx, rawIDToken, st.Nonce)
+	if err != nil {
+		log.Printf("[OIDC ERROR] %v", err)
+		return nil, errx.ErrOIDCLoginFailed
+	}
+
+	user, err := s.resolveOIDCUser(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user, client, loginMethodOIDC)
+}
+
+// resolveOIDCUser returns the user linked to the identity. Unknown identities
+// are linked to the account with the same email, or get a new account without
+// a local password; both require the provider to have verified the email.
+func (s *authService) resolveOIDCUser(ctx context.Context, idToken *oidc.IDToken) (*entity.User, error) {
+	user, err := s.repo.GetUserByIdentity(ctx, idToken.Issuer, idToken.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if err != errx.ErrUserNotFound {
+		return nil, err
+	}
+
+	if idToken.Email == "" || !idToken.EmailVerified {
+		return nil, errx.ErrOIDCEmailNotVerified
+	}
+
+	user, err = s.repo.GetUserByEmail(ctx, idToken.Email)
+	switch {
+	case err == errx.ErrUserNotFound:
+		user, err = s.createOIDCUser(ctx, idToken)
+	case err == nil && user.EmailVerifiedAt == nil:
+		err = s.claimUnverifiedAccount(ctx, user)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &entity.UserIdentity{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Issuer:    idToken.Issuer,
+		Subject:   idToken.Subject,
+		Email:     idToken.Email,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateUserIdentity(ctx, identity); err != nil {
+		return nil, err
+	}
+
+	// The identity is linked by now, so a failed audit write is only logged
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     user.ID,
+		Action:     auditOIDCLinked,
+		TargetType: "user_identity",
+		TargetID:   identity.ID.String(),
+		Metadata:   map[string]any{"issuer": identity.Issuer},
+	})
+
+	return user, nil
+}
+
+func (s *authService) createOIDCUser(ctx context.Context, idToken *oidc.IDToken) (*entity.User, error) {
+	name := strings.TrimSpace(idToken.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(idToken.Email, "@")
+	}
+
+	now := time.Now()
+	user := &entity.User{
+		ID:          uuid.New(),
+		Email:       idToken.Email,
+		Name:        name,
+		Role:        rbac.RoleUser,
+		Preferences: entity.DefaultPreferences(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.createUser(ctx, user); err != nil {
+		return nil, err
+	}
+	if err := s.repo.MarkEmailVerified(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	user.EmailVerifiedAt = &now
+
+	return user, nil
+}
+
+// claimUnverifiedAccount hands an unverified account to the provider user.
+// Whoever registered it never proved they own the email, so their password
+// and sessions are dropped.
+func (s *authService) claimUnverifiedAccount(ctx context.Context, user *entity.User) error {
+	if err := s.repo.UpdatePassword(ctx, user.ID, ""); err != nil {
+		return err
+	}
+	if err := s.repo.MarkEmailVerified(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.LogoutAll(ctx, user.ID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.Password = ""
+	user.EmailVerifiedAt = &now
+	return nil
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/oidc/oidctest"
+)
+
+var testClient = dto.ClientInfo{IP: "203.0.113.7", UserAgent: "go-test"}
+
+// newOIDCTestService returns a service whose OpenID Connect provider is a
+// mock issuer served by httptest.
+func newOIDCTestService(t *testing.T) (*authService, *fakeRepository, *oidctest.Provider) {
+	t.Helper()
+
+	mock, err := oidctest.NewProvider("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	mock.Issuer = server.URL
+
+	cfg := testConfig()
+	cfg.OIDCIssuer = server.URL
+	cfg.OIDCClientID = "cashflow"
+	cfg.OIDCClientSecret = "secret"
+	cfg.OIDCRedirectURL = "http://localhost:3000/auth/oidc/callback"
+
+	s, repo, _ := newTestService(t, cfg)
+	return s, repo, mock
+}
+
+// oidcLogin runs the whole flow as the provider user with the given email.
+func oidcLogin(t *testing.T, s *authService, email string) (*dto.AuthResponse, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	start, err := s.OIDCAuthorize(ctx)
+	if err != nil {
+		t.Fatalf("OIDCAuthorize: %v", err)
+	}
+
+	code, state, err := oidctest.Authorize(start.AuthorizationURL, email)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if state != start.State {
+		t.Fatalf("state = %q, want %q", state, start.State)
+	}
+
+	return s.OIDCCallback(ctx, &dto.OIDCCallbackRequest{Code: code, State: state}, testClient)
+}
+
+func TestOIDCCreatesAccountWithoutPassword(t *testing.T) {
+	s, repo, mock := newOIDCTestService(t)
+
+	resp, err := oidcLogin(t, s, "new@example.com")
+	if err != nil {
+		t.Fatalf("OIDCCallback: %v", err)
+	}
+	if resp.Token == "" || resp.RefreshToken == "" {
+		t.Fatalf("expected tokens, got %+v", resp)
+	}
+
+	user, err := repo.GetUserByEmail(context.Background(), "new@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Password != "" {
+		t.Error("account created by OIDC has a local password")
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Error("account created by OIDC is not verified")
+	}
+	if len(repo.identities) != 1 || repo.identities[0].Subject != oidctest.Subject("new@example.com") || repo.identities[0].Issuer != mock.Issuer {
+		t.Fatalf("identities = %+v", repo.identities)
+	}
+	if created := s.accounts.(*fakeAccounts).created; len(created) != 1 || created[0] != user.ID {
+		t.Errorf("default accounts opened for %v, want [%s]", created, user.ID)
+	}
+
+	// The account has no password, so password login is refused
+	_, err = s.Login(context.Background(), &dto.LoginRequest{Email: "new@example.com", Password: ""}, testClient)
+	if !errors.Is(err, errx.ErrInvalidCredentials) {
+		t.Fatalf("password login error = %v, want %v", err, errx.ErrInvalidCredentials)
+	}
+
+	// Signing in again goes through the linked identity
+	again, err := oidcLogin(t, s, "new@example.com")
+	if err != nil {
+		t.Fatalf("second OIDCCallback: %v", err)
+	}
+	if again.User.ID != user.ID.String() {
+		t.Errorf("second login signed in %s, want %s", again.User.ID, user.ID)
+	}
+	if len(repo.identities) != 1 {
+		t.Errorf("second login linked another identity: %d", len(repo.identities))
+	}
+}
+
+func TestOIDCLinksVerifiedEmail(t *testing.T) {
+	s, repo, _ := newOIDCTestService(t)
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+
+	resp, err := oidcLogin(t, s, "ada@example.com")
+	if err != nil {
+		t.Fatalf("OIDCCallback: %v", err)
+	}
+	if resp.User.ID != user.ID.String() {
+		t.Fatalf("signed in %s, want the existing account %s", resp.User.ID, user.ID)
+	}
+	if len(repo.identities) != 1 || repo.identities[0].UserID != user.ID {
+		t.Fatalf("identities = %+v", repo.identities)
+	}
+	if repo.storedPassword(user.ID) != user.Password {
+		t.Error("linking a verified account changed its password")
+	}
+}
+
+func TestOIDCRefusesUnverifiedProviderEmail(t *testing.T) {
+	s, repo, mock := newOIDCTestService(t)
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	mock.EmailVerified = false
+
+	_, err := oidcLogin(t, s, "ada@example.com")
+	if !errors.Is(err, errx.ErrOIDCEmailNotVerified) {
+		t.Fatalf("OIDCCallback error = %v, want %v", err, errx.ErrOIDCEmailNotVerified)
+	}
+	if len(repo.identities) != 0 {
+		t.Fatalf("identity linked from an unverified email: %+v", repo.identities)
+	}
+	if repo.storedPassword(user.ID) != user.Password {
+		t.Error("existing account was changed")
+	}
+
+	// Nor is a new account created
+	if _, err := oidcLogin(t, s, "stranger@example.com"); !errors.Is(err, errx.ErrOIDCEmailNotVerified) {
+		t.Fatalf("OIDCCallback error = %v, want %v", err, errx.ErrOIDCEmailNotVerified)
+	}
+	if _, err := repo.GetUserByEmail(context.Background(), "stranger@example.com"); !errors.Is(err, errx.ErrUserNotFound) {
+		t.Error("account created from an unverified email")
+	}
+}
+
+func TestOIDCClaimsUnverifiedAccount(t *testing.T) {
+	s, repo, _ := newOIDCTestService(t)
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	repo.users[user.ID].EmailVerifiedAt = nil
+
+	resp, err := oidcLogin(t, s, "ada@example.com")
+	if err != nil {
+		t.Fatalf("OIDCCallback: %v", err)
+	}
+	if resp.User.ID != user.ID.String() {
+		t.Fatalf("signed in %s, want %s", resp.User.ID, user.ID)
+	}
+
+	// Whoever registered the unverified account loses its password
+	if repo.storedPassword(user.ID) != "" {
+		t.Error("claimed account kept the unverified registrant's password")
+	}
+	if repo.users[user.ID].EmailVerifiedAt == nil {
+		t.Error("claimed account is not verified")
+	}
+}
+
+func TestOIDCStateIsSingleUse(t *testing.T) {
+	s, _, _ := newOIDCTestService(t)
+	ctx := context.Background()
+
+	start, err := s.OIDCAuthorize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := oidctest.Authorize(start.AuthorizationURL, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.OIDCCallback(ctx, &dto.OIDCCallbackRequest{Code: code, State: state}, testClient); err != nil {
+		t.Fatalf("OIDCCallback: %v", err)
+	}
+	_, err = s.OIDCCallback(ctx, &dto.OIDCCallbackRequest{Code: code, State: state}, testClient)
+	if !errors.Is(err, errx.ErrInvalidOIDCState) {
+		t.Fatalf("replayed callback error = %v, want %v", err, errx.ErrInvalidOIDCState)
+	}
+}
+
+func TestOIDCRejectsNonceMismatch(t *testing.T) {
+	s, repo, _ := newOIDCTestService(t)
+	ctx := context.Background()
+
+	start, err := s.OIDCAuthorize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := oidctest.Authorize(start.AuthorizationURL, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ID token was issued for another login attempt
+	st := repo.oidcStates[state]
+	repo.oidcStates[state] = &entity.OIDCState{CodeVerifier: st.CodeVerifier, Nonce: "other-nonce"}
+
+	_, err = s.OIDCCallback(ctx, &dto.OIDCCallbackRequest{Code: code, State: state}, testClient)
+	if !errors.Is(err, errx.ErrOIDCLoginFailed) {
+		t.Fatalf("OIDCCallback error = %v, want %v", err, errx.ErrOIDCLoginFailed)
+	}
+}
+
+func TestOIDCRejectsWrongVerifier(t *testing.T) {
+	s, repo, _ := newOIDCTestService(t)
+	ctx := context.Background()
+
+	start, err := s.OIDCAuthorize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := oidctest.Authorize(start.AuthorizationURL, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A stolen code is useless without the verifier kept server side
+	st := repo.oidcStates[state]
+	repo.oidcStates[state] = &entity.OIDCState{CodeVerifier: "not-the-verifier", Nonce: st.Nonce}
+
+	_, err = s.OIDCCallback(ctx, &dto.OIDCCallbackRequest{Code: code, State: state}, testClient)
+	if !errors.Is(err, errx.ErrOIDCLoginFailed) {
+		t.Fatalf("OIDCCallback error = %v, want %v", err, errx.ErrOIDCLoginFailed)
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/kenziehh/cashflow-be/config"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/password"
+)
+
+func newPasswordHasher(cfg *config.Config) password.Hasher {
+	params := password.DefaultArgon2idParams
+	params.Memory = cfg.Argon2Memory
+	params.Iterations = cfg.Argon2Iterations
+	params.Parallelism = cfg.Argon2Parallelism
+	return password.NewArgon2idHasher(params)
+}
+
+// newPasswordPolicy falls back to the built-in common password list when the
+// configured breached list cannot be read.
+func newPasswordPolicy(cfg *config.Config) *password.Policy {
+	policy := password.NewPolicy(cfg.PasswordMinLength, cfg.PasswordMaxLength)
+	if cfg.BreachedPasswordsFile != "" {
+		if err := policy.LoadBreachedFile(cfg.BreachedPasswordsFile); err != nil {
+			log.Printf("⚠️  Breached password list not loaded: %v", err)
+		}
+	}
+	return policy
+}
+
+// hashNewPassword checks a password chosen by the user against the policy
+// and hashes it.
+func (s *authService) hashNewPassword(plain string) (string, error) {
+	if err := s.passwordPolicy.Validate(plain); err != nil {
+		return "", errx.NewBadRequestError(err.Error())
+	}
+
+	hashed, err := s.hasher.Hash(plain)
+	if err != nil {
+		return "", errx.ErrInternalServer
+	}
+	return hashed, nil
+}
+
+// upgradePasswordHash re-hashes a verified password whose stored hash uses a
+// legacy algorithm or outdated parameters. Failures only delay the upgrade to
+// the next login.
+func (s *authService) upgradePasswordHash(ctx context.Context, user *entity.User, plain string) {
+	if !s.hasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hashed, err := s.hasher.Hash(plain)
+	if err == nil {
+		err = s.repo.ReplacePasswordHash(ctx, user.ID, user.Password, hashed)
+	}
+	if err != nil {
+		log.Printf("[PASSWORD REHASH ERROR] user %s: %v", user.ID, err)
+		return
+	}
+	user.Password = hashed
+}
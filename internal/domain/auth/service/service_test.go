@@ -0,0 +1,542 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/config"
+	accountService "github.com/kenziehh/cashflow-be/internal/domain/account/service"
+	apiKeyService "github.com/kenziehh/cashflow-be/internal/domain/api_key/service"
+	auditService "github.com/kenziehh/cashflow-be/internal/domain/audit/service"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/repository"
+	"github.com/kenziehh/cashflow-be/internal/infra/mailer"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/jwt"
+)
+
+func TestMain(m *testing.M) {
+	keyring, err := jwt.NewKeyring(jwt.DefaultKeyID, jwt.NewHMACKey(jwt.DefaultKeyID, []byte("test-secret")))
+	if err != nil {
+		panic(err)
+	}
+	jwt.SetKeyring(keyring)
+
+	os.Exit(m.Run())
+}
+
+// testConfig has cheap Argon2 parameters and a passkey relying party for
+// http://localhost:3000.
+func testConfig() *config.Config {
+	return &config.Config{
+		FrontendURL:       "http://localhost:3000",
+		WebAuthnRPID:      "localhost",
+		WebAuthnRPName:    "Cashflow",
+		WebAuthnOrigins:   "http://localhost:3000",
+		OIDCScopes:        "openid email profile",
+		Argon2Memory:      64,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+		PasswordMinLength: 8,
+		PasswordMaxLength: 128,
+	}
+}
+
+func newTestService(t *testing.T, cfg *config.Config) (*authService, *fakeRepository, *fakeAudit) {
+	t.Helper()
+
+	repo := newFakeRepository()
+	audit := &fakeAudit{}
+	s := NewAuthService(repo, &fakeAccounts{}, &fakeAPIKeys{}, nil, fakeMailer{}, cfg, audit, nil).(*authService)
+	return s, repo, audit
+}
+
+// newTestUser stores a verified user with the given password, or without a
+// local password when it is empty.
+func newTestUser(t *testing.T, s *authService, repo *fakeRepository, email, plain string) *entity.User {
+	t.Helper()
+
+	now := time.Now()
+	user := &entity.User{
+		ID:              uuid.New(),
+		Email:           email,
+		Name:            "Test User",
+		Role:            "user",
+		EmailVerifiedAt: &now,
+		Preferences:     entity.DefaultPreferences(),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if plain != "" {
+		hashed, err := s.hasher.Hash(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.Password = hashed
+	}
+
+	if err := repo.CreateUser(context.Background(), user, nil); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+type fakeMailer struct{}
+
+func (fakeMailer) Send(context.Context, mailer.Message) error {
+	return nil
+}
+
+// fakeAccounts records the users default accounts were opened for, or fails
+// with err.
+type fakeAccounts struct {
+	accountService.AccountService
+
+	mu      sync.Mutex
+	created []uuid.UUID
+	err     error
+}
+
+func (a *fakeAccounts) CreateDefaultAccount(_ context.Context, _ *sql.Tx, userID uuid.UUID) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err != nil {
+		return a.err
+	}
+	a.created = append(a.created, userID)
+	return nil
+}
+
+// fakeAPIKeys records the users whose API keys were all revoked.
+type fakeAPIKeys struct {
+	apiKeyService.APIKeyService
+
+	mu      sync.Mutex
+	revoked []uuid.UUID
+}
+
+func (k *fakeAPIKeys) RevokeAllAPIKeys(_ context.Context, userID uuid.UUID) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.revoked = append(k.revoked, userID)
+	return nil
+}
+
+func (k *fakeAPIKeys) revokedFor(userID uuid.UUID) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	n := 0
+	for _, id := range k.revoked {
+		if id == userID {
+			n++
+		}
+	}
+	return n
+}
+
+type fakeAudit struct {
+	auditService.AuditService
+
+	mu     sync.Mutex
+	events []auditService.Event
+}
+
+func (a *fakeAudit) Record(_ context.Context, event auditService.Event) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, event)
+	return nil
+}
+
+func (a *fakeAudit) actions() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	actions := make([]string, 0, len(a.events))
+	for _, event := range a.events {
+		actions = append(actions, event.Action)
+	}
+	return actions
+}
+
+// fakeRepository keeps the state the login flows touch in memory. Methods
+// the tests do not need panic through the embedded nil interface.
+type fakeRepository struct {
+	repository.AuthRepository
+
+	mu               sync.Mutex
+	users            map[uuid.UUID]*entity.User
+	identities       []*entity.UserIdentity
+	totp             map[uuid.UUID]*entity.UserTOTP
+	recoveryCodes    map[uuid.UUID][]string
+	credentials      []*entity.WebAuthnCredential
+	webauthnSessions map[string]*entity.WebAuthnSession
+	oidcStates       map[string]*entity.OIDCState
+	tokenVersions    map[uuid.UUID]int64
+	counters         map[string]int64
+	cooldowns        map[string]bool
+	loginLocks       map[string]time.Duration
+	sessions         []*entity.Session
+	refreshTokens    map[string]*entity.RefreshToken
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		users:            map[uuid.UUID]*entity.User{},
+		totp:             map[uuid.UUID]*entity.UserTOTP{},
+		recoveryCodes:    map[uuid.UUID][]string{},
+		webauthnSessions: map[string]*entity.WebAuthnSession{},
+		oidcStates:       map[string]*entity.OIDCState{},
+		tokenVersions:    map[uuid.UUID]int64{},
+		counters:         map[string]int64{},
+		cooldowns:        map[string]bool{},
+		loginLocks:       map[string]time.Duration{},
+		refreshTokens:    map[string]*entity.RefreshToken{},
+	}
+}
+
+// CreateUser stores nothing when setup fails, as a rolled back transaction.
+func (r *fakeRepository) CreateUser(_ context.Context, user *entity.User, setup func(tx *sql.Tx) error) error {
+	if setup != nil {
+		if err := setup(nil); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user.Email = entity.NormalizeEmail(user.Email)
+	stored := *user
+	r.users[user.ID] = &stored
+	return nil
+}
+
+func (r *fakeRepository) GetUserByEmail(_ context.Context, email string) (*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Email == entity.NormalizeEmail(email) {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, errx.ErrUserNotFound
+}
+
+func (r *fakeRepository) GetUserByID(_ context.Context, id uuid.UUID) (*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errx.ErrUserNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+func (r *fakeRepository) UpdatePassword(_ context.Context, userID uuid.UUID, hashedPassword string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[userID].Password = hashedPassword
+	return nil
+}
+
+func (r *fakeRepository) ReplacePasswordHash(_ context.Context, userID uuid.UUID, oldHash, newHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user := r.users[userID]; user.Password == oldHash {
+		user.Password = newHash
+	}
+	return nil
+}
+
+func (r *fakeRepository) MarkEmailVerified(_ context.Context, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.users[userID].EmailVerifiedAt = &now
+	return nil
+}
+
+func (r *fakeRepository) GetUserByIdentity(_ context.Context, issuer, subject string) (*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, identity := range r.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			found := *r.users[identity.UserID]
+			return &found, nil
+		}
+	}
+	return nil, errx.ErrUserNotFound
+}
+
+func (r *fakeRepository) CreateUserIdentity(_ context.Context, identity *entity.UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.identities {
+		if existing.Issuer == identity.Issuer && existing.Subject == identity.Subject {
+			return errx.ErrIdentityAlreadyLinked
+		}
+	}
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *fakeRepository) StoreOIDCState(_ context.Context, state string, st *entity.OIDCState, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.oidcStates[state] = st
+	return nil
+}
+
+func (r *fakeRepository) ConsumeOIDCState(_ context.Context, state string) (*entity.OIDCState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st, ok := r.oidcStates[state]
+	if !ok {
+		return nil, errx.ErrInvalidOIDCState
+	}
+	delete(r.oidcStates, state)
+	return st, nil
+}
+
+func (r *fakeRepository) GetTOTP(_ context.Context, userID uuid.UUID) (*entity.UserTOTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	userTOTP, ok := r.totp[userID]
+	if !ok {
+		return nil, errx.ErrTOTPNotEnrolled
+	}
+	return userTOTP, nil
+}
+
+func (r *fakeRepository) UseRecoveryCode(_ context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, hash := range r.recoveryCodes[userID] {
+		if hash == codeHash {
+			r.recoveryCodes[userID] = append(r.recoveryCodes[userID][:i], r.recoveryCodes[userID][i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepository) CreateWebAuthnCredential(_ context.Context, cred *entity.WebAuthnCredential) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.credentials = append(r.credentials, cred)
+	return nil
+}
+
+func (r *fakeRepository) ListWebAuthnCredentials(_ context.Context, userID uuid.UUID) ([]*entity.WebAuthnCredential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	creds := []*entity.WebAuthnCredential{}
+	for _, cred := range r.credentials {
+		if cred.UserID == userID {
+			found := *cred
+			creds = append(creds, &found)
+		}
+	}
+	return creds, nil
+}
+
+func (r *fakeRepository) UpdateWebAuthnCredentialUsage(_ context.Context, credentialID []byte, signCount uint32, backupState bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cred := range r.credentials {
+		if bytes.Equal(cred.CredentialID, credentialID) {
+			now := time.Now()
+			cred.SignCount = signCount
+			cred.BackupState = backupState
+			cred.LastUsedAt = &now
+			return nil
+		}
+	}
+	return errx.ErrPasskeyNotFound
+}
+
+func (r *fakeRepository) DeleteWebAuthnCredential(_ context.Context, userID, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, cred := range r.credentials {
+		if cred.ID == id && cred.UserID == userID {
+			r.credentials = append(r.credentials[:i], r.credentials[i+1:]...)
+			return nil
+		}
+	}
+	return errx.ErrPasskeyNotFound
+}
+
+func (r *fakeRepository) StoreWebAuthnSession(_ context.Context, key string, session *entity.WebAuthnSession, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.webauthnSessions[key] = session
+	return nil
+}
+
+func (r *fakeRepository) ConsumeWebAuthnSession(_ context.Context, key string) (*entity.WebAuthnSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.webauthnSessions[key]
+	if !ok {
+		return nil, errx.ErrInvalidPasskeySession
+	}
+	delete(r.webauthnSessions, key)
+	return session, nil
+}
+
+func (r *fakeRepository) GetTokenVersion(_ context.Context, userID uuid.UUID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tokenVersions[userID], nil
+}
+
+func (r *fakeRepository) IncrementTokenVersion(_ context.Context, userID uuid.UUID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokenVersions[userID]++
+	return r.tokenVersions[userID], nil
+}
+
+func (r *fakeRepository) RevokeAllRefreshTokenFamilies(_ context.Context, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, rt := range r.refreshTokens {
+		if rt.UserID == userID {
+			delete(r.refreshTokens, key)
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) RevokeRefreshTokenFamily(_ context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, rt := range r.refreshTokens {
+		if rt.FamilyID == familyID {
+			delete(r.refreshTokens, key)
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) StoreRefreshToken(_ context.Context, token string, rt *entity.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refreshTokens[token] = rt
+	return nil
+}
+
+func (r *fakeRepository) CreateSession(_ context.Context, session *entity.Session, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions = append(r.sessions, session)
+	return nil
+}
+
+func (r *fakeRepository) ListSessions(_ context.Context, userID uuid.UUID) ([]*entity.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := []*entity.Session{}
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			found := *session
+			sessions = append(sessions, &found)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *fakeRepository) GetSession(_ context.Context, sessionID string) (*entity.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range r.sessions {
+		if session.ID == sessionID {
+			found := *session
+			return &found, nil
+		}
+	}
+	return nil, errx.ErrSessionNotFound
+}
+
+func (r *fakeRepository) AcquireCooldown(_ context.Context, key string, _ time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cooldowns[key] {
+		return false, nil
+	}
+	r.cooldowns[key] = true
+	return true, nil
+}
+
+func (r *fakeRepository) ReleaseCooldown(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cooldowns, key)
+	return nil
+}
+
+func (r *fakeRepository) IncrementCounter(_ context.Context, key string, _ time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[key]++
+	return r.counters[key], nil
+}
+
+func (r *fakeRepository) RecordLoginFailure(_ context.Context, subject string, _ time.Duration) (int64, error) {
+	return r.IncrementCounter(context.Background(), "login_failures:"+subject, 0)
+}
+
+// GetLoginLock reports a lock until ClearLoginFailures; locks never expire
+// on their own here.
+func (r *fakeRepository) GetLoginLock(_ context.Context, subject string) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.loginLocks[subject], nil
+}
+
+func (r *fakeRepository) LockLogin(_ context.Context, subject string, duration time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loginLocks[subject] = duration
+	return nil
+}
+
+func (r *fakeRepository) ClearLoginFailures(_ context.Context, subject string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.counters, "login_failures:"+subject)
+	delete(r.loginLocks, subject)
+	return nil
+}
+
+// loginFailures returns the failures counted against subject.
+func (r *fakeRepository) loginFailures(subject string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counters["login_failures:"+subject]
+}
+
+func (r *fakeRepository) CreateLoginEvent(context.Context, *entity.LoginEvent) error {
+	return nil
+}
+
+// RememberDevice reports every device as the user's first, so no new-device
+// notice is sent.
+func (r *fakeRepository) RememberDevice(context.Context, uuid.UUID, string, time.Duration) (bool, bool, error) {
+	return true, true, nil
+}
+
+// storedPassword returns the user's password hash as the repository has it.
+func (r *fakeRepository) storedPassword(userID uuid.UUID) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.users[userID].Password
+}
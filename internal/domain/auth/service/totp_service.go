@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/jwt"
+	"github.com/kenziehh/cashflow-be/pkg/token"
+	"github.com/kenziehh/cashflow-be/pkg/totp"
+)
+
+const (
+	totpIssuer        = "Cashflow"
+	recoveryCodeCount = 10
+)
+
+// LoginMFA completes a login that was answered with an mfa_required challenge.
+func (s *authService) LoginMFA(ctx context.Context, req *dto.LoginMFARequest, client dto.ClientInfo) (*dto.AuthResponse, error) {
+	challengeID, user, err := s.startMFAAttempt(ctx, req.MFAToken, client)
+	if err != nil {
+		return nil, err
+	}
+
+	userTOTP, err := s.repo.GetTOTP(ctx, user.ID)
+	if err != nil || userTOTP.EnabledAt == nil {
+		return nil, errx.ErrInvalidMFAToken
+	}
+
+	// Claimed before the code is checked, so replaying a redeemed challenge
+	// cannot use up a recovery code
+	if err := s.claimMFAChallenge(ctx, challengeID); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.checkSecondFactor(ctx, user.ID, userTOTP.Secret, req.Code)
+	if err != nil {
+		s.releaseMFAChallenge(ctx, challengeID)
+		return nil, err
+	}
+	if !ok {
+		s.releaseMFAChallenge(ctx, challengeID)
+		if err := s.recordLoginFailure(ctx, user.Email, client, user, loginFailureMFACode); err != nil {
+			return nil, err
+		}
+		return nil, errx.ErrInvalidMFACode
+	}
+
+	return s.startSession(ctx, user, client, loginMethodTOTP)
+}
+
+// EnrollTOTP creates a new pending secret. It only takes effect after
+// ConfirmTOTP, so a half-finished enrollment never locks the user out.
+func (s *authService) EnrollTOTP(ctx context.Context, userID uuid.UUID) (*dto.TOTPEnrollResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.isTOTPEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, errx.ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, errx.ErrInternalServer
+	}
+
+	if err := s.repo.SaveTOTPSecret(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &dto.TOTPEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables 2FA once the user proves their authenticator works and
+// hands out the recovery codes. They are only ever shown here.
+func (s *authService) ConfirmTOTP(ctx context.Context, userID uuid.UUID, req *dto.TOTPConfirmRequest) (*dto.TOTPConfirmResponse, error) {
+	userTOTP, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if userTOTP.EnabledAt != nil {
+		return nil, errx.ErrTOTPAlreadyEnabled
+	}
+
+	if _, ok := totp.Validate(req.Code, userTOTP.Secret, time.Now()); !ok {
+		return nil, errx.NewBadRequestError("Invalid two-factor authentication code")
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, errx.ErrInternalServer
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, token.Hash(normalizeRecoveryCode(code)))
+	}
+
+	if err := s.repo.EnableTOTP(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &dto.TOTPConfirmResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *authService) DisableTOTP(ctx context.Context, userID uuid.UUID, req *dto.TOTPDisableRequest) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Accounts without a local password only confirm with the second factor
+	if user.Password != "" && !s.hasher.Verify(req.Password, user.Password) {
+		return errx.ErrIncorrectPassword
+	}
+
+	userTOTP, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if userTOTP.EnabledAt == nil {
+		return errx.ErrTOTPNotEnrolled
+	}
+
+	ok, err := s.checkSecondFactor(ctx, userID, userTOTP.Secret, req.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errx.ErrInvalidMFACode
+	}
+
+	return s.repo.DeleteTOTP(ctx, userID)
+}
+
+func (s *authService) isTOTPEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	userTOTP, err := s.repo.GetTOTP(ctx, userID)
+	if err == errx.ErrTOTPNotEnrolled {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return userTOTP.EnabledAt != nil, nil
+}
+
+// checkSecondFactor accepts either a TOTP code, which cannot be replayed within
+// its validity window, or an unused recovery code.
+func (s *authService) checkSecondFactor(ctx context.Context, userID uuid.UUID, secret, code string) (bool, error) {
+	if counter, ok := totp.Validate(code, secret, time.Now()); ok {
+		window := totp.Period * time.Duration(2*totp.Skew+1)
+		return s.repo.AcquireCooldown(ctx, fmt.Sprintf("totp:%s:%d", userID, counter), window)
+	}
+
+	return s.repo.UseRecoveryCode(ctx, userID, token.Hash(normalizeRecoveryCode(code)))
+}
+
+// startMFAAttempt checks a login challenge before a second factor is tried
+// against it. Each challenge allows mfaMaxAttempts tries, and none while the
+// user's email or the client's IP is locked out.
+func (s *authService) startMFAAttempt(ctx context.Context, mfaToken string, client dto.ClientInfo) (string, *entity.User, error) {
+	challengeID, userID, err := parseMFAToken(mfaToken)
+	if err != nil {
+		return "", nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", nil, errx.ErrInvalidMFAToken
+	}
+
+	if err := s.checkLoginLock(ctx, user.Email, client.IP); err != nil {
+		s.recordLoginFailureEvent(ctx, user.Email, user, client, loginFailureLocked)
+		return "", nil, err
+	}
+
+	attempts, err := s.repo.IncrementCounter(ctx, "mfa_attempts:"+challengeID, mfaChallengeTTL)
+	if err != nil {
+		return "", nil, err
+	}
+	if attempts > mfaMaxAttempts {
+		return "", nil, errx.ErrTooManyRequests
+	}
+
+	return challengeID, user, nil
+}
+
+// claimMFAChallenge marks a login challenge as redeemed. A challenge can only
+// be redeemed once, so a second claim fails with ErrInvalidMFAToken.
+func (s *authService) claimMFAChallenge(ctx context.Context, challengeID string) error {
+	fresh, err := s.repo.AcquireCooldown(ctx, "mfa_challenge:"+challengeID, mfaChallengeTTL)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return errx.ErrInvalidMFAToken
+	}
+	return nil
+}
+
+// releaseMFAChallenge lets a claimed challenge be answered again after a wrong
+// code. The attempt counter still limits how often that can happen.
+func (s *authService) releaseMFAChallenge(ctx context.Context, challengeID string) {
+	if err := s.repo.ReleaseCooldown(ctx, "mfa_challenge:"+challengeID); err != nil {
+		log.Printf("[MFA ERROR] release challenge %s: %v", challengeID, err)
+	}
+}
+
+// generateRecoveryCode returns a code such as "k3v9q-ma2xp".
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
+
+// parseMFAToken returns the challenge ID and user of a login challenge token.
+func parseMFAToken(mfaToken string) (string, uuid.UUID, error) {
+	claims, err := jwt.ValidatePurposeToken(mfaToken, purposeMFAChallenge)
+	if err != nil {
+		return "", uuid.Nil, errx.ErrInvalidMFAToken
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return "", uuid.Nil, errx.ErrInvalidMFAToken
+	}
+
+	return claims.ID, userID, nil
+}
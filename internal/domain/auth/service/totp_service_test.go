@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/token"
+	"github.com/kenziehh/cashflow-be/pkg/totp"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+// enableTestTOTP turns on TOTP for the user with the given recovery codes.
+func enableTestTOTP(repo *fakeRepository, userID uuid.UUID, recoveryCodes ...string) {
+	now := time.Now()
+	repo.totp[userID] = &entity.UserTOTP{UserID: userID, Secret: testTOTPSecret, EnabledAt: &now}
+	for _, code := range recoveryCodes {
+		repo.recoveryCodes[userID] = append(repo.recoveryCodes[userID], token.Hash(normalizeRecoveryCode(code)))
+	}
+}
+
+// startTestMFALogin signs in with the password and returns the challenge.
+func startTestMFALogin(t *testing.T, s *authService, user *entity.User) string {
+	t.Helper()
+
+	resp, err := s.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: "correct horse battery"}, testClient)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !resp.MFARequired {
+		t.Fatalf("expected an MFA challenge, got %+v", resp)
+	}
+	return resp.MFAToken
+}
+
+func TestLoginMFA(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	enableTestTOTP(repo, user.ID)
+	ctx := context.Background()
+
+	mfaToken := startTestMFALogin(t, s, user)
+
+	// A wrong code can be corrected within the same challenge
+	_, err := s.LoginMFA(ctx, &dto.LoginMFARequest{MFAToken: mfaToken, Code: "000000"}, testClient)
+	if !errors.Is(err, errx.ErrInvalidMFACode) {
+		t.Fatalf("LoginMFA with a wrong code error = %v, want %v", err, errx.ErrInvalidMFACode)
+	}
+
+	code, err := totp.Code(testTOTPSecret, totp.Counter(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.LoginMFA(ctx, &dto.LoginMFARequest{MFAToken: mfaToken, Code: code}, testClient)
+	if err != nil {
+		t.Fatalf("LoginMFA: %v", err)
+	}
+	if resp.Token == "" || resp.RefreshToken == "" {
+		t.Fatalf("expected tokens, got %+v", resp)
+	}
+
+	// The same code cannot be used again, even with a new challenge
+	_, err = s.LoginMFA(ctx, &dto.LoginMFARequest{MFAToken: startTestMFALogin(t, s, user), Code: code}, testClient)
+	if !errors.Is(err, errx.ErrInvalidMFACode) {
+		t.Fatalf("LoginMFA with a used code error = %v, want %v", err, errx.ErrInvalidMFACode)
+	}
+}
+
+func TestLoginMFAReplayKeepsRecoveryCode(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	enableTestTOTP(repo, user.ID, "aaaaa-bbbbb", "ccccc-ddddd")
+	ctx := context.Background()
+
+	mfaToken := startTestMFALogin(t, s, user)
+	if _, err := s.LoginMFA(ctx, &dto.LoginMFARequest{MFAToken: mfaToken, Code: "aaaaa-bbbbb"}, testClient); err != nil {
+		t.Fatalf("LoginMFA: %v", err)
+	}
+
+	// Replaying the redeemed challenge fails before the code is looked at
+	_, err := s.LoginMFA(ctx, &dto.LoginMFARequest{MFAToken: mfaToken, Code: "ccccc-ddddd"}, testClient)
+	if !errors.Is(err, errx.ErrInvalidMFAToken) {
+		t.Fatalf("replayed LoginMFA error = %v, want %v", err, errx.ErrInvalidMFAToken)
+	}
+	if len(repo.recoveryCodes[user.ID]) != 1 {
+		t.Fatalf("%d recovery codes left, want 1", len(repo.recoveryCodes[user.ID]))
+	}
+
+	if _, err := s.LoginMFA(ctx, &dto.LoginMFARequest{MFAToken: startTestMFALogin(t, s, user), Code: "ccccc-ddddd"}, testClient); err != nil {
+		t.Fatalf("LoginMFA with the remaining recovery code: %v", err)
+	}
+}
+
+func TestWrongMFACodesLockTheAccount(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	enableTestTOTP(repo, user.ID)
+	ctx := context.Background()
+
+	// The password is known, so every login hands out a fresh challenge, but
+	// the wrong codes add up across challenges
+	for i := 1; i < emailMaxFailures; i++ {
+		mfaToken := startTestMFALogin(t, s, user)
+		_, err := s.LoginMFA(ctx, &dto.LoginMFARequest{MFAToken: mfaToken, Code: "000000"}, testClient)
+		if !errors.Is(err, errx.ErrInvalidMFACode) {
+			t.Fatalf("wrong code %d error = %v, want %v", i, err, errx.ErrInvalidMFACode)
+		}
+	}
+	if got := repo.loginFailures(emailSubject(user.Email)); got != emailMaxFailures-1 {
+		t.Fatalf("%d failures counted, want %d", got, emailMaxFailures-1)
+	}
+
+	mfaToken := startTestMFALogin(t, s, user)
+	_, err := s.LoginMFA(ctx, &dto.LoginMFARequest{MFAToken: mfaToken, Code: "000000"}, testClient)
+	if !isAccountLocked(err) {
+		t.Fatalf("wrong code %d error = %v, want a lockout", emailMaxFailures, err)
+	}
+
+	// Neither the password nor a correct code get past the lock
+	_, err = s.Login(ctx, &dto.LoginRequest{Email: user.Email, Password: "correct horse battery"}, testClient)
+	if !isAccountLocked(err) {
+		t.Fatalf("Login error = %v, want a lockout", err)
+	}
+	code, err := totp.Code(testTOTPSecret, totp.Counter(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.LoginMFA(ctx, &dto.LoginMFARequest{MFAToken: mfaToken, Code: code}, testClient)
+	if !isAccountLocked(err) {
+		t.Fatalf("LoginMFA with a correct code error = %v, want a lockout", err)
+	}
+}
+
+func TestCompletedMFALoginClearsFailures(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	enableTestTOTP(repo, user.ID, "aaaaa-bbbbb")
+	ctx := context.Background()
+
+	mfaToken := startTestMFALogin(t, s, user)
+	_, err := s.LoginMFA(ctx, &dto.LoginMFARequest{MFAToken: mfaToken, Code: "zzzzz-zzzzz"}, testClient)
+	if !errors.Is(err, errx.ErrInvalidMFACode) {
+		t.Fatalf("wrong recovery code error = %v, want %v", err, errx.ErrInvalidMFACode)
+	}
+
+	// The password alone does not reset the count
+	mfaToken = startTestMFALogin(t, s, user)
+	if got := repo.loginFailures(emailSubject(user.Email)); got != 1 {
+		t.Fatalf("%d failures counted after the password, want 1", got)
+	}
+
+	if _, err := s.LoginMFA(ctx, &dto.LoginMFARequest{MFAToken: mfaToken, Code: "aaaaa-bbbbb"}, testClient); err != nil {
+		t.Fatalf("LoginMFA: %v", err)
+	}
+	if got := repo.loginFailures(emailSubject(user.Email)); got != 0 {
+		t.Fatalf("%d failures counted after a completed login, want 0", got)
+	}
+}
+
+// isAccountLocked reports whether err is a login lockout.
+func isAccountLocked(err error) bool {
+	var appErr *errx.AppError
+	return errors.As(err, &appErr) && appErr.Message == errx.ErrAccountLocked.Message
+}
@@ -0,0 +1,450 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/config"
+	auditService "github.com/kenziehh/cashflow-be/internal/domain/audit/service"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/token"
+)
+
+const (
+	passkeyCeremonyTTL = 5 * time.Minute
+	maxPasskeysPerUser = 10
+
+	mfaMethodTOTP    = "totp"
+	mfaMethodPasskey = "passkey"
+
+	auditPasskeyRegistered   = "passkey_registered"
+	auditPasskeyDeleted      = "passkey_deleted"
+	auditPasskeyCloneWarning = "passkey_clone_warning"
+)
+
+// newWebAuthn returns nil, disabling passkeys, when the relying party settings
+// are invalid.
+func newWebAuthn(cfg *config.Config) *webauthn.WebAuthn {
+	var origins []string
+	for _, origin := range strings.Split(cfg.WebAuthnOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: passkeyCeremonyTTL, TimeoutUVD: passkeyCeremonyTTL}
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthnRPID,
+		RPDisplayName: cfg.WebAuthnRPName,
+		RPOrigins:     origins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementPreferred,
+			UserVerification: protocol.VerificationPreferred,
+		},
+		Timeouts: webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+	if err != nil {
+		log.Printf("⚠️  Passkeys disabled: %v", err)
+		return nil
+	}
+	return wa
+}
+
+// webAuthnUser adapts a user and their passkeys to webauthn.User. The user
+// handle is the raw UUID, so it carries no personal data.
+type webAuthnUser struct {
+	user  *entity.User
+	creds []*entity.WebAuthnCredential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return u.user.ID[:]
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Name
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	creds := make([]webauthn.Credential, 0, len(u.creds))
+	for _, c := range u.creds {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+		for _, t := range c.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+
+		creds = append(creds, webauthn.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: c.BackupEligible,
+				BackupState:    c.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    c.AAGUID,
+				SignCount: c.SignCount,
+			},
+		})
+	}
+	return creds
+}
+
+func (s *authService) loadWebAuthnUser(ctx context.Context, userID uuid.UUID) (*webAuthnUser, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := s.repo.ListWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webAuthnUser{user: user, creds: creds}, nil
+}
+
+// BeginPasskeyRegistration returns the options for navigator.credentials.create().
+// A passkey signs its owner in on its own, so the user has to confirm who they
+// are first; FinishPasskeyRegistration only completes a ceremony started here.
+func (s *authService) BeginPasskeyRegistration(ctx context.Context, userID uuid.UUID, sessionID string, req *dto.PasskeyRegisterBeginRequest) (*protocol.CredentialCreation, error) {
+	if s.webauthn == nil {
+		return nil, errx.ErrPasskeysNotConfigured
+	}
+
+	waUser, err := s.loadWebAuthnUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reauthenticate(ctx, waUser.user, sessionID, req.Password, req.Code); err != nil {
+		return nil, err
+	}
+	if len(waUser.creds) >= maxPasskeysPerUser {
+		return nil, errx.NewBadRequestError(fmt.Sprintf("You can register at most %d passkeys", maxPasskeysPerUser))
+	}
+
+	// Excluding known credentials stops the same authenticator from being
+	// registered twice
+	exclusions := webauthn.Credentials(waUser.WebAuthnCredentials()).CredentialDescriptors()
+	creation, session, err := s.webauthn.BeginRegistration(waUser, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		log.Printf("[WEBAUTHN ERROR] begin registration: %v", err)
+		return nil, errx.ErrInternalServer
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = fmt.Sprintf("Passkey %d", len(waUser.creds)+1)
+	}
+
+	ceremony := &entity.WebAuthnSession{Data: *session, Name: name}
+	if err := s.repo.StoreWebAuthnSession(ctx, "register:"+userID.String(), ceremony, passkeyCeremonyTTL); err != nil {
+		return nil, err
+	}
+
+	return creation, nil
+}
+
+// FinishPasskeyRegistration verifies the attestation and stores the new passkey.
+func (s *authService) FinishPasskeyRegistration(ctx context.Context, userID uuid.UUID, req *dto.PasskeyCredentialRequest) (*entity.WebAuthnCredential, error) {
+	if s.webauthn == nil {
+		return nil, errx.ErrPasskeysNotConfigured
+	}
+
+	ceremony, err := s.repo.ConsumeWebAuthnSession(ctx, "register:"+userID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	waUser, err := s.loadWebAuthnUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, errx.NewBadRequestError("Invalid passkey credential")
+	}
+
+	credential, err := s.webauthn.CreateCredential(waUser, ceremony.Data, parsed)
+	if err != nil {
+		log.Printf("[WEBAUTHN ERROR] finish registration for %s: %v", userID, err)
+		return nil, errx.ErrPasskeyVerificationFailed
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+
+	cred := &entity.WebAuthnCredential{
+		ID:              uuid.New(),
+		UserID:          userID,
+		CredentialID:    credential.ID,
+		Name:            ceremony.Name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      transports,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		CreatedAt:       time.Now(),
+	}
+	if err := s.repo.CreateWebAuthnCredential(ctx, cred); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditPasskeyRegistered,
+		TargetType: "passkey",
+		TargetID:   cred.ID.String(),
+		Metadata:   map[string]any{"name": cred.Name},
+	})
+
+	return cred, nil
+}
+
+func (s *authService) ListPasskeys(ctx context.Context, userID uuid.UUID) ([]*entity.WebAuthnCredential, error) {
+	return s.repo.ListWebAuthnCredentials(ctx, userID)
+}
+
+// DeletePasskey removes one of the user's passkeys after they confirm who
+// they are, as it may be their only second factor.
+func (s *authService) DeletePasskey(ctx context.Context, userID uuid.UUID, sessionID string, passkeyID uuid.UUID, req *dto.PasskeyDeleteRequest) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.reauthenticate(ctx, user, sessionID, req.Password, req.Code); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteWebAuthnCredential(ctx, userID, passkeyID); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditPasskeyDeleted,
+		TargetType: "passkey",
+		TargetID:   passkeyID.String(),
+	})
+	return nil
+}
+
+// BeginPasskeyLogin starts a usernameless login with a discoverable
+// credential. The returned session ID has to be sent back with the assertion.
+func (s *authService) BeginPasskeyLogin(ctx context.Context) (*dto.PasskeyLoginBeginResponse, error) {
+	if s.webauthn == nil {
+		return nil, errx.ErrPasskeysNotConfigured
+	}
+
+	// A passkey used as the only factor must prove user verification
+	assertion, session, err := s.webauthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		log.Printf("[WEBAUTHN ERROR] begin login: %v", err)
+		return nil, errx.ErrInternalServer
+	}
+
+	sessionID, err := token.Generate()
+	if err != nil {
+		return nil, errx.ErrInternalServer
+	}
+
+	ceremony := &entity.WebAuthnSession{Data: *session}
+	if err := s.repo.StoreWebAuthnSession(ctx, "login:"+token.Hash(sessionID), ceremony, passkeyCeremonyTTL); err != nil {
+		return nil, err
+	}
+
+	return &dto.PasskeyLoginBeginResponse{
+		SessionID: sessionID,
+		Options:   assertion,
+	}, nil
+}
+
+// FinishPasskeyLogin verifies the assertion and signs the credential's owner
+// in. A user-verified passkey already covers two factors, so no TOTP
+// challenge follows.
+func (s *authService) FinishPasskeyLogin(ctx context.Context, req *dto.PasskeyLoginFinishRequest, client dto.ClientInfo) (*dto.AuthResponse, error) {
+	if s.webauthn == nil {
+		return nil, errx.ErrPasskeysNotConfigured
+	}
+
+	ceremony, err := s.repo.ConsumeWebAuthnSession(ctx, "login:"+token.Hash(req.SessionID))
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, errx.NewBadRequestError("Invalid passkey credential")
+	}
+
+	var waUser *webAuthnUser
+	findUser := func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		waUser, err = s.loadWebAuthnUser(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		return waUser, nil
+	}
+
+	_, credential, err := s.webauthn.ValidatePasskeyLogin(findUser, ceremony.Data, parsed)
+	if err != nil {
+		log.Printf("[WEBAUTHN ERROR] finish login: %v", err)
+		return nil, errx.ErrPasskeyVerificationFailed
+	}
+
+	if err := s.recordPasskeyUse(ctx, waUser.user.ID, credential); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, waUser.user, client, loginMethodPasskey)
+}
+
+// BeginPasskeyMFA returns assertion options for answering a login's
+// mfa_required challenge with a passkey.
+func (s *authService) BeginPasskeyMFA(ctx context.Context, req *dto.PasskeyMFABeginRequest) (*protocol.CredentialAssertion, error) {
+	if s.webauthn == nil {
+		return nil, errx.ErrPasskeysNotConfigured
+	}
+
+	challengeID, userID, err := parseMFAToken(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	waUser, err := s.loadWebAuthnUser(ctx, userID)
+	if err != nil {
+		return nil, errx.ErrInvalidMFAToken
+	}
+	if len(waUser.creds) == 0 {
+		return nil, errx.ErrPasskeyNotFound
+	}
+
+	assertion, session, err := s.webauthn.BeginLogin(waUser)
+	if err != nil {
+		log.Printf("[WEBAUTHN ERROR] begin second factor: %v", err)
+		return nil, errx.ErrInternalServer
+	}
+
+	ceremony := &entity.WebAuthnSession{Data: *session}
+	if err := s.repo.StoreWebAuthnSession(ctx, "mfa:"+challengeID, ceremony, passkeyCeremonyTTL); err != nil {
+		return nil, err
+	}
+
+	return assertion, nil
+}
+
+// FinishPasskeyMFA completes a login challenge with a passkey assertion.
+func (s *authService) FinishPasskeyMFA(ctx context.Context, req *dto.PasskeyMFAFinishRequest, client dto.ClientInfo) (*dto.AuthResponse, error) {
+	if s.webauthn == nil {
+		return nil, errx.ErrPasskeysNotConfigured
+	}
+
+	challengeID, user, err := s.startMFAAttempt(ctx, req.MFAToken, client)
+	if err != nil {
+		return nil, err
+	}
+
+	ceremony, err := s.repo.ConsumeWebAuthnSession(ctx, "mfa:"+challengeID)
+	if err != nil {
+		return nil, err
+	}
+
+	waUser, err := s.loadWebAuthnUser(ctx, user.ID)
+	if err != nil {
+		return nil, errx.ErrInvalidMFAToken
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, errx.NewBadRequestError("Invalid passkey credential")
+	}
+
+	// Claimed before the passkey's counter is updated, as in LoginMFA
+	if err := s.claimMFAChallenge(ctx, challengeID); err != nil {
+		return nil, err
+	}
+
+	credential, err := s.webauthn.ValidateLogin(waUser, ceremony.Data, parsed)
+	if err != nil {
+		s.releaseMFAChallenge(ctx, challengeID)
+		log.Printf("[WEBAUTHN ERROR] finish second factor for %s: %v", user.ID, err)
+		if err := s.recordLoginFailure(ctx, user.Email, client, user, loginFailureMFAPasskey); err != nil {
+			return nil, err
+		}
+		return nil, errx.ErrPasskeyVerificationFailed
+	}
+
+	if err := s.recordPasskeyUse(ctx, user.ID, credential); err != nil {
+		s.releaseMFAChallenge(ctx, challengeID)
+		return nil, err
+	}
+
+	return s.startSession(ctx, waUser.user, client, loginMethodPasskeyMFA)
+}
+
+// recordPasskeyUse stores the new signature counter. A counter that did not
+// increase means the key may have been cloned, so the login is refused.
+func (s *authService) recordPasskeyUse(ctx context.Context, userID uuid.UUID, credential *webauthn.Credential) error {
+	if credential.Authenticator.CloneWarning {
+		err := s.audit.Record(ctx, auditService.Event{
+			UserID:   userID,
+			Action:   auditPasskeyCloneWarning,
+			Metadata: map[string]any{"sign_count": credential.Authenticator.SignCount},
+		})
+		if err != nil {
+			return err
+		}
+		return errx.ErrPasskeyVerificationFailed
+	}
+
+	return s.repo.UpdateWebAuthnCredentialUsage(ctx, credential.ID, credential.Authenticator.SignCount, credential.Flags.BackupState)
+}
+
+// mfaMethods lists the second factors the user can answer a challenge with.
+// An empty list means the account has no second factor.
+func (s *authService) mfaMethods(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var methods []string
+
+	totpEnabled, err := s.isTOTPEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if totpEnabled {
+		methods = append(methods, mfaMethodTOTP)
+	}
+
+	if s.webauthn == nil {
+		return methods, nil
+	}
+
+	creds, err := s.repo.ListWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(creds) > 0 {
+		methods = append(methods, mfaMethodPasskey)
+	}
+	return methods, nil
+}
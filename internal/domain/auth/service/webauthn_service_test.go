@@ -0,0 +1,540 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/auth/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/totp"
+)
+
+const (
+	testOrigin = "http://localhost:3000"
+	testRPID   = "localhost"
+)
+
+// Authenticator data flags, see WebAuthn §6.1.
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// softPasskey is a software authenticator holding one ES256 credential. It
+// answers ceremonies the way a browser and platform authenticator would.
+type softPasskey struct {
+	id         []byte
+	key        *ecdsa.PrivateKey
+	userHandle []byte
+	signCount  uint32
+}
+
+func newSoftPasskey(t *testing.T, userHandle []byte) *softPasskey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatal(err)
+	}
+	return &softPasskey{id: id, key: key, userHandle: userHandle}
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func clientData(t *testing.T, ceremony string, challenge []byte) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": b64(challenge),
+		"origin":    testOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// authenticatorData returns rpIdHash || flags || signCount || attested.
+func (p *softPasskey) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, p.signCount)
+	return append(data, attested...)
+}
+
+// create answers navigator.credentials.create() with a "none" attestation.
+func (p *softPasskey) create(t *testing.T, creation *protocol.CredentialCreation) json.RawMessage {
+	t.Helper()
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: p.key.X.FillBytes(make([]byte, 32)),
+		YCoord: p.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// AAGUID || credential ID length || credential ID || COSE key
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(p.id)))
+	attested = append(attested, p.id...)
+	attested = append(attested, publicKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": p.authenticatorData(flagUserPresent|flagUserVerified|flagAttested, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return marshalCredential(t, p.id, map[string]any{
+		"clientDataJSON":    b64(clientData(t, "webauthn.create", creation.Response.Challenge)),
+		"attestationObject": b64(attestation),
+		"transports":        []string{"internal"},
+	})
+}
+
+// get answers navigator.credentials.get(), bumping the signature counter
+// first as a genuine authenticator does.
+func (p *softPasskey) get(t *testing.T, assertion *protocol.CredentialAssertion) json.RawMessage {
+	t.Helper()
+
+	p.signCount++
+	authData := p.authenticatorData(flagUserPresent|flagUserVerified, nil)
+	clientDataJSON := clientData(t, "webauthn.get", assertion.Response.Challenge)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(slices.Clone(authData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, p.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return marshalCredential(t, p.id, map[string]any{
+		"clientDataJSON":    b64(clientDataJSON),
+		"authenticatorData": b64(authData),
+		"signature":         b64(signature),
+		"userHandle":        b64(p.userHandle),
+	})
+}
+
+// clone returns an authenticator with the same key and counter, as an
+// attacker who extracted the key would have.
+func (p *softPasskey) clone() *softPasskey {
+	cloned := *p
+	return &cloned
+}
+
+func marshalCredential(t *testing.T, id []byte, response map[string]any) json.RawMessage {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{
+		"id":       b64(id),
+		"rawId":    b64(id),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// registerPasskey runs the registration ceremony with a new software passkey.
+func registerPasskey(t *testing.T, s *authService, user *entity.User, name string) (*softPasskey, *entity.WebAuthnCredential) {
+	t.Helper()
+	ctx := context.Background()
+
+	creation, err := s.BeginPasskeyRegistration(ctx, user.ID, "", &dto.PasskeyRegisterBeginRequest{Name: name, Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("BeginPasskeyRegistration: %v", err)
+	}
+
+	passkey := newSoftPasskey(t, user.ID[:])
+	cred, err := s.FinishPasskeyRegistration(ctx, user.ID, &dto.PasskeyCredentialRequest{Credential: passkey.create(t, creation)})
+	if err != nil {
+		t.Fatalf("FinishPasskeyRegistration: %v", err)
+	}
+	return passkey, cred
+}
+
+// passkeyLogin runs the usernameless login ceremony with the given passkey.
+func passkeyLogin(t *testing.T, s *authService, passkey *softPasskey) (*dto.AuthResponse, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	begin, err := s.BeginPasskeyLogin(ctx)
+	if err != nil {
+		t.Fatalf("BeginPasskeyLogin: %v", err)
+	}
+
+	req := &dto.PasskeyLoginFinishRequest{SessionID: begin.SessionID, Credential: passkey.get(t, begin.Options)}
+	return s.FinishPasskeyLogin(ctx, req, testClient)
+}
+
+func TestPasskeyRegistration(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+
+	passkey, cred := registerPasskey(t, s, user, "Laptop")
+	if cred.Name != "Laptop" || cred.UserID != user.ID {
+		t.Errorf("credential = %+v", cred)
+	}
+	if string(cred.CredentialID) != string(passkey.id) {
+		t.Error("stored credential ID differs from the authenticator's")
+	}
+	if cred.AttestationType != "none" {
+		t.Errorf("AttestationType = %q, want none", cred.AttestationType)
+	}
+
+	passkeys, err := s.ListPasskeys(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passkeys) != 1 || passkeys[0].ID != cred.ID {
+		t.Fatalf("ListPasskeys = %+v", passkeys)
+	}
+}
+
+func TestPasskeyRegistrationIsSingleUse(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	ctx := context.Background()
+
+	creation, err := s.BeginPasskeyRegistration(ctx, user.ID, "", &dto.PasskeyRegisterBeginRequest{Password: "correct horse battery"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential := newSoftPasskey(t, user.ID[:]).create(t, creation)
+
+	if _, err := s.FinishPasskeyRegistration(ctx, user.ID, &dto.PasskeyCredentialRequest{Credential: credential}); err != nil {
+		t.Fatalf("FinishPasskeyRegistration: %v", err)
+	}
+	_, err = s.FinishPasskeyRegistration(ctx, user.ID, &dto.PasskeyCredentialRequest{Credential: credential})
+	if !errors.Is(err, errx.ErrInvalidPasskeySession) {
+		t.Fatalf("replayed registration error = %v, want %v", err, errx.ErrInvalidPasskeySession)
+	}
+}
+
+func TestPasskeyRegistrationRejectsOtherChallenge(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	ctx := context.Background()
+
+	if _, err := s.BeginPasskeyRegistration(ctx, user.ID, "", &dto.PasskeyRegisterBeginRequest{Password: "correct horse battery"}); err != nil {
+		t.Fatal(err)
+	}
+	forged := &protocol.CredentialCreation{}
+	forged.Response.Challenge = []byte("a challenge the server never issued")
+
+	credential := newSoftPasskey(t, user.ID[:]).create(t, forged)
+	_, err := s.FinishPasskeyRegistration(ctx, user.ID, &dto.PasskeyCredentialRequest{Credential: credential})
+	if !errors.Is(err, errx.ErrPasskeyVerificationFailed) {
+		t.Fatalf("FinishPasskeyRegistration error = %v, want %v", err, errx.ErrPasskeyVerificationFailed)
+	}
+	if len(repo.credentials) != 0 {
+		t.Error("credential stored from a forged ceremony")
+	}
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	passkey, cred := registerPasskey(t, s, user, "Laptop")
+
+	for i := 0; i < 2; i++ {
+		resp, err := passkeyLogin(t, s, passkey)
+		if err != nil {
+			t.Fatalf("FinishPasskeyLogin: %v", err)
+		}
+		if resp.MFARequired || resp.Token == "" {
+			t.Fatalf("expected tokens, got %+v", resp)
+		}
+		if resp.User.ID != user.ID.String() {
+			t.Fatalf("signed in %s, want %s", resp.User.ID, user.ID)
+		}
+	}
+
+	stored, _ := repo.ListWebAuthnCredentials(context.Background(), user.ID)
+	if stored[0].ID != cred.ID || stored[0].SignCount != passkey.signCount || stored[0].LastUsedAt == nil {
+		t.Errorf("credential usage not recorded: %+v", stored[0])
+	}
+}
+
+func TestPasskeyLoginRejectsClonedKey(t *testing.T) {
+	s, repo, audit := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	passkey, _ := registerPasskey(t, s, user, "Laptop")
+
+	cloned := passkey.clone()
+	if _, err := passkeyLogin(t, s, passkey); err != nil {
+		t.Fatalf("FinishPasskeyLogin: %v", err)
+	}
+
+	// The copy signs with the same counter the original already used
+	_, err := passkeyLogin(t, s, cloned)
+	if !errors.Is(err, errx.ErrPasskeyVerificationFailed) {
+		t.Fatalf("cloned key error = %v, want %v", err, errx.ErrPasskeyVerificationFailed)
+	}
+	if !slices.Contains(audit.actions(), auditPasskeyCloneWarning) {
+		t.Errorf("audit actions = %v, want %s", audit.actions(), auditPasskeyCloneWarning)
+	}
+
+	stored, _ := repo.ListWebAuthnCredentials(context.Background(), user.ID)
+	if stored[0].SignCount != passkey.signCount {
+		t.Errorf("SignCount = %d, want %d from the genuine key", stored[0].SignCount, passkey.signCount)
+	}
+}
+
+func TestPasskeyAsSecondFactor(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	passkey, _ := registerPasskey(t, s, user, "Laptop")
+	ctx := context.Background()
+
+	challenge, err := s.Login(ctx, &dto.LoginRequest{Email: user.Email, Password: "correct horse battery"}, testClient)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !challenge.MFARequired {
+		t.Fatalf("expected an MFA challenge, got %+v", challenge)
+	}
+
+	assertion, err := s.BeginPasskeyMFA(ctx, &dto.PasskeyMFABeginRequest{MFAToken: challenge.MFAToken})
+	if err != nil {
+		t.Fatalf("BeginPasskeyMFA: %v", err)
+	}
+	req := &dto.PasskeyMFAFinishRequest{MFAToken: challenge.MFAToken, Credential: passkey.get(t, assertion)}
+	resp, err := s.FinishPasskeyMFA(ctx, req, testClient)
+	if err != nil {
+		t.Fatalf("FinishPasskeyMFA: %v", err)
+	}
+	if resp.Token == "" || resp.User.ID != user.ID.String() {
+		t.Fatalf("expected tokens for %s, got %+v", user.ID, resp)
+	}
+}
+
+func TestWrongPasskeyCountsTowardLockout(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	registerPasskey(t, s, user, "Laptop")
+	ctx := context.Background()
+
+	challenge, err := s.Login(ctx, &dto.LoginRequest{Email: user.Email, Password: "correct horse battery"}, testClient)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	assertion, err := s.BeginPasskeyMFA(ctx, &dto.PasskeyMFABeginRequest{MFAToken: challenge.MFAToken})
+	if err != nil {
+		t.Fatalf("BeginPasskeyMFA: %v", err)
+	}
+
+	// A key that was never registered for the account
+	stranger := newSoftPasskey(t, user.ID[:])
+	req := &dto.PasskeyMFAFinishRequest{MFAToken: challenge.MFAToken, Credential: stranger.get(t, assertion)}
+	if _, err := s.FinishPasskeyMFA(ctx, req, testClient); !errors.Is(err, errx.ErrPasskeyVerificationFailed) {
+		t.Fatalf("FinishPasskeyMFA error = %v, want %v", err, errx.ErrPasskeyVerificationFailed)
+	}
+	if got := repo.loginFailures(emailSubject(user.Email)); got != 1 {
+		t.Fatalf("%d failures counted, want 1", got)
+	}
+}
+
+func TestMultiplePasskeys(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	other := newTestUser(t, s, repo, "grace@example.com", "correct horse battery")
+
+	laptop, _ := registerPasskey(t, s, user, "Laptop")
+	phone, phoneCred := registerPasskey(t, s, user, "")
+	if phoneCred.Name != "Passkey 2" {
+		t.Errorf("default name = %q, want Passkey 2", phoneCred.Name)
+	}
+	otherKey, _ := registerPasskey(t, s, other, "Laptop")
+
+	for _, tt := range []struct {
+		name    string
+		passkey *softPasskey
+		want    uuid.UUID
+	}{
+		{name: "first passkey", passkey: laptop, want: user.ID},
+		{name: "second passkey", passkey: phone, want: user.ID},
+		{name: "another user's passkey", passkey: otherKey, want: other.ID},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := passkeyLogin(t, s, tt.passkey)
+			if err != nil {
+				t.Fatalf("FinishPasskeyLogin: %v", err)
+			}
+			if resp.User.ID != tt.want.String() {
+				t.Errorf("signed in %s, want %s", resp.User.ID, tt.want)
+			}
+		})
+	}
+
+	passkeys, err := s.ListPasskeys(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passkeys) != 2 {
+		t.Fatalf("ListPasskeys returned %d passkeys, want 2", len(passkeys))
+	}
+}
+
+func TestPasskeyLimit(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+
+	for i := 0; i < maxPasskeysPerUser; i++ {
+		registerPasskey(t, s, user, "")
+	}
+
+	_, err := s.BeginPasskeyRegistration(context.Background(), user.ID, "", &dto.PasskeyRegisterBeginRequest{Password: "correct horse battery"})
+	if err == nil {
+		t.Fatalf("registered more than %d passkeys", maxPasskeysPerUser)
+	}
+}
+
+func TestDeletePasskey(t *testing.T) {
+	s, repo, _ := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+	other := newTestUser(t, s, repo, "grace@example.com", "correct horse battery")
+	ctx := context.Background()
+
+	laptop, laptopCred := registerPasskey(t, s, user, "Laptop")
+	phone, _ := registerPasskey(t, s, user, "Phone")
+
+	if err := s.DeletePasskey(ctx, other.ID, "", laptopCred.ID, &dto.PasskeyDeleteRequest{Password: "correct horse battery"}); !errors.Is(err, errx.ErrPasskeyNotFound) {
+		t.Fatalf("deleting another user's passkey: error = %v, want %v", err, errx.ErrPasskeyNotFound)
+	}
+	if err := s.DeletePasskey(ctx, user.ID, "", laptopCred.ID, &dto.PasskeyDeleteRequest{Password: "correct horse battery"}); err != nil {
+		t.Fatalf("DeletePasskey: %v", err)
+	}
+
+	passkeys, _ := s.ListPasskeys(ctx, user.ID)
+	if len(passkeys) != 1 || passkeys[0].Name != "Phone" {
+		t.Fatalf("ListPasskeys = %+v", passkeys)
+	}
+
+	if _, err := passkeyLogin(t, s, laptop); !errors.Is(err, errx.ErrPasskeyVerificationFailed) {
+		t.Fatalf("removed passkey error = %v, want %v", err, errx.ErrPasskeyVerificationFailed)
+	}
+	if _, err := passkeyLogin(t, s, phone); err != nil {
+		t.Fatalf("remaining passkey: %v", err)
+	}
+}
+
+func TestPasskeyChangesRequireReauthentication(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string // the account's password, none when empty
+		totp       bool
+		sessionAge time.Duration // of the session the requests come from
+		reqPass    string
+		withCode   bool
+		wantErr    error
+	}{
+		{name: "password", password: "correct horse battery", reqPass: "correct horse battery"},
+		{name: "wrong password", password: "correct horse battery", reqPass: "wrong password", wantErr: errx.ErrIncorrectPassword},
+		{name: "no password given", password: "correct horse battery", wantErr: errx.ErrIncorrectPassword},
+		{name: "password and code", password: "correct horse battery", totp: true, reqPass: "correct horse battery", withCode: true},
+		{name: "code missing", password: "correct horse battery", totp: true, reqPass: "correct horse battery", wantErr: errx.ErrInvalidMFACode},
+		// Without a password or 2FA a fresh login is the only proof
+		{name: "passwordless, fresh login", sessionAge: time.Minute},
+		{name: "passwordless, old login", sessionAge: time.Hour, wantErr: errx.ErrRecentLoginRequired},
+		{name: "passwordless with code", totp: true, sessionAge: time.Hour, withCode: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo, audit := newTestService(t, testConfig())
+			user := newTestUser(t, s, repo, "ada@example.com", tt.password)
+			if tt.totp {
+				enableTestTOTP(repo, user.ID, "aaaaa-bbbbb")
+			}
+			session := &entity.Session{ID: uuid.NewString(), UserID: user.ID, CreatedAt: time.Now().Add(-tt.sessionAge)}
+			repo.sessions = append(repo.sessions, session)
+			ctx := context.Background()
+
+			// A TOTP code cannot be used twice, so registration gets the
+			// current code and deletion a recovery code
+			var registerCode, deleteCode string
+			if tt.withCode {
+				code, err := totp.Code(testTOTPSecret, totp.Counter(time.Now()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				registerCode, deleteCode = code, "aaaaa-bbbbb"
+			}
+
+			req := &dto.PasskeyRegisterBeginRequest{Password: tt.reqPass, Code: registerCode}
+			_, err := s.BeginPasskeyRegistration(ctx, user.ID, session.ID, req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BeginPasskeyRegistration error = %v, want %v", err, tt.wantErr)
+			}
+
+			stored := &entity.WebAuthnCredential{ID: uuid.New(), UserID: user.ID, CredentialID: []byte("credential"), CreatedAt: time.Now()}
+			repo.credentials = append(repo.credentials, stored)
+
+			err = s.DeletePasskey(ctx, user.ID, session.ID, stored.ID, &dto.PasskeyDeleteRequest{Password: tt.reqPass, Code: deleteCode})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeletePasskey error = %v, want %v", err, tt.wantErr)
+			}
+
+			deleted := !slices.ContainsFunc(repo.credentials, func(c *entity.WebAuthnCredential) bool { return c.ID == stored.ID })
+			if deleted != (tt.wantErr == nil) {
+				t.Errorf("passkey deleted = %v, want %v", deleted, tt.wantErr == nil)
+			}
+			if audited := slices.Contains(audit.actions(), auditPasskeyDeleted); audited != deleted {
+				t.Errorf("audit actions = %v", audit.actions())
+			}
+		})
+	}
+}
+
+func TestPasskeyRegistrationIsAudited(t *testing.T) {
+	s, repo, audit := newTestService(t, testConfig())
+	user := newTestUser(t, s, repo, "ada@example.com", "correct horse battery")
+
+	_, cred := registerPasskey(t, s, user, "Laptop")
+
+	for _, event := range audit.events {
+		if event.Action == auditPasskeyRegistered {
+			if event.UserID != user.ID || event.TargetID != cred.ID.String() {
+				t.Errorf("audit event = %+v", event)
+			}
+			return
+		}
+	}
+	t.Fatalf("audit actions = %v, want %s", audit.actions(), auditPasskeyRegistered)
+}
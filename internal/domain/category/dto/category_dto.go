@@ -5,3 +5,6 @@ type GetAllCategoryResponse struct {
 	Name string `json:"name"`
 }
 
+type CategoryRequest struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
@@ -3,8 +3,11 @@ package http
 import (
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/kenziehh/cashflow-be/internal/domain/category/dto"
 	"github.com/kenziehh/cashflow-be/internal/domain/category/service"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
 	"github.com/kenziehh/cashflow-be/pkg/response"
+	"github.com/oklog/ulid/v2"
 )
 
 type CategoryHandler struct {
@@ -41,3 +44,99 @@ func (h *CategoryHandler) GetAllCategories(c *fiber.Ctx) error {
 
 	return c.JSON(response.SuccessResponse("Categories retrieved successfully", categories))
 }
+
+// CreateCategory godoc
+// @Summary      Create a category
+// @Description  Create a global category (admin only)
+// @Security     BearerAuth
+// @Tags         Categories
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.CategoryRequest true "Category request"
+// @Success      201  {object}  response.Response{data=entity.Category}
+// @Failure      400  {object}  response.Response
+// @Failure      403  {object}  response.Response
+// @Failure      500  {object}  response.Response
+// @Router       /categories [post]
+func (h *CategoryHandler) CreateCategory(c *fiber.Ctx) error {
+	var req dto.CategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	category, err := h.service.CreateCategory(c.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response.SuccessResponse("Category created successfully", category))
+}
+
+// UpdateCategory godoc
+// @Summary      Rename a category
+// @Description  Rename a global category (admin only)
+// @Security     BearerAuth
+// @Tags         Categories
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Category ID"
+// @Param        request body dto.CategoryRequest true "Category request"
+// @Success      200  {object}  response.Response{data=entity.Category}
+// @Failure      400  {object}  response.Response
+// @Failure      403  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Failure      500  {object}  response.Response
+// @Router       /categories/{id} [put]
+func (h *CategoryHandler) UpdateCategory(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, err := ulid.ParseStrict(id); err != nil {
+		return errx.NewBadRequestError("Invalid category ID format")
+	}
+
+	var req dto.CategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	category, err := h.service.UpdateCategory(c.Context(), id, req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Category updated successfully", category))
+}
+
+// DeleteCategory godoc
+// @Summary      Delete a category
+// @Description  Delete a global category (admin only). Transactions in it become uncategorized.
+// @Security     BearerAuth
+// @Tags         Categories
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Category ID"
+// @Success      200  {object}  response.Response
+// @Failure      400  {object}  response.Response
+// @Failure      403  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Failure      500  {object}  response.Response
+// @Router       /categories/{id} [delete]
+func (h *CategoryHandler) DeleteCategory(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, err := ulid.ParseStrict(id); err != nil {
+		return errx.NewBadRequestError("Invalid category ID format")
+	}
+
+	if err := h.service.DeleteCategory(c.Context(), id); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Category deleted successfully", nil))
+}
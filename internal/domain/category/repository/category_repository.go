@@ -6,11 +6,15 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	"github.com/kenziehh/cashflow-be/internal/domain/category/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/category/entity"
 	"github.com/kenziehh/cashflow-be/pkg/errx"
 )
 
 type CategoryRepository interface {
 	GetAllCategories(ctx context.Context) ([]dto.GetAllCategoryResponse, error)
+	CreateCategory(ctx context.Context, category *entity.Category) error
+	UpdateCategory(ctx context.Context, category *entity.Category) error
+	DeleteCategory(ctx context.Context, id string) error
 }
 
 type categoryRepository struct {
@@ -53,3 +57,58 @@ func (r *categoryRepository) GetAllCategories(ctx context.Context) ([]dto.GetAll
 
 	return categories, nil
 }
+
+func (r *categoryRepository) CreateCategory(ctx context.Context, category *entity.Category) error {
+	query := `
+	INSERT INTO categories (id, name)
+	VALUES ($1, $2)
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, category.ID, category.Name); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *categoryRepository) UpdateCategory(ctx context.Context, category *entity.Category) error {
+	query := `
+	UPDATE categories
+	SET name = $1
+	WHERE id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, category.Name, category.ID)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return requireAffected(result)
+}
+
+// DeleteCategory removes a category. Transactions that used it keep existing
+// with category_id set to NULL.
+func (r *categoryRepository) DeleteCategory(ctx context.Context, id string) error {
+	query := `
+	DELETE FROM categories
+	WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return requireAffected(result)
+}
+
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	if affected == 0 {
+		return errx.ErrCategoryNotFound
+	}
+	return nil
+}
@@ -7,10 +7,15 @@ import (
 
 	"github.com/kenziehh/cashflow-be/internal/domain/category/repository"
 	"github.com/kenziehh/cashflow-be/internal/domain/category/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/category/entity"
+	"github.com/oklog/ulid/v2"
 )
 
 type CategoryService interface {
 	GetAllCategories(ctx context.Context) ([]dto.GetAllCategoryResponse, error)
+	CreateCategory(ctx context.Context, req dto.CategoryRequest) (*entity.Category, error)
+	UpdateCategory(ctx context.Context, id string, req dto.CategoryRequest) (*entity.Category, error)
+	DeleteCategory(ctx context.Context, id string) error
 }
 
 type categoryService struct {
@@ -28,4 +33,32 @@ func (s *categoryService) GetAllCategories(ctx context.Context) ([]dto.GetAllCat
 		return nil, err
 	}
 	return categories, nil
-}
\ No newline at end of file
+}
+
+func (s *categoryService) CreateCategory(ctx context.Context, req dto.CategoryRequest) (*entity.Category, error) {
+	category := &entity.Category{
+		ID:   ulid.Make().String(),
+		Name: req.Name,
+	}
+
+	if err := s.repo.CreateCategory(ctx, category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *categoryService) UpdateCategory(ctx context.Context, id string, req dto.CategoryRequest) (*entity.Category, error) {
+	category := &entity.Category{
+		ID:   id,
+		Name: req.Name,
+	}
+
+	if err := s.repo.UpdateCategory(ctx, category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *categoryService) DeleteCategory(ctx context.Context, id string) error {
+	return s.repo.DeleteCategory(ctx, id)
+}
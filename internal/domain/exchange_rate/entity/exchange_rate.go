@@ -0,0 +1,11 @@
+package entity
+
+// ExchangeRate says one unit of Base was worth Rate units of Quote on Date
+// (YYYY-MM-DD). Rate is kept as decimal text so it is stored exactly.
+type ExchangeRate struct {
+	Base   string `json:"base_currency"`
+	Quote  string `json:"quote_currency"`
+	Date   string `json:"rate_date"`
+	Rate   string `json:"rate"`
+	Source string `json:"source"`
+}
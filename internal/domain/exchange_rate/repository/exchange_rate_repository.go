@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/kenziehh/cashflow-be/internal/domain/exchange_rate/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+)
+
+type ExchangeRateRepository interface {
+	UpsertRates(ctx context.Context, rates []entity.ExchangeRate) error
+}
+
+type exchangeRateRepository struct {
+	db    *sql.DB
+	redis *redis.Client
+}
+
+func NewExchangeRateRepository(db *sql.DB, redis *redis.Client) ExchangeRateRepository {
+	return &exchangeRateRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// UpsertRates stores all rates or none; a rate already known for the same
+// pair and day is replaced.
+func (r *exchangeRateRepository) UpsertRates(ctx context.Context, rates []entity.ExchangeRate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO exchange_rates (base_currency, quote_currency, rate_date, rate, source)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (quote_currency, base_currency, rate_date) DO UPDATE
+		SET rate = EXCLUDED.rate,
+			source = EXCLUDED.source,
+			created_at = CURRENT_TIMESTAMP
+	`)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	defer stmt.Close()
+
+	for _, rate := range rates {
+		if _, err := stmt.ExecContext(ctx, rate.Base, rate.Quote, rate.Date, rate.Rate, rate.Source); err != nil {
+			log.Printf("[DB ERROR] Upsert exchange rate %s/%s %s failed: %v\n", rate.Base, rate.Quote, rate.Date, err)
+			return errx.ErrDatabaseError
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/kenziehh/cashflow-be/internal/domain/exchange_rate/entity"
+	"github.com/kenziehh/cashflow-be/internal/domain/exchange_rate/repository"
+)
+
+// Import file formats
+const (
+	FormatCSV    = "csv"
+	FormatECBXML = "ecb-xml"
+)
+
+type ExchangeRateService interface {
+	Import(ctx context.Context, r io.Reader, format, source string) (int, error)
+}
+
+type exchangeRateService struct {
+	repo repository.ExchangeRateRepository
+}
+
+func NewExchangeRateService(repo repository.ExchangeRateRepository) ExchangeRateService {
+	return &exchangeRateService{
+		repo: repo,
+	}
+}
+
+// Import reads every rate in r and stores them in one go, so a malformed file
+// changes nothing. It returns the number of rates stored.
+func (s *exchangeRateService) Import(ctx context.Context, r io.Reader, format, source string) (int, error) {
+	var rates []entity.ExchangeRate
+	var err error
+
+	switch format {
+	case FormatCSV:
+		rates, err = ParseCSV(r)
+	case FormatECBXML:
+		rates, err = ParseECBXML(r)
+	default:
+		return 0, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range rates {
+		rates[i].Source = source
+	}
+
+	if err := s.repo.UpsertRates(ctx, rates); err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/kenziehh/cashflow-be/internal/domain/exchange_rate/entity"
+)
+
+// ecbBase is the currency every ECB reference rate is quoted against
+const ecbBase = "EUR"
+
+// ErrNoRates is returned for a file that parses but holds no rate, which is
+// almost certainly the wrong file.
+var ErrNoRates = errors.New("no rates found")
+
+// ParseCSV reads rates in one of two layouts, told apart by the header:
+//
+//	date,base,quote,rate        one rate per row
+//	Date,USD,JPY,...            the ECB download: one day per row, against EUR
+//
+// Empty and "N/A" cells in the ECB layout are skipped. A file without any rate
+// fails with ErrNoRates.
+func ParseCSV(r io.Reader) ([]entity.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	_, hasBase := columns["base"]
+	required := []string{"date"}
+	if hasBase {
+		required = append(required, "quote", "rate")
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("header has no %s column", name)
+		}
+	}
+
+	var rates []entity.ExchangeRate
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		date := field(record, columns["date"])
+		if hasBase {
+			rate, err := newRate(field(record, columns["base"]), field(record, columns["quote"]), date, field(record, columns["rate"]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			rates = append(rates, rate)
+			continue
+		}
+
+		for i, name := range header {
+			value := field(record, i)
+			if i == columns["date"] || value == "" || value == "N/A" || strings.TrimSpace(name) == "" {
+				continue
+			}
+			rate, err := newRate(ecbBase, strings.TrimSpace(name), date, value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			rates = append(rates, rate)
+		}
+	}
+
+	if len(rates) == 0 {
+		return nil, ErrNoRates
+	}
+	return rates, nil
+}
+
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECBXML reads the ECB euro foreign exchange reference rates feed
+// (eurofxref-daily.xml, eurofxref-hist.xml).
+func ParseECBXML(r io.Reader) ([]entity.ExchangeRate, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("decode xml: %w", err)
+	}
+
+	var rates []entity.ExchangeRate
+	for _, day := range envelope.Days {
+		for _, cube := range day.Rates {
+			rate, err := newRate(ecbBase, cube.Currency, day.Time, cube.Rate)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", day.Time, err)
+			}
+			rates = append(rates, rate)
+		}
+	}
+
+	if len(rates) == 0 {
+		return nil, ErrNoRates
+	}
+	return rates, nil
+}
+
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func newRate(base, quote, date, rate string) (entity.ExchangeRate, error) {
+	base = strings.ToUpper(strings.TrimSpace(base))
+	quote = strings.ToUpper(strings.TrimSpace(quote))
+
+	if !isCurrencyCode(base) || !isCurrencyCode(quote) {
+		return entity.ExchangeRate{}, fmt.Errorf("invalid currency pair %q/%q", base, quote)
+	}
+	if base == quote {
+		return entity.ExchangeRate{}, fmt.Errorf("currency pair %s/%s is the same currency", base, quote)
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return entity.ExchangeRate{}, fmt.Errorf("invalid date %q", date)
+	}
+	if value, ok := new(big.Rat).SetString(rate); !ok || !isDecimal(rate) || value.Sign() <= 0 {
+		return entity.ExchangeRate{}, fmt.Errorf("invalid rate %q for %s/%s", rate, base, quote)
+	}
+
+	return entity.ExchangeRate{Base: base, Quote: quote, Date: date, Rate: rate}, nil
+}
+
+// isDecimal rejects the fraction and exponent forms big.Rat also accepts,
+// which NUMERIC columns do not.
+func isDecimal(s string) bool {
+	whole, frac, _ := strings.Cut(s, ".")
+	return whole+frac != "" && strings.Trim(whole+frac, "0123456789") == ""
+}
+
+// isCurrencyCode accepts any ISO 4217 shaped code: historical files contain
+// codes that are no longer current.
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
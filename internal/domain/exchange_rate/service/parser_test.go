@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kenziehh/cashflow-be/internal/domain/exchange_rate/entity"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []entity.ExchangeRate
+		wantErr string
+	}{
+		{
+			name: "one rate per row",
+			in:   "date,base,quote,rate\n2024-03-01,usd,idr,15725.5\n2024-03-01, EUR , USD ,1.0842\n",
+			want: []entity.ExchangeRate{
+				{Base: "USD", Quote: "IDR", Date: "2024-03-01", Rate: "15725.5"},
+				{Base: "EUR", Quote: "USD", Date: "2024-03-01", Rate: "1.0842"},
+			},
+		},
+		{
+			name: "columns in any order",
+			in:   "Rate,Quote,Date,Base\n0.92,EUR,2024-03-01,USD\n",
+			want: []entity.ExchangeRate{
+				{Base: "USD", Quote: "EUR", Date: "2024-03-01", Rate: "0.92"},
+			},
+		},
+		{
+			name: "ECB layout",
+			in:   "Date,USD,JPY,\n2024-03-01,1.0842,162.33,\n2024-02-29,1.0813,162.38,\n",
+			want: []entity.ExchangeRate{
+				{Base: "EUR", Quote: "USD", Date: "2024-03-01", Rate: "1.0842"},
+				{Base: "EUR", Quote: "JPY", Date: "2024-03-01", Rate: "162.33"},
+				{Base: "EUR", Quote: "USD", Date: "2024-02-29", Rate: "1.0813"},
+				{Base: "EUR", Quote: "JPY", Date: "2024-02-29", Rate: "162.38"},
+			},
+		},
+		{
+			name: "ECB layout skips N/A and empty cells",
+			in:   "Date,USD,CYP,SGD\n2024-03-01,1.0842,N/A,\n",
+			want: []entity.ExchangeRate{
+				{Base: "EUR", Quote: "USD", Date: "2024-03-01", Rate: "1.0842"},
+			},
+		},
+		{name: "header only", in: "date,base,quote,rate\n", wantErr: ErrNoRates.Error()},
+		{name: "ECB header only", in: "Date,USD,JPY\n", wantErr: ErrNoRates.Error()},
+		{name: "ECB rows without rates", in: "Date,USD,JPY\n2024-03-01,N/A,\n", wantErr: ErrNoRates.Error()},
+		{name: "empty file", in: "", wantErr: "read header"},
+		{name: "no date column", in: "day,USD\n2024-03-01,1.08\n", wantErr: "header has no date column"},
+		{name: "no rate column", in: "date,base,quote\n2024-03-01,USD,IDR\n", wantErr: "header has no rate column"},
+		{name: "bad currency", in: "date,base,quote,rate\n2024-03-01,USD,RUPIAH,15725\n", wantErr: "line 2: invalid currency pair"},
+		{name: "same currency", in: "date,base,quote,rate\n2024-03-01,USD,usd,1\n", wantErr: "line 2: currency pair USD/USD"},
+		{name: "bad date", in: "date,base,quote,rate\n01/03/2024,USD,IDR,15725\n", wantErr: `line 2: invalid date "01/03/2024"`},
+		{name: "zero rate", in: "date,base,quote,rate\n2024-03-01,USD,IDR,0\n", wantErr: "line 2: invalid rate"},
+		{name: "negative rate", in: "date,base,quote,rate\n2024-03-01,USD,IDR,-1\n", wantErr: "line 2: invalid rate"},
+		{name: "exponent rate", in: "date,base,quote,rate\n2024-03-01,USD,IDR,1e4\n", wantErr: "line 2: invalid rate"},
+		{name: "fraction rate", in: "date,base,quote,rate\n2024-03-01,USD,IDR,1/3\n", wantErr: "line 2: invalid rate"},
+		{name: "bad row after good ones", in: "Date,USD\n2024-03-01,1.08\n2024-02-29,abc\n", wantErr: "line 3: invalid rate"},
+		{name: "broken quoting", in: "date,base,quote,rate\n2024-03-01,\"USD,IDR,1\n", wantErr: "line 2:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSV(strings.NewReader(tt.in))
+			checkParseResult(t, got, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestParseECBXML(t *testing.T) {
+	const header = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>`
+	const footer = `
+	</Cube>
+</gesmes:Envelope>`
+
+	tests := []struct {
+		name    string
+		cubes   string
+		want    []entity.ExchangeRate
+		wantErr string
+	}{
+		{
+			name: "several days",
+			cubes: `
+		<Cube time="2024-03-01"><Cube currency="USD" rate="1.0842"/><Cube currency="JPY" rate="162.33"/></Cube>
+		<Cube time="2024-02-29"><Cube currency="USD" rate="1.0813"/></Cube>`,
+			want: []entity.ExchangeRate{
+				{Base: "EUR", Quote: "USD", Date: "2024-03-01", Rate: "1.0842"},
+				{Base: "EUR", Quote: "JPY", Date: "2024-03-01", Rate: "162.33"},
+				{Base: "EUR", Quote: "USD", Date: "2024-02-29", Rate: "1.0813"},
+			},
+		},
+		{name: "no days", cubes: "", wantErr: ErrNoRates.Error()},
+		{name: "days without rates", cubes: `<Cube time="2024-03-01"></Cube>`, wantErr: ErrNoRates.Error()},
+		{name: "bad rate", cubes: `<Cube time="2024-03-01"><Cube currency="USD" rate="N/A"/></Cube>`, wantErr: `2024-03-01: invalid rate "N/A"`},
+		{name: "bad date", cubes: `<Cube time="1 March"><Cube currency="USD" rate="1.08"/></Cube>`, wantErr: `1 March: invalid date`},
+		{name: "euro against itself", cubes: `<Cube time="2024-03-01"><Cube currency="EUR" rate="1"/></Cube>`, wantErr: "currency pair EUR/EUR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseECBXML(strings.NewReader(header + tt.cubes + footer))
+			checkParseResult(t, got, err, tt.want, tt.wantErr)
+		})
+	}
+
+	if _, err := ParseECBXML(strings.NewReader("<Envelope><Cube>")); err == nil || errors.Is(err, ErrNoRates) {
+		t.Errorf("truncated XML error = %v, want a decode error", err)
+	}
+}
+
+func checkParseResult(t *testing.T, got []entity.ExchangeRate, err error, want []entity.ExchangeRate, wantErr string) {
+	t.Helper()
+
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("error = %v, want one containing %q", err, wantErr)
+		}
+		if got != nil {
+			t.Errorf("rates = %v on error, want none", got)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rates = %v, want %v", got, want)
+	}
+}
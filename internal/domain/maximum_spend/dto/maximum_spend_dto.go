@@ -1,18 +1,28 @@
 package dto
 
+import "github.com/kenziehh/cashflow-be/pkg/money"
+
 type MaximumSpendRequest struct {
-	ID           string  `json:"id,omitempty"`
-	DailyLimit   float64 `json:"daily_limit" validate:"gte=0"`
-	MonthlyLimit float64 `json:"monthly_limit" validate:"gte=0"`
-	YearlyLimit  float64 `json:"yearly_limit" validate:"gte=0"`
+	ID           string       `json:"id,omitempty"`
+	DailyLimit   money.Amount `json:"daily_limit" validate:"gte=0" swaggertype:"number" example:"1000000"`
+	MonthlyLimit money.Amount `json:"monthly_limit" validate:"gte=0" swaggertype:"number" example:"1000000"`
+	YearlyLimit  money.Amount `json:"yearly_limit" validate:"gte=0" swaggertype:"number" example:"1000000"`
 }
 
+// MaximumSpendResponse limits are in the user's base currency. The spent
+// fields are only filled in when reading the limits.
 type MaximumSpendResponse struct {
-	ID           string  `json:"id"`
-	UserID       string  `json:"user_id"`
-	DailyLimit   float64 `json:"daily_limit"`
-	MonthlyLimit float64 `json:"monthly_limit"`
-	YearlyLimit  float64 `json:"yearly_limit"`
-	CreatedAt    string  `json:"created_at"`
-	UpdatedAt    string  `json:"updated_at"`
+	ID                    string       `json:"id"`
+	UserID                string       `json:"user_id"`
+	DailyLimit            money.Amount `json:"daily_limit" swaggertype:"number"`
+	MonthlyLimit          money.Amount `json:"monthly_limit" swaggertype:"number"`
+	YearlyLimit           money.Amount `json:"yearly_limit" swaggertype:"number"`
+	Currency              string       `json:"currency,omitempty" example:"IDR"`
+	SpentDaily            money.Amount `json:"spent_daily" swaggertype:"number"`
+	SpentMonthly          money.Amount `json:"spent_monthly" swaggertype:"number"`
+	SpentYearly           money.Amount `json:"spent_yearly" swaggertype:"number"`
+	ExceededPeriods       []string     `json:"exceeded_periods,omitempty" example:"daily"`
+	UnconvertedCurrencies []string     `json:"unconverted_currencies,omitempty" example:"SGD"`
+	CreatedAt             string       `json:"created_at"`
+	UpdatedAt             string       `json:"updated_at"`
 }
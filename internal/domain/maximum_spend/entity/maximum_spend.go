@@ -4,14 +4,43 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/pkg/money"
 )
 
 type MaximumSpend struct {
-	ID           string    `json:"id" db:"id"`
-	UserID       uuid.UUID `json:"user_id" db:"user_id"`
-	DailyLimit   float64   `json:"daily_limit" db:"daily_limit"`
-	MonthlyLimit float64   `json:"monthly_limit" db:"monthly_limit"`
-	YearlyLimit  float64   `json:"yearly_limit" db:"yearly_limit"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID           string       `json:"id" db:"id"`
+	UserID       uuid.UUID    `json:"user_id" db:"user_id"`
+	DailyLimit   money.Amount `json:"daily_limit" db:"daily_limit" swaggertype:"number"`
+	MonthlyLimit money.Amount `json:"monthly_limit" db:"monthly_limit" swaggertype:"number"`
+	YearlyLimit  money.Amount `json:"yearly_limit" db:"yearly_limit" swaggertype:"number"`
+	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// Spending is what the user spent in the periods containing today, in their
+// base currency. Limits are compared against it as is, so they are in the
+// base currency too.
+type Spending struct {
+	Currency string
+	Daily    money.Amount
+	Monthly  money.Amount
+	Yearly   money.Amount
+	// UnconvertedCurrencies had no known exchange rate and are not counted
+	UnconvertedCurrencies []string
+}
+
+// ExceededPeriods lists the periods whose limit spent is over. A zero limit
+// means no limit.
+func (ms *MaximumSpend) ExceededPeriods(spent *Spending) []string {
+	exceeded := []string{}
+	if ms.DailyLimit > 0 && spent.Daily > ms.DailyLimit {
+		exceeded = append(exceeded, "daily")
+	}
+	if ms.MonthlyLimit > 0 && spent.Monthly > ms.MonthlyLimit {
+		exceeded = append(exceeded, "monthly")
+	}
+	if ms.YearlyLimit > 0 && spent.Yearly > ms.YearlyLimit {
+		exceeded = append(exceeded, "yearly")
+	}
+	return exceeded
 }
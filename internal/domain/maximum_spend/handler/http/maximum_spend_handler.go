@@ -44,20 +44,13 @@ func (h *MaximumSpendHandler) SetMaximumSpend(c *fiber.Ctx) error {
 		})
 	}
 
-	userIDStr := c.Locals("user_id")
-	if userIDStr == nil {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Unauthorized",
 		})
 	}
 
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
-	}
-
 	ms, err := h.service.SetMaximumSpend(c.Context(), userID, req.DailyLimit, req.MonthlyLimit, req.YearlyLimit)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -78,7 +71,7 @@ func (h *MaximumSpendHandler) SetMaximumSpend(c *fiber.Ctx) error {
 
 // Get Maximum Spend godoc
 // @Summary Get maximum spend limits
-// @Description Retrieve the daily, monthly, and yearly maximum spend limits for the authenticated user
+// @Description Retrieve the daily, monthly, and yearly maximum spend limits for the authenticated user, with what was spent in each period converted into the user's base currency
 // @Tags maximum-spends
 // @Accept json
 // @Produce json
@@ -86,33 +79,39 @@ func (h *MaximumSpendHandler) SetMaximumSpend(c *fiber.Ctx) error {
 
 // @Router /maximum-spends [get]
 func (h *MaximumSpendHandler) GetMaximumSpend(c *fiber.Ctx) error {
-	userIDStr := c.Locals("user_id")
-	if userIDStr == nil {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Unauthorized",
 		})
 	}
 
-	userID, err := uuid.Parse(userIDStr.(string))
+	ms, err := h.service.GetMaximumSpend(c.Context(), userID)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
-	ms, err := h.service.GetMaximumSpend(c.Context(), userID)
+	spent, err := h.service.GetSpending(c.Context(), userID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
 	resp := dto.MaximumSpendResponse{
-		ID:           ms.ID,
-		UserID:       ms.UserID.String(),
-		DailyLimit:   ms.DailyLimit,
-		MonthlyLimit: ms.MonthlyLimit,
-		YearlyLimit:  ms.YearlyLimit,
+		ID:                    ms.ID,
+		UserID:                ms.UserID.String(),
+		DailyLimit:            ms.DailyLimit,
+		MonthlyLimit:          ms.MonthlyLimit,
+		YearlyLimit:           ms.YearlyLimit,
+		Currency:              spent.Currency,
+		SpentDaily:            spent.Daily,
+		SpentMonthly:          spent.Monthly,
+		SpentYearly:           spent.Yearly,
+		ExceededPeriods:       ms.ExceededPeriods(spent),
+		UnconvertedCurrencies: spent.UnconvertedCurrencies,
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
@@ -8,10 +8,11 @@ import (
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	"github.com/kenziehh/cashflow-be/internal/domain/maximum_spend/entity"
+	"github.com/lib/pq"
 )
 
 type MaximumSpendRepository interface {
-	CheckAlert(ctx context.Context, tx *entity.MaximumSpend, period string) error
+	GetSpending(ctx context.Context, userID uuid.UUID) (*entity.Spending, error)
 	UpsertMaximumSpend(ctx context.Context, ms *entity.MaximumSpend) error
 	GetMaximumSpendByUserID(ctx context.Context, userID uuid.UUID) (*entity.MaximumSpend, error)
 }
@@ -28,32 +29,54 @@ func NewMaximumSpendRepository(db *sql.DB, redis *redis.Client) MaximumSpendRepo
 	}
 }
 
-func (r *maximumSpendRepository) CheckAlert(ctx context.Context, ms *entity.MaximumSpend, period string) error {
+// GetSpending totals the user's expenses for the day, month and year
+// containing today in their timezone, converted into their base currency at
+// the rate of each transaction's date. Transfers are not spending.
+func (r *maximumSpendRepository) GetSpending(ctx context.Context, userID uuid.UUID) (*entity.Spending, error) {
 	query := `
-		SELECT id, user_id, amount, period
-		FROM maximum_spends
-		WHERE user_id = $1 AND period = $2
+	WITH pref AS (
+		SELECT currency, (now() AT TIME ZONE timezone)::date AS today
+		FROM users
+		WHERE id = $1
+	), tx AS (
+		SELECT
+			p.currency AS base_currency,
+			p.today,
+			t.date,
+			t.currency,
+			ROUND(t.amount * exchange_rate(t.currency, p.currency, t.date), 2) AS amount
+		FROM pref p
+		LEFT JOIN transactions t
+			ON t.user_id = $1 AND t.type = 'expense' AND t.transfer_id IS NULL AND date_trunc('year', t.date) = date_trunc('year', p.today)
+	)
+	SELECT
+		base_currency,
+		COALESCE(SUM(CASE WHEN date = today THEN amount END), 0),
+		COALESCE(SUM(CASE WHEN date_trunc('month', date) = date_trunc('month', today) THEN amount END), 0),
+		COALESCE(SUM(amount), 0),
+		ARRAY_REMOVE(ARRAY_AGG(DISTINCT CASE WHEN date IS NOT NULL AND amount IS NULL THEN currency END), NULL)
+	FROM tx
+	GROUP BY base_currency
 	`
-	var row *sql.Row
-	if period == "daily" {
-		row = r.db.QueryRowContext(ctx, query, ms.UserID, ms.DailyLimit)
-	} else if period == "monthly" {
-		row = r.db.QueryRowContext(ctx, query, ms.UserID, ms.MonthlyLimit)
-	} else if period == "yearly" {
-		row = r.db.QueryRowContext(ctx, query, ms.UserID, ms.YearlyLimit)
+
+	spending := &entity.Spending{}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&spending.Currency,
+		&spending.Daily,
+		&spending.Monthly,
+		&spending.Yearly,
+		pq.Array(&spending.UnconvertedCurrencies),
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, errx.ErrUserNotFound
 	}
 
-	var existingMS entity.MaximumSpend
-	err := row.Scan(&existingMS.ID, &existingMS.UserID, &existingMS.DailyLimit, &existingMS.MonthlyLimit, &existingMS.YearlyLimit)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil // No existing maximum spend found
-		}
-		return err
+		return nil, errx.ErrDatabaseError
 	}
 
-	ms.ID = existingMS.ID // Set the ID for update operation
-	return nil
+	return spending, nil
 }
 
 func (r *maximumSpendRepository) UpsertMaximumSpend(ctx context.Context, ms *entity.MaximumSpend) error {
@@ -98,7 +121,7 @@ func (r *maximumSpendRepository) GetMaximumSpendByUserID(ctx context.Context, us
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, errx.NewNotFoundError("User doesnt set maximum spend yet")
+		return nil, errx.ErrMaximumSpendNotFound
 	}
 
 	if err != nil {
@@ -2,33 +2,39 @@ package service
 
 import (
 	"context"
+	"log"
 
 	"github.com/google/uuid"
+	auditService "github.com/kenziehh/cashflow-be/internal/domain/audit/service"
 	"github.com/kenziehh/cashflow-be/internal/domain/maximum_spend/entity"
 	"github.com/kenziehh/cashflow-be/internal/domain/maximum_spend/repository"
 	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/money"
 )
 
 type MaximumSpendService interface {
-	SetMaximumSpend(ctx context.Context, userID uuid.UUID, daily, monthly, yearly float64) (*entity.MaximumSpend, error)
+	SetMaximumSpend(ctx context.Context, userID uuid.UUID, daily, monthly, yearly money.Amount) (*entity.MaximumSpend, error)
 	GetMaximumSpend(ctx context.Context, userID uuid.UUID) (*entity.MaximumSpend, error)
+	GetSpending(ctx context.Context, userID uuid.UUID) (*entity.Spending, error)
 }
 
 type maximumSpendService struct {
-	repo repository.MaximumSpendRepository
+	repo  repository.MaximumSpendRepository
+	audit auditService.AuditService
 }
 
-func NewMaximumSpendService(repo repository.MaximumSpendRepository) MaximumSpendService {
+func NewMaximumSpendService(repo repository.MaximumSpendRepository, audit auditService.AuditService) MaximumSpendService {
 	return &maximumSpendService{
-		repo: repo,
+		repo:  repo,
+		audit: audit,
 	}
 }
 
-func (s *maximumSpendService) SetMaximumSpend(ctx context.Context, userID uuid.UUID, daily, monthly, yearly float64) (*entity.MaximumSpend, error) {
+func (s *maximumSpendService) SetMaximumSpend(ctx context.Context, userID uuid.UUID, daily, monthly, yearly money.Amount) (*entity.MaximumSpend, error) {
 	// Cek apakah user sudah pernah set sebelumnya
 	existing, err := s.repo.GetMaximumSpendByUserID(ctx, userID)
 	if err != nil {
-		if err == errx.NewNotFoundError("User doesnt set maximum spend yet") {
+		if err == errx.ErrMaximumSpendNotFound {
 			// Belum ada, buat baru
 			newMS := &entity.MaximumSpend{
 				ID:           uuid.NewString(),
@@ -40,12 +46,14 @@ func (s *maximumSpendService) SetMaximumSpend(ctx context.Context, userID uuid.U
 			if err := s.repo.UpsertMaximumSpend(ctx, newMS); err != nil {
 				return nil, err
 			}
+			s.recordLimitsUpdated(ctx, nil, newMS)
 			return newMS, nil
 		}
 		return nil, err
 	}
 
 	// Sudah ada, update data
+	before := *existing
 	existing.DailyLimit = daily
 	existing.MonthlyLimit = monthly
 	existing.YearlyLimit = yearly
@@ -53,10 +61,27 @@ func (s *maximumSpendService) SetMaximumSpend(ctx context.Context, userID uuid.U
 	if err := s.repo.UpsertMaximumSpend(ctx, existing); err != nil {
 		return nil, err
 	}
+	s.recordLimitsUpdated(ctx, &before, existing)
 
 	return existing, nil
 }
 
+// recordLimitsUpdated audits limits that are already saved, so failures are
+// only logged.
+func (s *maximumSpendService) recordLimitsUpdated(ctx context.Context, before, after *entity.MaximumSpend) {
+	err := s.audit.Record(ctx, auditService.Event{
+		UserID:     after.UserID,
+		Action:     auditService.ActionLimitsUpdated,
+		TargetType: auditService.TargetMaximumSpend,
+		TargetID:   after.ID,
+		Before:     before,
+		After:      after,
+	})
+	if err != nil {
+		log.Printf("[AUDIT ERROR] %s %s: %v", auditService.ActionLimitsUpdated, after.ID, err)
+	}
+}
+
 func (s *maximumSpendService) GetMaximumSpend(ctx context.Context, userID uuid.UUID) (*entity.MaximumSpend, error) {
 	ms, err := s.repo.GetMaximumSpendByUserID(ctx, userID)
 	if err != nil {
@@ -64,3 +89,7 @@ func (s *maximumSpendService) GetMaximumSpend(ctx context.Context, userID uuid.U
 	}
 	return ms, nil
 }
+
+func (s *maximumSpendService) GetSpending(ctx context.Context, userID uuid.UUID) (*entity.Spending, error) {
+	return s.repo.GetSpending(ctx, userID)
+}
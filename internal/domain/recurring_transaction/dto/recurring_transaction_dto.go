@@ -0,0 +1,34 @@
+package dto
+
+import "github.com/kenziehh/cashflow-be/pkg/money"
+
+type CreateRecurringTransactionRequest struct {
+	TransactionType string       `json:"transaction_type" validate:"required,oneof=income expense"`
+	Amount          money.Amount `json:"amount" validate:"required,gt=0" swaggertype:"number" example:"150000.50"`
+	Currency        string       `json:"currency,omitempty" validate:"omitempty,iso4217" example:"USD"` // ISO 4217, defaults to the account's currency
+	AccountID       string       `json:"account_id,omitempty" validate:"omitempty,uuid"`                // defaults to the user's first account
+	CategoryID      string       `json:"category_id" validate:"required,ulid" swaggertype:"string" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
+	Note            string       `json:"note,omitempty"`
+	Period          string       `json:"period" validate:"required,oneof=daily weekly monthly yearly"`
+	Interval        int          `json:"interval,omitempty" validate:"omitempty,min=1,max=366" example:"1"` // every Interval periods, defaults to 1
+	MonthEnd        bool         `json:"month_end,omitempty"`                                               // monthly and yearly rules fall on the last day of the month
+	StartDate       string       `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate         string       `json:"end_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// UpdateRecurringTransactionRequest changes the occurrence on FromDate and
+// every later one, including those already created. Earlier occurrences keep
+// the old values.
+type UpdateRecurringTransactionRequest struct {
+	FromDate        string       `json:"from_date" validate:"required,datetime=2006-01-02" example:"2025-03-31"`
+	TransactionType string       `json:"transaction_type" validate:"required,oneof=income expense"`
+	Amount          money.Amount `json:"amount" validate:"required,gt=0" swaggertype:"number" example:"150000.50"`
+	Currency        string       `json:"currency,omitempty" validate:"omitempty,iso4217" example:"USD"` // ISO 4217, unchanged when empty
+	AccountID       string       `json:"account_id,omitempty" validate:"omitempty,uuid"`                // unchanged when empty
+	CategoryID      string       `json:"category_id" validate:"required,ulid" swaggertype:"string" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
+	Note            string       `json:"note,omitempty"`
+	Period          string       `json:"period" validate:"required,oneof=daily weekly monthly yearly"`
+	Interval        int          `json:"interval,omitempty" validate:"omitempty,min=1,max=366" example:"1"`
+	MonthEnd        bool         `json:"month_end,omitempty"`
+	EndDate         string       `json:"end_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
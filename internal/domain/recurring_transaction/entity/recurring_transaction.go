@@ -0,0 +1,134 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/pkg/money"
+)
+
+const dateLayout = "2006-01-02"
+
+// RecurringTransaction is a rule that creates a transaction every Interval
+// periods from StartDate until EndDate (inclusive, open ended when empty).
+//
+// Monthly and yearly occurrences keep the day of StartDate and fall on the
+// last day of shorter months (the 31st becomes the 30th or Feb 28/29). With
+// MonthEnd they always fall on the last day of the month.
+//
+// NextIndex counts the occurrences already created or skipped; NextDate is
+// the date of the next one and is empty once the rule has ended. Dates are
+// YYYY-MM-DD.
+type RecurringTransaction struct {
+	ID              uuid.UUID    `json:"id"`
+	UserID          uuid.UUID    `json:"user_id"`
+	AccountID       uuid.UUID    `json:"account_id"`
+	CategoryID      string       `json:"category_id" swaggertype:"string" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
+	TransactionType string       `json:"transaction_type"`
+	Amount          money.Amount `json:"amount" swaggertype:"number" example:"150000.50"`
+	Currency        string       `json:"currency" example:"IDR"`
+	Note            string       `json:"note"`
+	Period          string       `json:"period" example:"monthly"`
+	Interval        int          `json:"interval" example:"1"`
+	MonthEnd        bool         `json:"month_end"`
+	StartDate       string       `json:"start_date" example:"2025-01-31"`
+	EndDate         string       `json:"end_date,omitempty"`
+	NextIndex       int          `json:"occurrences"`
+	NextDate        string       `json:"next_date,omitempty" example:"2025-02-28"`
+	PausedAt        *time.Time   `json:"paused_at,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+}
+
+// OccurrenceDate returns the date of the n-th occurrence, counting from 0,
+// ignoring EndDate.
+func (r *RecurringTransaction) OccurrenceDate(n int) string {
+	start := parseDate(r.StartDate)
+	step := n * max(r.Interval, 1)
+
+	switch r.Period {
+	case "daily":
+		return start.AddDate(0, 0, step).Format(dateLayout)
+	case "weekly":
+		return start.AddDate(0, 0, 7*step).Format(dateLayout)
+	case "yearly":
+		return r.dayInMonth(start.Year()+step, start.Month(), start.Day())
+	default:
+		return r.dayInMonth(start.Year(), start.Month()+time.Month(step), start.Day())
+	}
+}
+
+// dayInMonth normalizes month overflow (month 14 is February next year) and
+// clamps day to the month's length.
+func (r *RecurringTransaction) dayInMonth(year int, month time.Month, day int) string {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	if r.MonthEnd || day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1).Format(dateLayout)
+}
+
+// IndexOnOrAfter returns the index of the first occurrence on or after date.
+// The index is computed from the distance to StartDate; month-end clamping
+// can put the estimate one occurrence off, which the loops correct.
+func (r *RecurringTransaction) IndexOnOrAfter(date string) int {
+	start, target := parseDate(r.StartDate), parseDate(date)
+	if !target.After(start) {
+		return 0
+	}
+	step := max(r.Interval, 1)
+
+	var n int
+	switch r.Period {
+	case "daily":
+		n = ceilDiv(daysBetween(start, target), step)
+	case "weekly":
+		n = ceilDiv(daysBetween(start, target), 7*step)
+	case "yearly":
+		n = (target.Year() - start.Year()) / step
+	default:
+		months := (target.Year()-start.Year())*12 + int(target.Month()-start.Month())
+		n = months / step
+	}
+
+	for n > 0 && r.OccurrenceDate(n-1) >= date {
+		n--
+	}
+	for r.OccurrenceDate(n) < date {
+		n++
+	}
+	return n
+}
+
+// daysBetween counts whole days between two UTC dates. time.Sub is not used
+// as a Duration only spans about 290 years.
+func daysBetween(from, to time.Time) int {
+	return int((to.Unix() - from.Unix()) / (24 * 60 * 60))
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
+
+// IsOccurrence reports whether an occurrence falls on date, and its index.
+func (r *RecurringTransaction) IsOccurrence(date string) (int, bool) {
+	n := r.IndexOnOrAfter(date)
+	return n, r.OccurrenceDate(n) == date && (r.EndDate == "" || date <= r.EndDate)
+}
+
+// SetNextIndex moves the schedule to the n-th occurrence.
+func (r *RecurringTransaction) SetNextIndex(n int) {
+	r.NextIndex = n
+	r.NextDate = r.OccurrenceDate(n)
+	if r.EndDate != "" && r.NextDate > r.EndDate {
+		r.NextDate = ""
+	}
+}
+
+// parseDate reads dates that were validated on input or come from a DATE
+// column, so it does not fail.
+func parseDate(s string) time.Time {
+	t, _ := time.Parse(dateLayout, s)
+	return t
+}
@@ -0,0 +1,191 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOccurrenceDate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule RecurringTransaction
+		want []string
+	}{
+		{
+			name: "daily",
+			rule: RecurringTransaction{Period: "daily", Interval: 1, StartDate: "2024-02-27"},
+			want: []string{"2024-02-27", "2024-02-28", "2024-02-29", "2024-03-01"},
+		},
+		{
+			name: "every 3 days",
+			rule: RecurringTransaction{Period: "daily", Interval: 3, StartDate: "2024-12-30"},
+			want: []string{"2024-12-30", "2025-01-02", "2025-01-05"},
+		},
+		{
+			name: "every 2 weeks",
+			rule: RecurringTransaction{Period: "weekly", Interval: 2, StartDate: "2024-12-20"},
+			want: []string{"2024-12-20", "2025-01-03", "2025-01-17"},
+		},
+		{
+			name: "monthly from the 31st clamps to shorter months",
+			rule: RecurringTransaction{Period: "monthly", Interval: 1, StartDate: "2024-01-31"},
+			want: []string{"2024-01-31", "2024-02-29", "2024-03-31", "2024-04-30", "2024-05-31"},
+		},
+		{
+			name: "monthly from the 30th outside a leap year",
+			rule: RecurringTransaction{Period: "monthly", Interval: 1, StartDate: "2025-01-30"},
+			want: []string{"2025-01-30", "2025-02-28", "2025-03-30"},
+		},
+		{
+			name: "every 3 months across the year end",
+			rule: RecurringTransaction{Period: "monthly", Interval: 3, StartDate: "2024-11-30"},
+			want: []string{"2024-11-30", "2025-02-28", "2025-05-30", "2025-08-30"},
+		},
+		{
+			name: "month end",
+			rule: RecurringTransaction{Period: "monthly", Interval: 1, MonthEnd: true, StartDate: "2025-02-28"},
+			want: []string{"2025-02-28", "2025-03-31", "2025-04-30"},
+		},
+		{
+			name: "yearly from a leap day",
+			rule: RecurringTransaction{Period: "yearly", Interval: 1, StartDate: "2024-02-29"},
+			want: []string{"2024-02-29", "2025-02-28", "2026-02-28", "2027-02-28", "2028-02-29"},
+		},
+		{
+			name: "every 2 years at the month end",
+			rule: RecurringTransaction{Period: "yearly", Interval: 2, MonthEnd: true, StartDate: "2023-02-01"},
+			want: []string{"2023-02-28", "2025-02-28", "2027-02-28", "2029-02-28"},
+		},
+		{
+			name: "missing interval counts as 1",
+			rule: RecurringTransaction{Period: "weekly", StartDate: "2025-01-01"},
+			want: []string{"2025-01-01", "2025-01-08"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for n, want := range tt.want {
+				if got := tt.rule.OccurrenceDate(n); got != want {
+					t.Errorf("OccurrenceDate(%d) = %s, want %s", n, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestIndexOnOrAfter checks the computed index against a walk through the
+// occurrences for every day of several years.
+func TestIndexOnOrAfter(t *testing.T) {
+	rules := []RecurringTransaction{
+		{Period: "daily", Interval: 1, StartDate: "2024-01-31"},
+		{Period: "daily", Interval: 5, StartDate: "2024-01-31"},
+		{Period: "weekly", Interval: 1, StartDate: "2024-01-31"},
+		{Period: "weekly", Interval: 3, StartDate: "2024-01-31"},
+		{Period: "monthly", Interval: 1, StartDate: "2024-01-31"},
+		{Period: "monthly", Interval: 1, StartDate: "2024-01-29"},
+		{Period: "monthly", Interval: 5, StartDate: "2024-01-31"},
+		{Period: "monthly", Interval: 1, MonthEnd: true, StartDate: "2024-02-01"},
+		{Period: "yearly", Interval: 1, StartDate: "2024-02-29"},
+		{Period: "yearly", Interval: 3, StartDate: "2024-12-31"},
+	}
+
+	from := parseDate("2023-12-01")
+	until := parseDate("2030-01-01")
+	for _, rule := range rules {
+		n := 0
+		for day := from; day.Before(until); day = day.AddDate(0, 0, 1) {
+			date := day.Format(dateLayout)
+			for rule.OccurrenceDate(n) < date {
+				n++
+			}
+			if got := rule.IndexOnOrAfter(date); got != n {
+				t.Fatalf("%s every %d from %s (month end %t): IndexOnOrAfter(%s) = %d, want %d",
+					rule.Period, rule.Interval, rule.StartDate, rule.MonthEnd, date, got, n)
+			}
+		}
+	}
+}
+
+func TestIndexOnOrAfterFarFromStart(t *testing.T) {
+	rule := RecurringTransaction{Period: "daily", Interval: 1, StartDate: "2025-01-01"}
+
+	done := make(chan int)
+	go func() { done <- rule.IndexOnOrAfter("9999-12-31") }()
+
+	select {
+	case n := <-done:
+		if got := rule.OccurrenceDate(n); got != "9999-12-31" {
+			t.Errorf("occurrence %d is %s, want 9999-12-31", n, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("IndexOnOrAfter walked the occurrences one by one")
+	}
+}
+
+func TestEndedRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     RecurringTransaction
+		index    int
+		wantNext string
+	}{
+		{
+			name:     "open ended",
+			rule:     RecurringTransaction{Period: "monthly", Interval: 1, StartDate: "2025-01-31"},
+			index:    1,
+			wantNext: "2025-02-28",
+		},
+		{
+			name:     "last occurrence on the end date",
+			rule:     RecurringTransaction{Period: "monthly", Interval: 1, StartDate: "2025-01-31", EndDate: "2025-02-28"},
+			index:    1,
+			wantNext: "2025-02-28",
+		},
+		{
+			name:     "past the end date",
+			rule:     RecurringTransaction{Period: "monthly", Interval: 1, StartDate: "2025-01-31", EndDate: "2025-02-28"},
+			index:    2,
+			wantNext: "",
+		},
+		{
+			name:     "end date between occurrences",
+			rule:     RecurringTransaction{Period: "weekly", Interval: 2, StartDate: "2025-01-01", EndDate: "2025-01-20"},
+			index:    2,
+			wantNext: "",
+		},
+		{
+			name:     "end date on the start date",
+			rule:     RecurringTransaction{Period: "daily", Interval: 1, StartDate: "2025-01-01", EndDate: "2025-01-01"},
+			index:    0,
+			wantNext: "2025-01-01",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := tt.rule
+			rule.SetNextIndex(tt.index)
+			if rule.NextIndex != tt.index || rule.NextDate != tt.wantNext {
+				t.Errorf("SetNextIndex(%d) = %d %q, want %d %q", tt.index, rule.NextIndex, rule.NextDate, tt.index, tt.wantNext)
+			}
+		})
+	}
+
+	rule := RecurringTransaction{Period: "monthly", Interval: 1, StartDate: "2025-01-31", EndDate: "2025-03-31"}
+	occurrences := []struct {
+		date  string
+		index int
+		ok    bool
+	}{
+		{date: "2025-01-31", index: 0, ok: true},
+		{date: "2025-02-28", index: 1, ok: true},
+		{date: "2025-03-01", index: 2, ok: false},
+		{date: "2025-03-31", index: 2, ok: true},
+		{date: "2025-04-30", index: 3, ok: false},
+		{date: "2024-12-31", index: 0, ok: false},
+	}
+	for _, o := range occurrences {
+		if index, ok := rule.IsOccurrence(o.date); index != o.index || ok != o.ok {
+			t.Errorf("IsOccurrence(%s) = %d, %t, want %d, %t", o.date, index, ok, o.index, o.ok)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package http
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/recurring_transaction/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/recurring_transaction/service"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/response"
+)
+
+type RecurringTransactionHandler struct {
+	service  service.RecurringTransactionService
+	validate *validator.Validate
+}
+
+func NewRecurringTransactionHandler(service service.RecurringTransactionService) *RecurringTransactionHandler {
+	return &RecurringTransactionHandler{
+		service:  service,
+		validate: validator.New(),
+	}
+}
+
+// CreateRecurringTransaction godoc
+// @Summary Create a recurring transaction
+// @Description Create a rule that adds a transaction every interval periods from start_date until end_date. Occurrences already due are created right away.
+// @Tags recurring-transactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateRecurringTransactionRequest true "Recurring transaction request"
+// @Success 201 {object} response.Response{data=entity.RecurringTransaction}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /recurring-transactions [post]
+func (h *RecurringTransactionHandler) CreateRecurringTransaction(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	var req dto.CreateRecurringTransactionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	rule, err := h.service.CreateRecurringTransaction(c.Context(), userID, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response.SuccessResponse("Recurring transaction created successfully", rule))
+}
+
+// ListRecurringTransactions godoc
+// @Summary List recurring transactions
+// @Description List the current user's recurring transactions, soonest next occurrence first
+// @Tags recurring-transactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]entity.RecurringTransaction}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /recurring-transactions [get]
+func (h *RecurringTransactionHandler) ListRecurringTransactions(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	rules, err := h.service.ListRecurringTransactions(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Recurring transactions retrieved successfully", rules))
+}
+
+// GetRecurringTransactionByID godoc
+// @Summary Get a recurring transaction
+// @Description Get one of the current user's recurring transactions
+// @Tags recurring-transactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Recurring transaction ID"
+// @Success 200 {object} response.Response{data=entity.RecurringTransaction}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /recurring-transactions/{id} [get]
+func (h *RecurringTransactionHandler) GetRecurringTransactionByID(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid recurring transaction ID format")
+	}
+
+	rule, err := h.service.GetRecurringTransactionByID(c.Context(), userID, id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Recurring transaction retrieved successfully", rule))
+}
+
+// UpdateRecurringTransaction godoc
+// @Summary Edit this and future occurrences
+// @Description Change the occurrence on from_date and every later one, including those already created. Earlier occurrences are kept as they are; editing from a later occurrence splits the rule and returns the new one.
+// @Tags recurring-transactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Recurring transaction ID"
+// @Param request body dto.UpdateRecurringTransactionRequest true "Recurring transaction request"
+// @Success 200 {object} response.Response{data=entity.RecurringTransaction}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /recurring-transactions/{id} [put]
+func (h *RecurringTransactionHandler) UpdateRecurringTransaction(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid recurring transaction ID format")
+	}
+
+	var req dto.UpdateRecurringTransactionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	rule, err := h.service.UpdateFutureOccurrences(c.Context(), userID, id, req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Recurring transaction updated successfully", rule))
+}
+
+// PauseRecurringTransaction godoc
+// @Summary Pause a recurring transaction
+// @Description Stop creating occurrences until the rule is resumed
+// @Tags recurring-transactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Recurring transaction ID"
+// @Success 200 {object} response.Response{data=entity.RecurringTransaction}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /recurring-transactions/{id}/pause [post]
+func (h *RecurringTransactionHandler) PauseRecurringTransaction(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid recurring transaction ID format")
+	}
+
+	rule, err := h.service.PauseRecurringTransaction(c.Context(), userID, id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Recurring transaction paused successfully", rule))
+}
+
+// ResumeRecurringTransaction godoc
+// @Summary Resume a recurring transaction
+// @Description Continue a paused rule from today. Occurrences that fell due while it was paused are not created.
+// @Tags recurring-transactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Recurring transaction ID"
+// @Success 200 {object} response.Response{data=entity.RecurringTransaction}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /recurring-transactions/{id}/resume [post]
+func (h *RecurringTransactionHandler) ResumeRecurringTransaction(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid recurring transaction ID format")
+	}
+
+	rule, err := h.service.ResumeRecurringTransaction(c.Context(), userID, id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Recurring transaction resumed successfully", rule))
+}
+
+// SkipNextOccurrence godoc
+// @Summary Skip the next occurrence
+// @Description Move the rule past its next occurrence without creating it
+// @Tags recurring-transactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Recurring transaction ID"
+// @Success 200 {object} response.Response{data=entity.RecurringTransaction}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /recurring-transactions/{id}/skip [post]
+func (h *RecurringTransactionHandler) SkipNextOccurrence(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid recurring transaction ID format")
+	}
+
+	rule, err := h.service.SkipNextOccurrence(c.Context(), userID, id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Next occurrence skipped successfully", rule))
+}
+
+// DeleteRecurringTransaction godoc
+// @Summary Delete a recurring transaction
+// @Description Stop a rule for good. Occurrences already created are kept as ordinary transactions.
+// @Tags recurring-transactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Recurring transaction ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /recurring-transactions/{id} [delete]
+func (h *RecurringTransactionHandler) DeleteRecurringTransaction(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return errx.NewBadRequestError("Invalid recurring transaction ID format")
+	}
+
+	if err := h.service.DeleteRecurringTransaction(c.Context(), userID, id); err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Recurring transaction deleted successfully", nil))
+}
@@ -0,0 +1,479 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/internal/domain/recurring_transaction/entity"
+	transactionEntity "github.com/kenziehh/cashflow-be/internal/domain/transaction/entity"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+)
+
+const dateLayout = "2006-01-02"
+
+type RecurringTransactionRepository interface {
+	CreateRecurringTransaction(ctx context.Context, rule *entity.RecurringTransaction) error
+	GetRecurringTransactionByID(ctx context.Context, userID, id uuid.UUID) (*entity.RecurringTransaction, error)
+	ListRecurringTransactions(ctx context.Context, userID uuid.UUID) ([]entity.RecurringTransaction, error)
+	UpdateSchedule(ctx context.Context, rule *entity.RecurringTransaction, prevIndex int) error
+	ReplaceFrom(ctx context.Context, current, next *entity.RecurringTransaction, fromDate string, prevIndex int) error
+	DeleteRecurringTransaction(ctx context.Context, userID, id uuid.UUID) error
+	UserToday(ctx context.Context, userID uuid.UUID) (string, error)
+	ListDueRecurringTransactions(ctx context.Context, limit int) ([]uuid.UUID, error)
+	Materialize(ctx context.Context, id uuid.UUID, limit int) ([]*transactionEntity.Transaction, error)
+}
+
+type recurringTransactionRepository struct {
+	db    *sql.DB
+	redis *redis.Client
+}
+
+func NewRecurringTransactionRepository(db *sql.DB, redis *redis.Client) RecurringTransactionRepository {
+	return &recurringTransactionRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+const recurringColumns = `
+	r.id, r.user_id, r.account_id, COALESCE(r.category_id, ''), r.type, r.amount, r.currency, r.note,
+	r.period, r.interval_count, r.month_end, r.start_date, r.end_date, r.next_index, r.next_date,
+	r.paused_at, r.created_at, r.updated_at
+`
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (r *recurringTransactionRepository) CreateRecurringTransaction(ctx context.Context, rule *entity.RecurringTransaction) error {
+	if err := insertRecurring(ctx, r.db, rule); err != nil {
+		log.Printf("[DB ERROR] CreateRecurringTransaction failed: %v\n", err)
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func insertRecurring(ctx context.Context, db execer, rule *entity.RecurringTransaction) error {
+	query := `
+	INSERT INTO recurring_transactions (
+		id, user_id, account_id, category_id, type, amount, currency, note, period, interval_count,
+		month_end, start_date, end_date, next_index, next_date, paused_at, created_at, updated_at
+	)
+	VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+	`
+
+	_, err := db.ExecContext(ctx, query,
+		rule.ID,
+		rule.UserID,
+		rule.AccountID,
+		rule.CategoryID,
+		rule.TransactionType,
+		rule.Amount,
+		rule.Currency,
+		rule.Note,
+		rule.Period,
+		rule.Interval,
+		rule.MonthEnd,
+		rule.StartDate,
+		nullDate(rule.EndDate),
+		rule.NextIndex,
+		nullDate(rule.NextDate),
+		rule.PausedAt,
+		rule.CreatedAt,
+		rule.UpdatedAt,
+	)
+	return err
+}
+
+func (r *recurringTransactionRepository) GetRecurringTransactionByID(ctx context.Context, userID, id uuid.UUID) (*entity.RecurringTransaction, error) {
+	query := `SELECT ` + recurringColumns + `
+	FROM recurring_transactions r
+	WHERE r.id = $1 AND r.user_id = $2
+	`
+
+	var rule entity.RecurringTransaction
+	err := scanRecurring(r.db.QueryRowContext(ctx, query, id, userID), &rule)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errx.ErrRecurringTransactionNotFound
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] GetRecurringTransactionByID failed: %v\n", err)
+		return nil, errx.ErrDatabaseError
+	}
+
+	return &rule, nil
+}
+
+func (r *recurringTransactionRepository) ListRecurringTransactions(ctx context.Context, userID uuid.UUID) ([]entity.RecurringTransaction, error) {
+	query := `SELECT ` + recurringColumns + `
+	FROM recurring_transactions r
+	WHERE r.user_id = $1
+	ORDER BY r.next_date NULLS LAST, r.created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		log.Printf("[DB ERROR] ListRecurringTransactions failed: %v\n", err)
+		return nil, errx.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	rules := []entity.RecurringTransaction{}
+	for rows.Next() {
+		var rule entity.RecurringTransaction
+		if err := scanRecurring(rows, &rule); err != nil {
+			return nil, errx.ErrDatabaseError
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	return rules, nil
+}
+
+// UpdateSchedule saves the position and pause state of a rule read at
+// prevIndex. It fails when the scheduler has moved the rule on meanwhile.
+func (r *recurringTransactionRepository) UpdateSchedule(ctx context.Context, rule *entity.RecurringTransaction, prevIndex int) error {
+	query := `
+	UPDATE recurring_transactions
+	SET next_index = $1, next_date = $2, paused_at = $3, updated_at = $4
+	WHERE id = $5 AND user_id = $6 AND next_index = $7
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		rule.NextIndex, nullDate(rule.NextDate), rule.PausedAt, rule.UpdatedAt, rule.ID, rule.UserID, prevIndex,
+	)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return expectOneRow(result)
+}
+
+// ReplaceFrom stores an edit of every occurrence from fromDate on. When next
+// is a new rule, current has been ended before fromDate and next takes over;
+// otherwise current is updated in place. Occurrences already created from
+// fromDate on are changed to match and moved to next. They take next's
+// category, so any splits they had are dropped.
+func (r *recurringTransactionRepository) ReplaceFrom(ctx context.Context, current, next *entity.RecurringTransaction, fromDate string, prevIndex int) error {
+	dbTx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	defer dbTx.Rollback()
+
+	if current.ID == next.ID {
+		result, err := dbTx.ExecContext(ctx, `
+		UPDATE recurring_transactions
+		SET account_id = $1, category_id = NULLIF($2, ''), type = $3, amount = $4, currency = $5, note = $6,
+			period = $7, interval_count = $8, month_end = $9, end_date = $10, next_index = $11, next_date = $12,
+			updated_at = $13
+		WHERE id = $14 AND next_index = $15
+		`,
+			next.AccountID, next.CategoryID, next.TransactionType, next.Amount, next.Currency, next.Note,
+			next.Period, next.Interval, next.MonthEnd, nullDate(next.EndDate), next.NextIndex, nullDate(next.NextDate),
+			next.UpdatedAt, next.ID, prevIndex,
+		)
+		if err != nil {
+			log.Printf("[DB ERROR] ReplaceFrom failed to update rule: %v\n", err)
+			return errx.ErrDatabaseError
+		}
+		if err := expectOneRow(result); err != nil {
+			return err
+		}
+	} else {
+		result, err := dbTx.ExecContext(ctx, `
+		UPDATE recurring_transactions
+		SET end_date = $1, next_index = $2, next_date = $3, updated_at = $4
+		WHERE id = $5 AND next_index = $6
+		`,
+			nullDate(current.EndDate), current.NextIndex, nullDate(current.NextDate), current.UpdatedAt, current.ID, prevIndex,
+		)
+		if err != nil {
+			log.Printf("[DB ERROR] ReplaceFrom failed to end rule: %v\n", err)
+			return errx.ErrDatabaseError
+		}
+		if err := expectOneRow(result); err != nil {
+			return err
+		}
+
+		if err := insertRecurring(ctx, dbTx, next); err != nil {
+			log.Printf("[DB ERROR] ReplaceFrom failed to create rule: %v\n", err)
+			return errx.ErrDatabaseError
+		}
+	}
+
+	_, err = dbTx.ExecContext(ctx, `
+	DELETE FROM transaction_splits s
+	USING transactions t
+	WHERE s.transaction_id = t.id AND t.recurring_id = $1 AND t.occurrence_date >= $2
+	`, current.ID, fromDate)
+	if err != nil {
+		log.Printf("[DB ERROR] ReplaceFrom failed to drop occurrence splits: %v\n", err)
+		return errx.ErrDatabaseError
+	}
+
+	_, err = dbTx.ExecContext(ctx, `
+	UPDATE transactions
+	SET account_id = $1, category_id = NULLIF($2, ''), type = $3, amount = $4, currency = $5, note = $6,
+		period = $7, recurring_id = $8, updated_at = $9
+	WHERE recurring_id = $10 AND occurrence_date >= $11
+	`,
+		next.AccountID, next.CategoryID, next.TransactionType, next.Amount, next.Currency, next.Note,
+		next.Period, next.ID, next.UpdatedAt, current.ID, fromDate,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] ReplaceFrom failed to update occurrences: %v\n", err)
+		return errx.ErrDatabaseError
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// DeleteRecurringTransaction stops a rule. Occurrences already created are
+// kept as ordinary transactions.
+func (r *recurringTransactionRepository) DeleteRecurringTransaction(ctx context.Context, userID, id uuid.UUID) error {
+	query := `
+	DELETE FROM recurring_transactions
+	WHERE id = $1 AND user_id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	if affected == 0 {
+		return errx.ErrRecurringTransactionNotFound
+	}
+
+	return nil
+}
+
+// UserToday returns today's date in the user's timezone.
+func (r *recurringTransactionRepository) UserToday(ctx context.Context, userID uuid.UUID) (string, error) {
+	var today time.Time
+	err := r.db.QueryRowContext(ctx,
+		`SELECT (now() AT TIME ZONE timezone)::date FROM users WHERE id = $1`, userID,
+	).Scan(&today)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errx.ErrUserNotFound
+	}
+	if err != nil {
+		return "", errx.ErrDatabaseError
+	}
+
+	return today.Format(dateLayout), nil
+}
+
+// ListDueRecurringTransactions returns active rules with an occurrence due
+// today or earlier in their user's timezone. Users awaiting deletion are
+// left alone.
+func (r *recurringTransactionRepository) ListDueRecurringTransactions(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	query := `
+	SELECT r.id
+	FROM recurring_transactions r
+	JOIN users u ON u.id = r.user_id
+	WHERE r.paused_at IS NULL
+		AND r.next_date <= (now() AT TIME ZONE u.timezone)::date
+		AND u.deletion_scheduled_at IS NULL
+	ORDER BY r.next_date
+	LIMIT $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		log.Printf("[DB ERROR] ListDueRecurringTransactions failed: %v\n", err)
+		return nil, errx.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, errx.ErrDatabaseError
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	return ids, nil
+}
+
+// Materialize creates up to limit due occurrences of an active rule and
+// moves it past them, all in one transaction. The rule row stays locked
+// meanwhile, so concurrent runs skip it, and an occurrence that already
+// exists is never created twice. It returns the transactions created.
+func (r *recurringTransactionRepository) Materialize(ctx context.Context, id uuid.UUID, limit int) ([]*transactionEntity.Transaction, error) {
+	dbTx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+	defer dbTx.Rollback()
+
+	query := `SELECT ` + recurringColumns + `, (now() AT TIME ZONE u.timezone)::date
+	FROM recurring_transactions r
+	JOIN users u ON u.id = r.user_id
+	WHERE r.id = $1 AND r.paused_at IS NULL
+	FOR UPDATE OF r SKIP LOCKED
+	`
+
+	var rule entity.RecurringTransaction
+	var today time.Time
+	err = scanRecurring(dbTx.QueryRowContext(ctx, query, id), &rule, &today)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Paused, deleted or being materialized by another run
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] Materialize failed to lock rule %s: %v\n", id, err)
+		return nil, errx.ErrDatabaseError
+	}
+
+	insert := `
+	INSERT INTO transactions (
+		id, user_id, account_id, recurring_id, occurrence_date, amount, currency, type, category_id,
+		note, period, date, proof_file, created_at, updated_at
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11, $5, '', $12, $12)
+	ON CONFLICT (recurring_id, occurrence_date) DO NOTHING
+	RETURNING id
+	`
+
+	now := time.Now()
+	created := []*transactionEntity.Transaction{}
+	for i := 0; i < limit && rule.NextDate != "" && rule.NextDate <= today.Format(dateLayout); i++ {
+		tx := &transactionEntity.Transaction{
+			ID:              uuid.New(),
+			UserID:          rule.UserID,
+			AccountID:       rule.AccountID,
+			RecurringID:     &rule.ID,
+			CategoryID:      rule.CategoryID,
+			TransactionType: rule.TransactionType,
+			Amount:          rule.Amount,
+			Currency:        rule.Currency,
+			Period:          rule.Period,
+			Note:            rule.Note,
+			Date:            rule.NextDate,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+
+		err := dbTx.QueryRowContext(ctx, insert,
+			tx.ID, tx.UserID, tx.AccountID, rule.ID, rule.NextDate, tx.Amount, tx.Currency,
+			tx.TransactionType, tx.CategoryID, tx.Note, tx.Period, now,
+		).Scan(&tx.ID)
+		switch {
+		case err == nil:
+			created = append(created, tx)
+		case !errors.Is(err, sql.ErrNoRows):
+			log.Printf("[DB ERROR] Materialize failed to create occurrence of %s: %v\n", id, err)
+			return nil, errx.ErrDatabaseError
+		}
+
+		rule.SetNextIndex(rule.NextIndex + 1)
+	}
+
+	_, err = dbTx.ExecContext(ctx,
+		`UPDATE recurring_transactions SET next_index = $1, next_date = $2, updated_at = $3 WHERE id = $4`,
+		rule.NextIndex, nullDate(rule.NextDate), now, rule.ID,
+	)
+	if err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	return created, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanRecurring reads recurringColumns followed by any extra columns.
+func scanRecurring(row rowScanner, rule *entity.RecurringTransaction, extra ...any) error {
+	var startDate time.Time
+	var endDate, nextDate, pausedAt sql.NullTime
+
+	dest := []any{
+		&rule.ID,
+		&rule.UserID,
+		&rule.AccountID,
+		&rule.CategoryID,
+		&rule.TransactionType,
+		&rule.Amount,
+		&rule.Currency,
+		&rule.Note,
+		&rule.Period,
+		&rule.Interval,
+		&rule.MonthEnd,
+		&startDate,
+		&endDate,
+		&rule.NextIndex,
+		&nextDate,
+		&pausedAt,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	rule.StartDate = startDate.Format(dateLayout)
+	rule.EndDate = formatNullDate(endDate)
+	rule.NextDate = formatNullDate(nextDate)
+	rule.PausedAt = nil
+	if pausedAt.Valid {
+		rule.PausedAt = &pausedAt.Time
+	}
+	return nil
+}
+
+func formatNullDate(d sql.NullTime) string {
+	if !d.Valid {
+		return ""
+	}
+	return d.Time.Format(dateLayout)
+}
+
+func nullDate(date string) interface{} {
+	if date == "" {
+		return nil
+	}
+	return date
+}
+
+func expectOneRow(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	if affected == 0 {
+		return errx.ErrRecurringTransactionChanged
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	accountService "github.com/kenziehh/cashflow-be/internal/domain/account/service"
+	auditService "github.com/kenziehh/cashflow-be/internal/domain/audit/service"
+	"github.com/kenziehh/cashflow-be/internal/domain/recurring_transaction/dto"
+	"github.com/kenziehh/cashflow-be/internal/domain/recurring_transaction/entity"
+	"github.com/kenziehh/cashflow-be/internal/domain/recurring_transaction/repository"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+)
+
+const dateLayout = "2006-01-02"
+
+const (
+	// dueBatchSize is how many due rules one pass of the scheduler loads
+	dueBatchSize = 100
+	// maxCatchUp bounds the occurrences created for one rule at a time, e.g.
+	// for a daily rule started years ago
+	maxCatchUp = 366
+
+	// A rule may start this many years before or after the user's today
+	maxStartYearsBack  = 1
+	maxStartYearsAhead = 10
+)
+
+type RecurringTransactionService interface {
+	CreateRecurringTransaction(ctx context.Context, userID uuid.UUID, req dto.CreateRecurringTransactionRequest) (*entity.RecurringTransaction, error)
+	GetRecurringTransactionByID(ctx context.Context, userID, id uuid.UUID) (*entity.RecurringTransaction, error)
+	ListRecurringTransactions(ctx context.Context, userID uuid.UUID) ([]entity.RecurringTransaction, error)
+	UpdateFutureOccurrences(ctx context.Context, userID, id uuid.UUID, req dto.UpdateRecurringTransactionRequest) (*entity.RecurringTransaction, error)
+	PauseRecurringTransaction(ctx context.Context, userID, id uuid.UUID) (*entity.RecurringTransaction, error)
+	ResumeRecurringTransaction(ctx context.Context, userID, id uuid.UUID) (*entity.RecurringTransaction, error)
+	SkipNextOccurrence(ctx context.Context, userID, id uuid.UUID) (*entity.RecurringTransaction, error)
+	DeleteRecurringTransaction(ctx context.Context, userID, id uuid.UUID) error
+	MaterializeDue(ctx context.Context) (int, error)
+}
+
+type recurringTransactionService struct {
+	repo     repository.RecurringTransactionRepository
+	accounts accountService.AccountService
+	audit    auditService.AuditService
+}
+
+func NewRecurringTransactionService(repo repository.RecurringTransactionRepository, accounts accountService.AccountService, audit auditService.AuditService) RecurringTransactionService {
+	return &recurringTransactionService{
+		repo:     repo,
+		accounts: accounts,
+		audit:    audit,
+	}
+}
+
+// CreateRecurringTransaction saves the rule and creates the occurrences
+// already due, so a rule starting today shows up in today's transactions.
+func (s *recurringTransactionService) CreateRecurringTransaction(ctx context.Context, userID uuid.UUID, req dto.CreateRecurringTransactionRequest) (*entity.RecurringTransaction, error) {
+	if req.EndDate != "" && req.EndDate < req.StartDate {
+		return nil, errx.ErrInvalidDateRange
+	}
+	if err := s.checkStartDate(ctx, userID, req.StartDate); err != nil {
+		return nil, err
+	}
+
+	account, err := s.accounts.ResolveAccount(ctx, userID, req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rule := &entity.RecurringTransaction{
+		ID:              uuid.New(),
+		UserID:          userID,
+		AccountID:       account.ID,
+		CategoryID:      req.CategoryID,
+		TransactionType: req.TransactionType,
+		Amount:          req.Amount,
+		Currency:        req.Currency,
+		Note:            req.Note,
+		Period:          req.Period,
+		Interval:        max(req.Interval, 1),
+		MonthEnd:        req.MonthEnd,
+		StartDate:       req.StartDate,
+		EndDate:         req.EndDate,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if rule.Currency == "" {
+		rule.Currency = account.Currency
+	}
+	rule.SetNextIndex(0)
+
+	if err := s.repo.CreateRecurringTransaction(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditService.ActionRecurringCreated,
+		TargetType: auditService.TargetRecurring,
+		TargetID:   rule.ID.String(),
+		After:      rule,
+	})
+
+	return s.materializeAndReload(ctx, rule)
+}
+
+// checkStartDate keeps rules near the present: the scheduler would otherwise
+// catch up on decades of occurrences, or never reach the first one.
+func (s *recurringTransactionService) checkStartDate(ctx context.Context, userID uuid.UUID, startDate string) error {
+	today, err := s.repo.UserToday(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	t, _ := time.Parse(dateLayout, today)
+	earliest := t.AddDate(-maxStartYearsBack, 0, 0).Format(dateLayout)
+	latest := t.AddDate(maxStartYearsAhead, 0, 0).Format(dateLayout)
+	if startDate < earliest || startDate > latest {
+		return errx.ErrStartDateOutOfRange
+	}
+	return nil
+}
+
+func (s *recurringTransactionService) GetRecurringTransactionByID(ctx context.Context, userID, id uuid.UUID) (*entity.RecurringTransaction, error) {
+	return s.repo.GetRecurringTransactionByID(ctx, userID, id)
+}
+
+func (s *recurringTransactionService) ListRecurringTransactions(ctx context.Context, userID uuid.UUID) ([]entity.RecurringTransaction, error) {
+	return s.repo.ListRecurringTransactions(ctx, userID)
+}
+
+// UpdateFutureOccurrences applies req to the occurrence on req.FromDate and
+// every later one. From the first occurrence the rule is changed in place;
+// otherwise it ends the occurrence before and a new rule takes over. The new
+// schedule picks up where the old one was, so nothing is created twice.
+func (s *recurringTransactionService) UpdateFutureOccurrences(ctx context.Context, userID, id uuid.UUID, req dto.UpdateRecurringTransactionRequest) (*entity.RecurringTransaction, error) {
+	current, err := s.repo.GetRecurringTransactionByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	from, ok := current.IsOccurrence(req.FromDate)
+	if !ok {
+		return nil, errx.ErrNotAnOccurrence
+	}
+	if req.EndDate != "" && req.EndDate < req.FromDate {
+		return nil, errx.ErrInvalidDateRange
+	}
+
+	before := *current
+	now := time.Now()
+
+	next := *current
+	if req.AccountID != "" {
+		account, err := s.accounts.ResolveAccount(ctx, userID, req.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		next.AccountID = account.ID
+	}
+	if req.Currency != "" {
+		next.Currency = req.Currency
+	}
+	next.CategoryID = req.CategoryID
+	next.TransactionType = req.TransactionType
+	next.Amount = req.Amount
+	next.Note = req.Note
+	next.Period = req.Period
+	next.Interval = max(req.Interval, 1)
+	next.MonthEnd = req.MonthEnd
+	next.EndDate = req.EndDate
+	next.UpdatedAt = now
+	if from > 0 {
+		next.ID = uuid.New()
+		next.StartDate = req.FromDate
+		next.CreatedAt = now
+
+		current.EndDate = current.OccurrenceDate(from - 1)
+		current.SetNextIndex(current.NextIndex)
+		current.UpdatedAt = now
+	}
+
+	// Occurrences before the current rule's next one were created or skipped
+	resumeFrom := current.OccurrenceDate(before.NextIndex)
+	if resumeFrom < req.FromDate {
+		resumeFrom = req.FromDate
+	}
+	next.SetNextIndex(next.IndexOnOrAfter(resumeFrom))
+
+	if err := s.repo.ReplaceFrom(ctx, current, &next, req.FromDate, before.NextIndex); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditService.ActionRecurringUpdated,
+		TargetType: auditService.TargetRecurring,
+		TargetID:   before.ID.String(),
+		Before:     before,
+		After:      &next,
+		Metadata:   map[string]any{"from_date": req.FromDate},
+	})
+
+	return s.materializeAndReload(ctx, &next)
+}
+
+func (s *recurringTransactionService) PauseRecurringTransaction(ctx context.Context, userID, id uuid.UUID) (*entity.RecurringTransaction, error) {
+	return s.updateSchedule(ctx, userID, id, func(rule *entity.RecurringTransaction) error {
+		if rule.PausedAt == nil {
+			now := time.Now()
+			rule.PausedAt = &now
+		}
+		return nil
+	})
+}
+
+// ResumeRecurringTransaction continues from today; occurrences that fell due
+// while the rule was paused are not created.
+func (s *recurringTransactionService) ResumeRecurringTransaction(ctx context.Context, userID, id uuid.UUID) (*entity.RecurringTransaction, error) {
+	today, err := s.repo.UserToday(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := s.updateSchedule(ctx, userID, id, func(rule *entity.RecurringTransaction) error {
+		if rule.PausedAt == nil {
+			return nil
+		}
+		rule.PausedAt = nil
+		if rule.NextDate != "" && rule.NextDate < today {
+			rule.SetNextIndex(rule.IndexOnOrAfter(today))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.materializeAndReload(ctx, rule)
+}
+
+func (s *recurringTransactionService) SkipNextOccurrence(ctx context.Context, userID, id uuid.UUID) (*entity.RecurringTransaction, error) {
+	return s.updateSchedule(ctx, userID, id, func(rule *entity.RecurringTransaction) error {
+		if rule.NextDate == "" {
+			return errx.ErrRecurringTransactionEnded
+		}
+		rule.SetNextIndex(rule.NextIndex + 1)
+		return nil
+	})
+}
+
+// updateSchedule applies change to the rule's position or pause state and
+// saves it unless nothing changed.
+func (s *recurringTransactionService) updateSchedule(ctx context.Context, userID, id uuid.UUID, change func(rule *entity.RecurringTransaction) error) (*entity.RecurringTransaction, error) {
+	rule, err := s.repo.GetRecurringTransactionByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *rule
+	if err := change(rule); err != nil {
+		return nil, err
+	}
+	if rule.NextIndex == before.NextIndex && (rule.PausedAt == nil) == (before.PausedAt == nil) {
+		return rule, nil
+	}
+
+	rule.UpdatedAt = time.Now()
+	if err := s.repo.UpdateSchedule(ctx, rule, before.NextIndex); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditService.ActionRecurringUpdated,
+		TargetType: auditService.TargetRecurring,
+		TargetID:   rule.ID.String(),
+		Before:     before,
+		After:      rule,
+	})
+
+	return rule, nil
+}
+
+func (s *recurringTransactionService) DeleteRecurringTransaction(ctx context.Context, userID, id uuid.UUID) error {
+	rule, err := s.repo.GetRecurringTransactionByID(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteRecurringTransaction(ctx, userID, id); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditService.ActionRecurringDeleted,
+		TargetType: auditService.TargetRecurring,
+		TargetID:   rule.ID.String(),
+		Before:     rule,
+	})
+	return nil
+}
+
+// MaterializeDue creates every occurrence that is due, in batches, and
+// returns how many transactions were created. Running it again, or on
+// several instances at once, creates nothing twice.
+func (s *recurringTransactionService) MaterializeDue(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		ids, err := s.repo.ListDueRecurringTransactions(ctx, dueBatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		batch := 0
+		for _, id := range ids {
+			created, err := s.materialize(ctx, id)
+			batch += created
+			if err != nil {
+				return total + batch, err
+			}
+		}
+		total += batch
+
+		// Rules locked by another instance stay due; leave them to it
+		if len(ids) < dueBatchSize || batch == 0 {
+			return total, nil
+		}
+	}
+}
+
+// materialize creates the due occurrences of one rule and records them in
+// the audit log.
+func (s *recurringTransactionService) materialize(ctx context.Context, id uuid.UUID) (int, error) {
+	created, err := s.repo.Materialize(ctx, id, maxCatchUp)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, tx := range created {
+		s.recordAudit(ctx, auditService.Event{
+			UserID:     tx.UserID,
+			Action:     auditService.ActionTransactionCreated,
+			TargetType: auditService.TargetTransaction,
+			TargetID:   tx.ID.String(),
+			After:      tx,
+			Metadata:   map[string]any{"recurring_id": id.String()},
+		})
+	}
+
+	return len(created), nil
+}
+
+// materializeAndReload creates the rule's due occurrences right away instead
+// of waiting for the scheduler, and returns the rule as it is afterwards.
+func (s *recurringTransactionService) materializeAndReload(ctx context.Context, rule *entity.RecurringTransaction) (*entity.RecurringTransaction, error) {
+	created, err := s.materialize(ctx, rule.ID)
+	if err != nil {
+		return nil, err
+	}
+	if created == 0 {
+		return rule, nil
+	}
+	return s.repo.GetRecurringTransactionByID(ctx, rule.UserID, rule.ID)
+}
+
+// recordAudit writes the audit entry of a change the repository has already
+// committed, so failures are only logged. Failing the request instead would
+// report a saved change as lost and skip creating the occurrences due.
+func (s *recurringTransactionService) recordAudit(ctx context.Context, event auditService.Event) {
+	if err := s.audit.Record(ctx, event); err != nil {
+		log.Printf("[AUDIT ERROR] %s %s: %v", event.Action, event.TargetID, err)
+	}
+}
@@ -1,32 +1,49 @@
 package dto
 
 import (
+	"github.com/google/uuid"
 	"github.com/kenziehh/cashflow-be/internal/domain/transaction/entity"
+	"github.com/kenziehh/cashflow-be/pkg/money"
 )
 
 type CreateTransactionRequest struct {
-	TransactionType string  `json:"transaction_type" validate:"required,oneof=income expense"`
-	Amount          float64 `json:"amount" validate:"required,gt=0"`
-	CategoryID      string  `json:"category_id" validate:"required,ulid" swaggertype:"string" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
-	Note            string  `json:"note,omitempty"`
-	Period          string  `json:"period" validate:"required,oneof=daily weekly monthly yearly"`
-	Date            string  `json:"date" validate:"required,datetime=2006-01-02"`
-	ProofFile       string  `json:"proof_file,omitempty"`
+	TransactionType string         `json:"transaction_type" validate:"required,oneof=income expense"`
+	Amount          money.Amount   `json:"amount" validate:"required,gt=0" swaggertype:"number" example:"150000.50"`
+	Currency        string         `json:"currency,omitempty" validate:"omitempty,iso4217" example:"USD"` // ISO 4217, defaults to the account's currency
+	AccountID       string         `json:"account_id,omitempty" validate:"omitempty,uuid"`                // defaults to the user's first account
+	CategoryID      string         `json:"category_id,omitempty" validate:"required_without=Splits,excluded_with=Splits,omitempty,ulid" swaggertype:"string" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
+	Splits          []SplitRequest `json:"splits,omitempty" validate:"omitempty,min=2,max=20,dive"` // instead of category_id, must add up to amount
+	Note            string         `json:"note,omitempty"`
+	Period          string         `json:"period" validate:"required,oneof=daily weekly monthly yearly"`
+	Date            string         `json:"date" validate:"required,datetime=2006-01-02"`
+	ProofFile       string         `json:"proof_file,omitempty"`
 }
 
+// SplitRequest is the part of a transaction's amount that belongs to one
+// category.
+type SplitRequest struct {
+	CategoryID string       `json:"category_id" validate:"required,ulid" swaggertype:"string" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
+	Amount     money.Amount `json:"amount" validate:"required,gt=0" swaggertype:"number" example:"50000"`
+	Note       string       `json:"note,omitempty"`
+}
+
+// UpdateTransactionRequest replaces the transaction's category or splits:
+// sending category_id removes the splits and sending splits removes the
+// category.
 type UpdateTransactionRequest struct {
 	// TransactionId   uuid.UUID `json:"transaction_id" validate:"required,uuid4"`
-	TransactionType string  `json:"transaction_type" validate:"required,oneof=income expense"`
-	Amount          float64 `json:"amount" validate:"required,gt=0"`
-	CategoryID      string  `json:"category_id" validate:"required,ulid" swaggertype:"string" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
-	Note            string  `json:"note,omitempty"`
-	Period          string  `json:"period" validate:"required,oneof=daily weekly monthly yearly"`
-	Date            string  `json:"date" validate:"required,datetime=2006-01-02"`
-	ProofFile       string  `json:"proof_file,omitempty"`
+	TransactionType string         `json:"transaction_type" validate:"required,oneof=income expense"`
+	Amount          money.Amount   `json:"amount" validate:"required,gt=0" swaggertype:"number" example:"150000.50"`
+	Currency        string         `json:"currency,omitempty" validate:"omitempty,iso4217" example:"USD"` // ISO 4217, unchanged when empty
+	AccountID       string         `json:"account_id,omitempty" validate:"omitempty,uuid"`                // unchanged when empty
+	CategoryID      string         `json:"category_id,omitempty" validate:"required_without=Splits,excluded_with=Splits,omitempty,ulid" swaggertype:"string" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
+	Splits          []SplitRequest `json:"splits,omitempty" validate:"omitempty,min=2,max=20,dive"` // instead of category_id, must add up to amount
+	Note            string         `json:"note,omitempty"`
+	Period          string         `json:"period" validate:"required,oneof=daily weekly monthly yearly"`
+	Date            string         `json:"date" validate:"required,datetime=2006-01-02"`
+	ProofFile       string         `json:"proof_file,omitempty"`
 }
 
-
-
 type PaginationMeta struct {
 	CurrentPage  int `json:"current_page"`
 	TotalPages   int `json:"total_pages"`
@@ -35,14 +52,68 @@ type PaginationMeta struct {
 }
 
 type TransactionListParams struct {
-	Page      int    `query:"page"`
-	Limit     int    `query:"limit"`
-	Type      string `query:"type"`
-	Period    string `query:"period"`
-	StartDate string `query:"start_date"`
-	EndDate   string `query:"end_date"`
-	SortBy    string `query:"sort_by"`
-	OrderBy   string `query:"order_by"`
+	Page       int    `query:"page"`
+	Limit      int    `query:"limit"`
+	Type       string `query:"type"`
+	Period     string `query:"period"`
+	AccountID  string `query:"account_id" validate:"omitempty,uuid"`
+	CategoryID string `query:"category_id" validate:"omitempty,ulid"` // also matches transactions with a split in the category
+	StartDate  string `query:"start_date"`
+	EndDate    string `query:"end_date"`
+	SortBy     string `query:"sort_by"`
+	OrderBy    string `query:"order_by"`
+}
+
+// TransferRequest moves Amount out of FromAccountID into ToAccountID.
+// ToAmount is what arrives and is required when the two accounts have
+// different currencies; otherwise it defaults to Amount.
+type TransferRequest struct {
+	FromAccountID string       `json:"from_account_id" validate:"required,uuid"`
+	ToAccountID   string       `json:"to_account_id" validate:"required,uuid"`
+	Amount        money.Amount `json:"amount" validate:"required,gt=0" swaggertype:"number" example:"100"`
+	ToAmount      money.Amount `json:"to_amount,omitempty" validate:"omitempty,gt=0" swaggertype:"number" example:"1550000"`
+	Note          string       `json:"note,omitempty"`
+	Date          string       `json:"date" validate:"required,datetime=2006-01-02"`
+}
+
+// TransferResponse holds both legs of a transfer: an expense on the source
+// account and an income on the destination.
+type TransferResponse struct {
+	TransferID uuid.UUID           `json:"transfer_id"`
+	Debit      *entity.Transaction `json:"debit"`
+	Credit     *entity.Transaction `json:"credit"`
+}
+
+// CategoryBreakdownParams selects the transactions to break down, dated
+// StartDate to EndDate inclusive. Without dates it covers the current month
+// in the user's timezone.
+type CategoryBreakdownParams struct {
+	Type      string `query:"type" validate:"omitempty,oneof=income expense"`
+	StartDate string `query:"start_date" validate:"required_with=EndDate,omitempty,datetime=2006-01-02"`
+	EndDate   string `query:"end_date" validate:"required_with=StartDate,omitempty,datetime=2006-01-02"`
+}
+
+// CategoryTotal is what one category adds up to. Split transactions count
+// towards each of their splits' categories with the split's amount.
+// CategoryID is empty for uncategorized transactions and splits.
+type CategoryTotal struct {
+	CategoryID      string       `json:"category_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
+	Name            string       `json:"name" example:"Food"`
+	TransactionType string       `json:"transaction_type" example:"expense"`
+	Total           money.Amount `json:"total" swaggertype:"number"`
+	Transactions    int          `json:"transactions"`
+}
+
+// CategoryBreakdownResponse totals the transactions from StartDate to EndDate
+// per category and type, in Currency, the user's base currency, largest
+// first. UnconvertedCurrencies lists currencies left out of the totals
+// because no exchange rate was known for them.
+type CategoryBreakdownResponse struct {
+	StartDate             string          `json:"start_date" example:"2025-01-01"`
+	EndDate               string          `json:"end_date" example:"2025-01-31"`
+	Currency              string          `json:"currency" example:"IDR"`
+	UnconvertedCurrencies []string        `json:"unconverted_currencies,omitempty"`
+	Categories            []CategoryTotal `json:"categories"`
 }
 
 type PaginatedTransactionsResponse struct {
@@ -52,9 +123,21 @@ type PaginatedTransactionsResponse struct {
 	TotalPage   int                   `json:"total_page"`
 }
 
+// SummaryTransactionResponse totals the periods containing Date, which is
+// today in the user's timezone. Totals are in Currency, the user's base
+// currency; UnconvertedCurrencies lists currencies left out of the totals
+// because no exchange rate was known for them.
 type SummaryTransactionResponse struct {
-	TotalIncomeMonthly  float64 `json:"total_income_monthly"`
-	TotalExpenseMonthly float64 `json:"total_expense_monthly"`
-	TotalIncomeDaily    float64 `json:"total_income_daily"`
-	TotalExpenseDaily   float64 `json:"total_expense_daily"`
-}
\ No newline at end of file
+	Date                  string       `json:"date" example:"2025-01-31"`
+	Timezone              string       `json:"timezone" example:"Asia/Jakarta"`
+	Currency              string       `json:"currency" example:"IDR"`
+	UnconvertedCurrencies []string     `json:"unconverted_currencies,omitempty"`
+	TotalIncomeMonthly    money.Amount `json:"total_income_monthly" swaggertype:"number"`
+	TotalExpenseMonthly   money.Amount `json:"total_expense_monthly" swaggertype:"number"`
+	TotalIncomeDaily      money.Amount `json:"total_income_daily" swaggertype:"number"`
+	TotalExpenseDaily     money.Amount `json:"total_expense_daily" swaggertype:"number"`
+	TotalIncomeWeekly     money.Amount `json:"total_income_weekly" swaggertype:"number"`
+	TotalExpenseWeekly    money.Amount `json:"total_expense_weekly" swaggertype:"number"`
+	TotalIncomeYearly     money.Amount `json:"total_income_yearly" swaggertype:"number"`
+	TotalExpenseYearly    money.Amount `json:"total_expense_yearly" swaggertype:"number"`
+}
@@ -2,19 +2,53 @@ package entity
 
 import (
 	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/pkg/money"
 	"time"
 )
 
 type Transaction struct {
-	ID              uuid.UUID `json:"id"`
-	UserID          uuid.UUID `json:"user_id"`
-	CategoryID      string    `json:"category_id" swaggertype:"string" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
-	TransactionType string    `json:"transaction_type"` // e.g., "income" or "expense"
-	Amount          float64   `json:"amount"`
-	Period          string    `json:"period"`
-	Note            string    `json:"note"`
-	Date            string    `json:"date"`
-	ProofFile       string   `json:"proof_file,omitempty"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID              uuid.UUID    `json:"id"`
+	UserID          uuid.UUID    `json:"user_id"`
+	AccountID       uuid.UUID    `json:"account_id"`
+	TransferID      *uuid.UUID   `json:"transfer_id,omitempty"`  // shared by both legs of a transfer
+	RecurringID     *uuid.UUID   `json:"recurring_id,omitempty"` // the recurring transaction that created it
+	CategoryID      string       `json:"category_id" swaggertype:"string" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
+	Splits          []Split      `json:"splits,omitempty"`
+	TransactionType string       `json:"transaction_type"` // e.g., "income" or "expense"
+	Amount          money.Amount `json:"amount" swaggertype:"number" example:"150000.50"`
+	Currency        string       `json:"currency" example:"IDR"` // ISO 4217
+	Period          string       `json:"period"`
+	Note            string       `json:"note"`
+	Date            string       `json:"date"`
+	ProofFile       string       `json:"proof_file,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+
+	// CategoryAmount is the part of Amount in the category the list was
+	// filtered by: all of it, or the matching splits.
+	CategoryAmount money.Amount `json:"category_amount,omitempty" swaggertype:"number"`
+}
+
+// Split is the part of a transaction that belongs to one category. The
+// splits of a transaction add up to its Amount, and a split transaction has
+// no CategoryID of its own.
+type Split struct {
+	ID         uuid.UUID    `json:"id"`
+	CategoryID string       `json:"category_id" swaggertype:"string" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
+	Amount     money.Amount `json:"amount" swaggertype:"number" example:"50000"`
+	Note       string       `json:"note,omitempty"`
+}
+
+// SplitTotal adds up the amounts of the splits, which are never negative. It
+// fails with money.ErrRange once the total exceeds money.Max, well before the
+// sum could overflow.
+func SplitTotal(splits []Split) (money.Amount, error) {
+	var total money.Amount
+	for _, split := range splits {
+		if split.Amount > money.Max-total {
+			return 0, money.ErrRange
+		}
+		total += split.Amount
+	}
+	return total, nil
 }
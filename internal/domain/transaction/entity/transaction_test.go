@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kenziehh/cashflow-be/pkg/money"
+)
+
+func TestSplitTotal(t *testing.T) {
+	tests := []struct {
+		name    string
+		amounts []money.Amount
+		want    money.Amount
+		wantErr error
+	}{
+		{name: "no splits", want: 0},
+		{name: "two splits", amounts: []money.Amount{5000, 2550}, want: 7550},
+		{name: "up to the maximum", amounts: []money.Amount{money.Max - 1, 1}, want: money.Max},
+		{name: "over the maximum", amounts: []money.Amount{money.Max, 1}, wantErr: money.ErrRange},
+		// Ten maximal splits would wrap around int64 without the check
+		{name: "would overflow", amounts: []money.Amount{money.Max, money.Max, money.Max, money.Max, money.Max, money.Max, money.Max, money.Max, money.Max, money.Max}, wantErr: money.ErrRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			splits := make([]Split, 0, len(tt.amounts))
+			for _, amount := range tt.amounts {
+				splits = append(splits, Split{Amount: amount})
+			}
+
+			got, err := SplitTotal(splits)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SplitTotal error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SplitTotal = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -60,8 +60,10 @@ func (h *TransactionHandler) CreateTransaction(c *fiber.Ctx) error {
 			field := err.Field()
 			tag := err.Tag()
 			switch tag {
-			case "required":
+			case "required", "required_without":
 				validationErrors = append(validationErrors, fmt.Sprintf("%s is required", field))
+			case "excluded_with":
+				validationErrors = append(validationErrors, fmt.Sprintf("%s cannot be combined with Splits", field))
 			case "oneof":
 				validationErrors = append(validationErrors, fmt.Sprintf("%s must be one of the allowed values", field))
 			case "datetime":
@@ -101,6 +103,43 @@ func (h *TransactionHandler) CreateTransaction(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(response.SuccessResponse("Transaction created successfully", result))
 }
 
+// CreateTransfer godoc
+// @Summary Transfer between accounts
+// @Description Move money between two of the authenticated user's accounts. Both legs are created together and are left out of income and expense totals.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param request body dto.TransferRequest true "Transfer request"
+// @Success 201 {object} response.Response{data=dto.TransferResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Security BearerAuth
+// @Router /transactions/transfers [post]
+func (h *TransactionHandler) CreateTransfer(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	var req dto.TransferRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errx.NewBadRequestError("Invalid request body")
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.CreateTransfer(c.Context(), userID, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response.SuccessResponse("Transfer created successfully", result))
+}
+
 // GetTransactionByID godoc
 // @Summary Get transaction by ID
 // @Description Get a transaction by its ID for the authenticated user
@@ -239,7 +278,7 @@ func (h *TransactionHandler) UpdateTransaction(c *fiber.Ctx) error {
 
 // DeleteTransaction godoc
 // @Summary Delete a transaction
-// @Description Delete a transaction by its ID for the authenticated user
+// @Description Delete a transaction by its ID for the authenticated user. Deleting either leg of a transfer deletes both.
 // @Tags transactions
 // @Accept json
 // @Produce json
@@ -292,6 +331,8 @@ func (h *TransactionHandler) DeleteTransaction(c *fiber.Ctx) error {
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Number of items per page" default(10)
 // @Param sort_by query string false "Field to sort by" Enums(date, amount, created_at) default(date)
+// @Param account_id query string false "Only transactions of this account"
+// @Param category_id query string false "Only transactions in this category, including split transactions with a split in it"
 // @Param order query string false "Sort order" Enums(asc, desc) default(desc)
 // @Success 200 {object} response.Response{data=dto.PaginatedTransactionsResponse}
 // @Failure 400 {object} response.Response
@@ -385,6 +426,7 @@ func (h *TransactionHandler) GetProofFile(c *fiber.Ctx) error {
 // @Failure 500 {object} response.Response
 // @Security BearerAuth
 // @Router /transactions/summary [get]
+// @Router /reports/summary [get]
 func (h *TransactionHandler) GetSummaryTransaction(c *fiber.Ctx) error {
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
@@ -398,3 +440,41 @@ func (h *TransactionHandler) GetSummaryTransaction(c *fiber.Ctx) error {
 
 	return c.JSON(response.SuccessResponse("Transaction summary retrieved successfully", result))
 }
+
+// GetCategoryBreakdown godoc
+// @Summary Get totals per category
+// @Description Total income and expenses per category for a date range, the current month by default. Split transactions count towards each split's category.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param type query string false "Only this transaction type" Enums(income, expense)
+// @Param start_date query string false "First day, YYYY-MM-DD"
+// @Param end_date query string false "Last day, YYYY-MM-DD"
+// @Success 200 {object} response.Response{data=dto.CategoryBreakdownResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Security BearerAuth
+// @Router /reports/categories [get]
+func (h *TransactionHandler) GetCategoryBreakdown(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return errx.NewUnauthorizedError("Invalid user ID")
+	}
+
+	var params dto.CategoryBreakdownParams
+	if err := c.QueryParser(&params); err != nil {
+		return errx.NewBadRequestError("Invalid query parameters")
+	}
+
+	if err := h.validate.Struct(params); err != nil {
+		return errx.NewBadRequestError(err.Error())
+	}
+
+	result, err := h.service.GetCategoryBreakdown(c.Context(), userID, params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response.SuccessResponse("Category breakdown retrieved successfully", result))
+}
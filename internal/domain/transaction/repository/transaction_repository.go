@@ -6,21 +6,27 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	"github.com/kenziehh/cashflow-be/internal/domain/transaction/dto"
 	"github.com/kenziehh/cashflow-be/internal/domain/transaction/entity"
 	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/lib/pq"
 )
 
 type TransactionRepository interface {
 	CreateTransaction(ctx context.Context, tx *entity.Transaction) error
+	CreateTransfer(ctx context.Context, debit, credit *entity.Transaction) error
 	GetTransactionByID(ctx context.Context, id string) (*entity.Transaction, error)
 	UpdateTransaction(ctx context.Context, tx *entity.Transaction) error
 	DeleteTransaction(ctx context.Context, id string) error
+	DeleteTransfer(ctx context.Context, transferID uuid.UUID) error
+	ListProofFiles(ctx context.Context, userID uuid.UUID) ([]string, error)
 	GetTransactionsWithPagination(ctx context.Context, userID uuid.UUID, filter dto.TransactionListParams) (dto.PaginatedTransactionsResponse, error)
 	GetSummaryTransaction(ctx context.Context, userID uuid.UUID) (dto.SummaryTransactionResponse, error)
+	GetCategoryBreakdown(ctx context.Context, userID uuid.UUID, params dto.CategoryBreakdownParams) (dto.CategoryBreakdownResponse, error)
 }
 
 type transactionRepository struct {
@@ -35,16 +41,70 @@ func NewTransactionRepository(db *sql.DB, redis *redis.Client) TransactionReposi
 	}
 }
 
+// CreateTransaction stores the transaction together with its splits.
 func (r *transactionRepository) CreateTransaction(ctx context.Context, tx *entity.Transaction) error {
+	dbTx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	defer dbTx.Rollback()
+
+	if err := insertTransaction(ctx, dbTx, tx); err != nil {
+		log.Println("[DB ERROR]:", err)
+		return errx.ErrDatabaseError
+	}
+
+	if err := insertSplits(ctx, dbTx, tx); err != nil {
+		log.Printf("[DB ERROR] CreateTransaction failed to store splits: %v\n", err)
+		return errx.ErrDatabaseError
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// CreateTransfer stores both legs of a transfer or neither.
+func (r *transactionRepository) CreateTransfer(ctx context.Context, debit, credit *entity.Transaction) error {
+	dbTx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	defer dbTx.Rollback()
+
+	for _, leg := range []*entity.Transaction{debit, credit} {
+		if err := insertTransaction(ctx, dbTx, leg); err != nil {
+			log.Printf("[DB ERROR] CreateTransfer failed: %v\n", err)
+			return errx.ErrDatabaseError
+		}
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertTransaction(ctx context.Context, db execer, tx *entity.Transaction) error {
 	query := `
-		INSERT INTO transactions (id, user_id, amount, type, category_id, note, period, date, proof_file, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO transactions (id, user_id, account_id, transfer_id, amount, currency, type, category_id, note, period, date, proof_file, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11, $12, $13, $14)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := db.ExecContext(ctx, query,
 		tx.ID,
 		tx.UserID,
+		tx.AccountID,
+		tx.TransferID,
 		tx.Amount,
+		tx.Currency,
 		tx.TransactionType,
 		tx.CategoryID,
 		tx.Note,
@@ -54,8 +114,63 @@ func (r *transactionRepository) CreateTransaction(ctx context.Context, tx *entit
 		tx.CreatedAt,
 		tx.UpdatedAt,
 	)
+	return err
+}
+
+func insertSplits(ctx context.Context, db execer, tx *entity.Transaction) error {
+	query := `
+		INSERT INTO transaction_splits (id, transaction_id, category_id, amount, note, position)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	for i, split := range tx.Splits {
+		_, err := db.ExecContext(ctx, query, split.ID, tx.ID, split.CategoryID, split.Amount, split.Note, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSplits fills in the splits of the given transactions.
+func (r *transactionRepository) loadSplits(ctx context.Context, transactions []*entity.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*entity.Transaction, len(transactions))
+	ids := make([]string, 0, len(transactions))
+	for _, tx := range transactions {
+		byID[tx.ID] = tx
+		ids = append(ids, tx.ID.String())
+	}
+
+	query := `
+		SELECT transaction_id, id, COALESCE(category_id, ''), amount, note
+		FROM transaction_splits
+		WHERE transaction_id = ANY($1::uuid[])
+		ORDER BY transaction_id, position
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
 	if err != nil {
-		log.Println("[DB ERROR]:", err)
+		log.Printf("[DB ERROR] loadSplits failed: %v\n", err)
+		return errx.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transactionID uuid.UUID
+		var split entity.Split
+		if err := rows.Scan(&transactionID, &split.ID, &split.CategoryID, &split.Amount, &split.Note); err != nil {
+			return errx.ErrDatabaseError
+		}
+		if tx, ok := byID[transactionID]; ok {
+			tx.Splits = append(tx.Splits, split)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
 		return errx.ErrDatabaseError
 	}
 
@@ -64,7 +179,7 @@ func (r *transactionRepository) CreateTransaction(ctx context.Context, tx *entit
 
 func (r *transactionRepository) GetTransactionByID(ctx context.Context, id string) (*entity.Transaction, error) {
 	query := `
-		SELECT id, user_id, amount, type, category_id, note, date, proof_file, created_at, updated_at, period
+		SELECT id, user_id, account_id, transfer_id, recurring_id, amount, currency, type, COALESCE(category_id, ''), note, date, proof_file, created_at, updated_at, period
 		FROM transactions
 		WHERE id = $1
 	`
@@ -74,7 +189,11 @@ func (r *transactionRepository) GetTransactionByID(ctx context.Context, id strin
 	err := row.Scan(
 		&tx.ID,
 		&tx.UserID,
+		&tx.AccountID,
+		&tx.TransferID,
+		&tx.RecurringID,
 		&tx.Amount,
+		&tx.Currency,
 		&tx.TransactionType,
 		&tx.CategoryID,
 		&tx.Note,
@@ -94,17 +213,28 @@ func (r *transactionRepository) GetTransactionByID(ctx context.Context, id strin
 		return nil, errx.ErrDatabaseError
 	}
 
+	if err := r.loadSplits(ctx, []*entity.Transaction{tx}); err != nil {
+		return nil, err
+	}
+
 	return tx, nil
 }
 
+// UpdateTransaction saves the transaction and replaces its splits.
 func (r *transactionRepository) UpdateTransaction(ctx context.Context, tx *entity.Transaction) error {
+	dbTx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+	defer dbTx.Rollback()
+
 	query := `
 		UPDATE transactions
-		SET amount = $1, type = $2, category_id = $3, note = $4, date = $5, updated_at = $6, proof_file = $8
+		SET amount = $1, type = $2, category_id = NULLIF($3, ''), note = $4, date = $5, updated_at = $6, proof_file = $8, currency = $9, account_id = $10
 		WHERE id = $7
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err = dbTx.ExecContext(ctx, query,
 		tx.Amount,
 		tx.TransactionType,
 		tx.CategoryID,
@@ -113,12 +243,27 @@ func (r *transactionRepository) UpdateTransaction(ctx context.Context, tx *entit
 		tx.UpdatedAt,
 		tx.ID,
 		tx.ProofFile,
+		tx.Currency,
+		tx.AccountID,
 	)
 
 	if err != nil {
 		return errx.ErrDatabaseError
 	}
 
+	if _, err := dbTx.ExecContext(ctx, `DELETE FROM transaction_splits WHERE transaction_id = $1`, tx.ID); err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	if err := insertSplits(ctx, dbTx, tx); err != nil {
+		log.Printf("[DB ERROR] UpdateTransaction failed to store splits: %v\n", err)
+		return errx.ErrDatabaseError
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return errx.ErrDatabaseError
+	}
+
 	return nil
 }
 
@@ -136,6 +281,52 @@ func (r *transactionRepository) DeleteTransaction(ctx context.Context, id string
 	return nil
 }
 
+// DeleteTransfer deletes both legs of a transfer.
+func (r *transactionRepository) DeleteTransfer(ctx context.Context, transferID uuid.UUID) error {
+	query := `
+		DELETE FROM transactions
+		WHERE transfer_id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, transferID)
+	if err != nil {
+		return errx.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// ListProofFiles returns the stored proof file paths of the user's
+// transactions.
+func (r *transactionRepository) ListProofFiles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	query := `
+		SELECT proof_file
+		FROM transactions
+		WHERE user_id = $1 AND COALESCE(proof_file, '') <> ''
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	files := []string{}
+	for rows.Next() {
+		var file string
+		if err := rows.Scan(&file); err != nil {
+			return nil, errx.ErrDatabaseError
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.ErrDatabaseError
+	}
+
+	return files, nil
+}
+
 func (r *transactionRepository) GetTransactionsWithPagination(
 	ctx context.Context,
 	userID uuid.UUID,
@@ -144,35 +335,53 @@ func (r *transactionRepository) GetTransactionsWithPagination(
 
 	// fmt.Println("Filter received in repository:", filter)
 
-	query := `
-		SELECT id, user_id, amount, type, category_id, note, date, created_at, updated_at, proof_file, period
-		FROM transactions
-		WHERE user_id = $1
-	`
-
+	where := " WHERE user_id = $1"
 	args := []interface{}{userID}
 	paramIndex := 2
 
 	// Filter tanggal
 	if filter.StartDate != "" && filter.EndDate != "" {
-		query += fmt.Sprintf(" AND date >= $%d AND date <= $%d", paramIndex, paramIndex+1)
+		where += fmt.Sprintf(" AND date >= $%d AND date <= $%d", paramIndex, paramIndex+1)
 		args = append(args, filter.StartDate, filter.EndDate)
 		paramIndex += 2
 	}
 
 	// Filter type
 	if filter.Type != "" {
-		query += fmt.Sprintf(" AND type = $%d", paramIndex)
+		where += fmt.Sprintf(" AND type = $%d", paramIndex)
 		args = append(args, filter.Type)
 		paramIndex++
 	}
 
 	if filter.Period != "" {
-		query += fmt.Sprintf(" AND period = $%d", paramIndex)
+		where += fmt.Sprintf(" AND period = $%d", paramIndex)
 		args = append(args, filter.Period)
 		paramIndex++
 	}
 
+	if filter.AccountID != "" {
+		where += fmt.Sprintf(" AND account_id = $%d", paramIndex)
+		args = append(args, filter.AccountID)
+		paramIndex++
+	}
+
+	// A split transaction matches a category through any of its splits, and
+	// only the amount of those splits is attributed to the category
+	categoryAmount := "0"
+	if filter.CategoryID != "" {
+		splitAmount := fmt.Sprintf("SELECT SUM(s.amount) FROM transaction_splits s WHERE s.transaction_id = transactions.id AND s.category_id = $%d", paramIndex)
+		where += fmt.Sprintf(" AND (category_id = $%d OR EXISTS (%s))", paramIndex, splitAmount)
+		categoryAmount = fmt.Sprintf("CASE WHEN category_id = $%d THEN amount ELSE (%s) END", paramIndex, splitAmount)
+		args = append(args, filter.CategoryID)
+		paramIndex++
+	}
+	filterArgs := args
+
+	query := `
+		SELECT id, user_id, account_id, transfer_id, recurring_id, amount, currency, type, COALESCE(category_id, ''), note, date, created_at, updated_at, proof_file, period,
+			` + categoryAmount + ` AS category_amount
+		FROM transactions` + where
+
 	// Sort
 	validSortColumns := map[string]bool{
 		"date":       true,
@@ -182,6 +391,9 @@ func (r *transactionRepository) GetTransactionsWithPagination(
 	if !validSortColumns[filter.SortBy] {
 		filter.SortBy = "date"
 	}
+	if filter.SortBy == "amount" && filter.CategoryID != "" {
+		filter.SortBy = "category_amount"
+	}
 
 	order := strings.ToUpper(filter.OrderBy)
 	if order != "ASC" && order != "DESC" {
@@ -207,7 +419,11 @@ func (r *transactionRepository) GetTransactionsWithPagination(
 		err := rows.Scan(
 			&tx.ID,
 			&tx.UserID,
+			&tx.AccountID,
+			&tx.TransferID,
+			&tx.RecurringID,
 			&tx.Amount,
+			&tx.Currency,
 			&tx.TransactionType,
 			&tx.CategoryID,
 			&tx.Note,
@@ -216,6 +432,7 @@ func (r *transactionRepository) GetTransactionsWithPagination(
 			&tx.UpdatedAt,
 			&tx.ProofFile,
 			&tx.Period,
+			&tx.CategoryAmount,
 		)
 		if err != nil {
 			return dto.PaginatedTransactionsResponse{}, errx.ErrDatabaseError
@@ -227,9 +444,13 @@ func (r *transactionRepository) GetTransactionsWithPagination(
 		return dto.PaginatedTransactionsResponse{}, errx.ErrDatabaseError
 	}
 
+	if err := r.loadSplits(ctx, transactions); err != nil {
+		return dto.PaginatedTransactionsResponse{}, err
+	}
+
 	var total int
-	countQuery := `SELECT COUNT(*) FROM transactions WHERE user_id = $1`
-	err = r.db.QueryRowContext(ctx, countQuery, userID).Scan(&total)
+	countQuery := `SELECT COUNT(*) FROM transactions` + where
+	err = r.db.QueryRowContext(ctx, countQuery, filterArgs...).Scan(&total)
 	if err != nil {
 		return dto.PaginatedTransactionsResponse{}, errx.ErrDatabaseError
 	}
@@ -244,29 +465,188 @@ func (r *transactionRepository) GetTransactionsWithPagination(
 	return response, nil
 }
 
+// GetSummaryTransaction totals the current day, week, month and year as seen
+// in the user's timezone, with weeks starting on the user's week start; a week
+// spanning New Year counts all of its days. Every amount is
+// converted into the user's base currency at the rate of its transaction date;
+// amounts without a known rate are left out and their currencies reported.
+// Transfers between the user's accounts are neither income nor expense and are
+// not counted.
 func (r *transactionRepository) GetSummaryTransaction(ctx context.Context, userID uuid.UUID) (dto.SummaryTransactionResponse, error) {
 	query := `
+	WITH pref AS (
+		SELECT
+			timezone,
+			currency,
+			(now() AT TIME ZONE timezone)::date AS today,
+			CASE week_start WHEN 'sunday' THEN 0 WHEN 'saturday' THEN 6 ELSE 1 END AS week_dow
+		FROM users
+		WHERE id = $1
+	), period AS (
+		SELECT timezone, currency, today, today - ((EXTRACT(DOW FROM today)::int - week_dow + 7) % 7) AS week_start
+		FROM pref
+	), tx AS (
+		SELECT
+			p.timezone,
+			p.currency AS base_currency,
+			p.today,
+			p.week_start,
+			t.type,
+			t.date,
+			t.currency,
+			ROUND(t.amount * exchange_rate(t.currency, p.currency, t.date), 2) AS amount
+		FROM period p
+		LEFT JOIN transactions t
+			ON t.user_id = $1 AND t.transfer_id IS NULL
+			AND t.date BETWEEN LEAST(date_trunc('year', p.today)::date, p.week_start)
+				AND GREATEST((date_trunc('year', p.today) + INTERVAL '1 year - 1 day')::date, p.week_start + 6)
+	)
 	SELECT
-		COALESCE(SUM(CASE WHEN type = 'income' AND EXTRACT(MONTH FROM date) = EXTRACT(MONTH FROM CURRENT_DATE) THEN amount END), 0) AS total_income_monthly,
-		COALESCE(SUM(CASE WHEN type = 'expense' AND EXTRACT(MONTH FROM date) = EXTRACT(MONTH FROM CURRENT_DATE) THEN amount END), 0) AS total_expense_monthly,
-		COALESCE(SUM(CASE WHEN type = 'income' AND date = CURRENT_DATE THEN amount END), 0) AS total_income_daily,
-		COALESCE(SUM(CASE WHEN type = 'expense' AND date = CURRENT_DATE THEN amount END), 0) AS total_expense_daily
-	FROM transactions
-	WHERE user_id = $1
+		timezone,
+		base_currency,
+		today,
+		COALESCE(SUM(CASE WHEN type = 'income' AND date_trunc('month', date) = date_trunc('month', today) THEN amount END), 0) AS total_income_monthly,
+		COALESCE(SUM(CASE WHEN type = 'expense' AND date_trunc('month', date) = date_trunc('month', today) THEN amount END), 0) AS total_expense_monthly,
+		COALESCE(SUM(CASE WHEN type = 'income' AND date = today THEN amount END), 0) AS total_income_daily,
+		COALESCE(SUM(CASE WHEN type = 'expense' AND date = today THEN amount END), 0) AS total_expense_daily,
+		COALESCE(SUM(CASE WHEN type = 'income' AND date BETWEEN week_start AND week_start + 6 THEN amount END), 0) AS total_income_weekly,
+		COALESCE(SUM(CASE WHEN type = 'expense' AND date BETWEEN week_start AND week_start + 6 THEN amount END), 0) AS total_expense_weekly,
+		COALESCE(SUM(CASE WHEN type = 'income' AND date_trunc('year', date) = date_trunc('year', today) THEN amount END), 0) AS total_income_yearly,
+		COALESCE(SUM(CASE WHEN type = 'expense' AND date_trunc('year', date) = date_trunc('year', today) THEN amount END), 0) AS total_expense_yearly,
+		ARRAY_REMOVE(ARRAY_AGG(DISTINCT CASE WHEN type IS NOT NULL AND amount IS NULL THEN currency END), NULL) AS unconverted
+	FROM tx
+	GROUP BY timezone, base_currency, today
 	`
 
 	var summary dto.SummaryTransactionResponse
+	var today time.Time
 	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&summary.Timezone,
+		&summary.Currency,
+		&today,
 		&summary.TotalIncomeMonthly,
 		&summary.TotalExpenseMonthly,
 		&summary.TotalIncomeDaily,
 		&summary.TotalExpenseDaily,
+		&summary.TotalIncomeWeekly,
+		&summary.TotalExpenseWeekly,
+		&summary.TotalIncomeYearly,
+		&summary.TotalExpenseYearly,
+		pq.Array(&summary.UnconvertedCurrencies),
 	)
 
+	if err == sql.ErrNoRows {
+		return dto.SummaryTransactionResponse{}, errx.ErrUserNotFound
+	}
+
 	if err != nil {
 		log.Printf("[DB ERROR] GetSummaryTransaction failed: %v\n", err)
 		return dto.SummaryTransactionResponse{}, errx.ErrDatabaseError
 	}
 
+	summary.Date = today.Format("2006-01-02")
 	return summary, nil
 }
+
+// GetCategoryBreakdown totals the user's income and expenses per category.
+// A split transaction counts towards each split's category with the split's
+// amount. Amounts are converted into the user's base currency as in
+// GetSummaryTransaction, and transfers are not counted.
+func (r *transactionRepository) GetCategoryBreakdown(ctx context.Context, userID uuid.UUID, params dto.CategoryBreakdownParams) (dto.CategoryBreakdownResponse, error) {
+	query := `
+	WITH pref AS (
+		SELECT
+			currency,
+			COALESCE(NULLIF($2, '')::date, date_trunc('month', (now() AT TIME ZONE timezone)::date)::date) AS start_date,
+			COALESCE(NULLIF($3, '')::date, (date_trunc('month', (now() AT TIME ZONE timezone)::date) + INTERVAL '1 month - 1 day')::date) AS end_date
+		FROM users
+		WHERE id = $1
+	), lines AS (
+		SELECT
+			t.id,
+			t.type,
+			t.currency,
+			COALESCE(s.category_id, t.category_id) AS category_id,
+			ROUND(COALESCE(s.amount, t.amount) * exchange_rate(t.currency, p.currency, t.date), 2) AS amount
+		FROM pref p
+		JOIN transactions t
+			ON t.user_id = $1 AND t.transfer_id IS NULL AND t.date BETWEEN p.start_date AND p.end_date
+		LEFT JOIN transaction_splits s ON s.transaction_id = t.id
+		WHERE $4 = '' OR t.type::text = $4
+	)
+	SELECT
+		p.start_date,
+		p.end_date,
+		p.currency,
+		COALESCE(l.category_id, ''),
+		COALESCE(c.name, ''),
+		l.type,
+		COALESCE(SUM(l.amount), 0) AS total,
+		COUNT(DISTINCT l.id) AS transactions,
+		ARRAY_REMOVE(ARRAY_AGG(DISTINCT CASE WHEN l.amount IS NULL THEN l.currency END), NULL) AS unconverted
+	FROM pref p
+	LEFT JOIN lines l ON TRUE
+	LEFT JOIN categories c ON c.id = l.category_id
+	GROUP BY p.start_date, p.end_date, p.currency, l.category_id, c.name, l.type
+	ORDER BY total DESC, c.name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, params.StartDate, params.EndDate, params.Type)
+	if err != nil {
+		log.Printf("[DB ERROR] GetCategoryBreakdown failed: %v\n", err)
+		return dto.CategoryBreakdownResponse{}, errx.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	breakdown := dto.CategoryBreakdownResponse{Categories: []dto.CategoryTotal{}}
+	unconverted := map[string]bool{}
+	found := false
+	for rows.Next() {
+		var startDate, endDate time.Time
+		var transactionType sql.NullString
+		var total dto.CategoryTotal
+		var currencies []string
+		err := rows.Scan(
+			&startDate,
+			&endDate,
+			&breakdown.Currency,
+			&total.CategoryID,
+			&total.Name,
+			&transactionType,
+			&total.Total,
+			&total.Transactions,
+			pq.Array(&currencies),
+		)
+		if err != nil {
+			log.Printf("[DB ERROR] GetCategoryBreakdown failed: %v\n", err)
+			return dto.CategoryBreakdownResponse{}, errx.ErrDatabaseError
+		}
+
+		found = true
+		breakdown.StartDate = startDate.Format("2006-01-02")
+		breakdown.EndDate = endDate.Format("2006-01-02")
+		for _, currency := range currencies {
+			if !unconverted[currency] {
+				unconverted[currency] = true
+				breakdown.UnconvertedCurrencies = append(breakdown.UnconvertedCurrencies, currency)
+			}
+		}
+
+		// The user has no transactions in the range
+		if !transactionType.Valid {
+			continue
+		}
+		total.TransactionType = transactionType.String
+		breakdown.Categories = append(breakdown.Categories, total)
+	}
+
+	if err := rows.Err(); err != nil {
+		return dto.CategoryBreakdownResponse{}, errx.ErrDatabaseError
+	}
+
+	if !found {
+		return dto.CategoryBreakdownResponse{}, errx.ErrUserNotFound
+	}
+
+	return breakdown, nil
+}
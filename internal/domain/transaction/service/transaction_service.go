@@ -2,13 +2,19 @@ package service
 
 import (
 	"context"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	accountEntity "github.com/kenziehh/cashflow-be/internal/domain/account/entity"
+	accountService "github.com/kenziehh/cashflow-be/internal/domain/account/service"
+	auditService "github.com/kenziehh/cashflow-be/internal/domain/audit/service"
 	"github.com/kenziehh/cashflow-be/internal/domain/transaction/dto"
 	"github.com/kenziehh/cashflow-be/internal/domain/transaction/entity"
 	"github.com/kenziehh/cashflow-be/internal/domain/transaction/repository"
 	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/money"
 )
 
 type TransactionService interface {
@@ -16,29 +22,44 @@ type TransactionService interface {
 	GetTransactionByID(ctx context.Context, id uuid.UUID) (*entity.Transaction, error)
 	UpdateTransaction(ctx context.Context, id uuid.UUID, req dto.UpdateTransactionRequest,proofFilePath string) (*entity.Transaction, error)
 	DeleteTransaction(ctx context.Context, id uuid.UUID) error
+	CreateTransfer(ctx context.Context, userID uuid.UUID, req dto.TransferRequest) (*dto.TransferResponse, error)
 	GetTransactionsWithPagination(ctx context.Context, userID uuid.UUID, params dto.TransactionListParams) (dto.PaginatedTransactionsResponse, error)
 	GetSummaryTransaction(ctx context.Context, userID uuid.UUID) (dto.SummaryTransactionResponse, error)
+	GetCategoryBreakdown(ctx context.Context, userID uuid.UUID, params dto.CategoryBreakdownParams) (dto.CategoryBreakdownResponse, error)
+	ListProofFiles(ctx context.Context, userID uuid.UUID) ([]string, error)
 }
 
 type transactionService struct {
-	repo repository.TransactionRepository
+	repo     repository.TransactionRepository
+	accounts accountService.AccountService
+	audit    auditService.AuditService
 }
 
-func NewTransactionService(repo repository.TransactionRepository) TransactionService {
+func NewTransactionService(repo repository.TransactionRepository, accounts accountService.AccountService, audit auditService.AuditService) TransactionService {
 	return &transactionService{
-		repo: repo,
+		repo:     repo,
+		accounts: accounts,
+		audit:    audit,
 	}
 }
 
 func (s *transactionService) CreateTransaction(ctx context.Context, req dto.CreateTransactionRequest, userID uuid.UUID, proofPath string) (*entity.Transaction, error) {
+	account, err := s.accounts.ResolveAccount(ctx, userID, req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 
 	tx := &entity.Transaction{
 		ID:              uuid.New(),
 		UserID:          userID,
+		AccountID:       account.ID,
 		TransactionType: req.TransactionType,
 		Amount:          req.Amount,
+		Currency:        req.Currency,
 		CategoryID:      req.CategoryID,
+		Splits:          newSplits(req.Splits),
 		Period:          req.Period,
 		Note:            req.Note,
 		Date:            req.Date,
@@ -47,10 +68,26 @@ func (s *transactionService) CreateTransaction(ctx context.Context, req dto.Crea
 		UpdatedAt:       now,
 	}
 
+	if tx.Currency == "" {
+		tx.Currency = account.Currency
+	}
+
+	if err := checkSplitTotal(tx); err != nil {
+		return nil, err
+	}
+
 	if err := s.repo.CreateTransaction(ctx, tx); err != nil {
 		return nil, err
 	}
 
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     tx.UserID,
+		Action:     auditService.ActionTransactionCreated,
+		TargetType: auditService.TargetTransaction,
+		TargetID:   tx.ID.String(),
+		After:      tx,
+	})
+
 	return tx, nil
 }
 
@@ -75,15 +112,40 @@ func (s *transactionService) UpdateTransaction(ctx context.Context, id uuid.UUID
 		return nil, errx.ErrTransactionNotFound
 	}
 
+	if tx.TransferID != nil {
+		return nil, errx.ErrTransferLegReadOnly
+	}
+
+	before := *tx
+
+	if req.AccountID != "" {
+		account, err := s.accounts.ResolveAccount(ctx, tx.UserID, req.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		tx.AccountID = account.ID
+	}
+
 	// Update fields (hanya jika ada perubahan)
 	if req.Amount != 0 {
 		tx.Amount = req.Amount
 	}
+	if req.Currency != "" {
+		tx.Currency = req.Currency
+	}
 	if req.TransactionType != "" {
 		tx.TransactionType = req.TransactionType
 	}
-	if req.CategoryID != "" {
+	// A transaction has either a category or splits
+	if len(req.Splits) > 0 {
+		tx.CategoryID = ""
+		tx.Splits = newSplits(req.Splits)
+	} else if req.CategoryID != "" {
 		tx.CategoryID = req.CategoryID
+		tx.Splits = nil
+	}
+	if err := checkSplitTotal(tx); err != nil {
+		return nil, err
 	}
 	if req.Note != "" {
 		tx.Note = req.Note
@@ -103,10 +165,50 @@ func (s *transactionService) UpdateTransaction(ctx context.Context, id uuid.UUID
 		return nil, err
 	}
 
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     tx.UserID,
+		Action:     auditService.ActionTransactionUpdated,
+		TargetType: auditService.TargetTransaction,
+		TargetID:   tx.ID.String(),
+		Before:     before,
+		After:      tx,
+	})
+
 	return tx, nil
 }
 
 
+// newSplits gives each requested split an ID.
+func newSplits(req []dto.SplitRequest) []entity.Split {
+	if len(req) == 0 {
+		return nil
+	}
+
+	splits := make([]entity.Split, 0, len(req))
+	for _, split := range req {
+		splits = append(splits, entity.Split{
+			ID:         uuid.New(),
+			CategoryID: split.CategoryID,
+			Amount:     split.Amount,
+			Note:       split.Note,
+		})
+	}
+	return splits
+}
+
+// checkSplitTotal makes sure a split transaction's splits add up to its
+// amount.
+func checkSplitTotal(tx *entity.Transaction) error {
+	if len(tx.Splits) == 0 {
+		return nil
+	}
+	total, err := entity.SplitTotal(tx.Splits)
+	if err != nil || total != tx.Amount {
+		return errx.ErrSplitAmountMismatch
+	}
+	return nil
+}
+
 func (s *transactionService) DeleteTransaction(ctx context.Context, id uuid.UUID) error {
 	tx, err := s.repo.GetTransactionByID(ctx, id.String())
 	if err != nil {
@@ -116,13 +218,100 @@ func (s *transactionService) DeleteTransaction(ctx context.Context, id uuid.UUID
 		return errx.ErrTransactionNotFound
 	}
 
+	// Deleting either leg of a transfer deletes the whole transfer
+	if tx.TransferID != nil {
+		if err := s.repo.DeleteTransfer(ctx, *tx.TransferID); err != nil {
+			return err
+		}
+
+		s.recordAudit(ctx, auditService.Event{
+			UserID:     tx.UserID,
+			Action:     auditService.ActionTransferDeleted,
+			TargetType: auditService.TargetTransfer,
+			TargetID:   tx.TransferID.String(),
+			Before:     tx,
+		})
+		return nil
+	}
+
 	if err := s.repo.DeleteTransaction(ctx, id.String()); err != nil {
 		return err
 	}
 
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     tx.UserID,
+		Action:     auditService.ActionTransactionDeleted,
+		TargetType: auditService.TargetTransaction,
+		TargetID:   tx.ID.String(),
+		Before:     tx,
+	})
 	return nil
 }
 
+// CreateTransfer records a transfer between two of the user's accounts as an
+// expense on the source and an income on the destination, each in its
+// account's currency.
+func (s *transactionService) CreateTransfer(ctx context.Context, userID uuid.UUID, req dto.TransferRequest) (*dto.TransferResponse, error) {
+	if strings.EqualFold(req.FromAccountID, req.ToAccountID) {
+		return nil, errx.ErrSameTransferAccount
+	}
+
+	from, err := s.accounts.ResolveAccount(ctx, userID, req.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.accounts.ResolveAccount(ctx, userID, req.ToAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	toAmount := req.ToAmount
+	if toAmount == 0 {
+		if from.Currency != to.Currency {
+			return nil, errx.ErrTransferAmountRequired
+		}
+		toAmount = req.Amount
+	}
+
+	now := time.Now()
+	transferID := uuid.New()
+	leg := func(account *accountEntity.Account, transactionType string, amount money.Amount) *entity.Transaction {
+		return &entity.Transaction{
+			ID:              uuid.New(),
+			UserID:          userID,
+			AccountID:       account.ID,
+			TransferID:      &transferID,
+			TransactionType: transactionType,
+			Amount:          amount,
+			Currency:        account.Currency,
+			Note:            req.Note,
+			Date:            req.Date,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+	}
+
+	transfer := &dto.TransferResponse{
+		TransferID: transferID,
+		Debit:      leg(from, "expense", req.Amount),
+		Credit:     leg(to, "income", toAmount),
+	}
+
+	if err := s.repo.CreateTransfer(ctx, transfer.Debit, transfer.Credit); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, auditService.Event{
+		UserID:     userID,
+		Action:     auditService.ActionTransferCreated,
+		TargetType: auditService.TargetTransfer,
+		TargetID:   transferID.String(),
+		After:      transfer,
+	})
+
+	return transfer, nil
+}
+
 func (s *transactionService) GetTransactionsWithPagination(ctx context.Context, userID uuid.UUID, params dto.TransactionListParams) (dto.PaginatedTransactionsResponse, error) {
 	txs, err := s.repo.GetTransactionsWithPagination(ctx, userID, params)
 	if err != nil {
@@ -139,4 +328,26 @@ func (s *transactionService) GetSummaryTransaction(ctx context.Context, userID u
 		return dto.SummaryTransactionResponse{}, err
 	}
 	return summary, nil
-}
\ No newline at end of file
+}
+
+func (s *transactionService) GetCategoryBreakdown(ctx context.Context, userID uuid.UUID, params dto.CategoryBreakdownParams) (dto.CategoryBreakdownResponse, error) {
+	if params.StartDate > params.EndDate {
+		return dto.CategoryBreakdownResponse{}, errx.ErrInvalidDateRange
+	}
+	return s.repo.GetCategoryBreakdown(ctx, userID, params)
+}
+
+// recordAudit writes the audit entry of a change the repository has already
+// committed. Failing the request then would only invite a duplicate retry,
+// so failures are logged instead.
+func (s *transactionService) recordAudit(ctx context.Context, event auditService.Event) {
+	if err := s.audit.Record(ctx, event); err != nil {
+		log.Printf("[AUDIT ERROR] %s %s: %v", event.Action, event.TargetID, err)
+	}
+}
+
+// ListProofFiles returns the proof files of every transaction of the user, so
+// that erasing the account can remove them from disk.
+func (s *transactionService) ListProofFiles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return s.repo.ListProofFiles(ctx, userID)
+}
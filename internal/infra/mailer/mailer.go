@@ -0,0 +1,99 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kenziehh/cashflow-be/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// InitMailer picks the mail driver from config: "smtp" delivers real mail,
+// anything else writes messages to the log (and MAIL_LOG_FILE when set).
+func InitMailer(cfg *config.Config) Mailer {
+	if cfg.MailDriver == "smtp" {
+		log.Printf("Mailer using SMTP %s:%s", cfg.SMTPHost, cfg.SMTPPort)
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+
+	log.Println("Mailer using log driver")
+	return NewLogMailer(cfg.MailLogFile)
+}
+
+type smtpMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) Mailer {
+	return &smtpMailer{
+		addr:     host + ":" + port,
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	body := strings.Join([]string{
+		"From: " + m.from,
+		"To: " + msg.To,
+		"Subject: " + msg.Subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=\"UTF-8\"",
+		"",
+		msg.Body,
+	}, "\r\n")
+
+	return smtp.SendMail(m.addr, auth, m.from, []string{msg.To}, []byte(body))
+}
+
+type logMailer struct {
+	path string
+}
+
+func NewLogMailer(path string) Mailer {
+	return &logMailer{path: path}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg Message) error {
+	entry := fmt.Sprintf("[%s] To: %s | Subject: %s\n%s\n\n",
+		time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+
+	if m.path == "" {
+		log.Printf("[MAIL] %s", entry)
+		return nil
+	}
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(entry)
+	return err
+}
@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/kenziehh/cashflow-be/config"
+	"github.com/kenziehh/cashflow-be/internal/infra/mailer"
+)
+
+// Notification is a security notice for one user.
+type Notification struct {
+	UserID  uuid.UUID
+	Email   string
+	Subject string
+	Body    string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// InitNotifier picks the driver from config: "mail" emails the user through
+// m, anything else only logs the notification.
+func InitNotifier(cfg *config.Config, m mailer.Mailer) Notifier {
+	if cfg.NotifierDriver == "mail" {
+		log.Println("Notifier using mail driver")
+		return NewMailNotifier(m)
+	}
+
+	log.Println("Notifier using log driver")
+	return NewLogNotifier()
+}
+
+type mailNotifier struct {
+	mailer mailer.Mailer
+}
+
+func NewMailNotifier(m mailer.Mailer) Notifier {
+	return &mailNotifier{mailer: m}
+}
+
+func (n *mailNotifier) Notify(ctx context.Context, notification Notification) error {
+	return n.mailer.Send(ctx, mailer.Message{
+		To:      notification.Email,
+		Subject: notification.Subject,
+		Body:    notification.Body,
+	})
+}
+
+type logNotifier struct{}
+
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+func (n *logNotifier) Notify(ctx context.Context, notification Notification) error {
+	log.Printf("[NOTIFY] user %s <%s> | %s\n%s", notification.UserID, notification.Email, notification.Subject, notification.Body)
+	return nil
+}
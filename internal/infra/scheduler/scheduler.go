@@ -0,0 +1,30 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job is one run of a periodic task. A failed run is logged and retried on
+// the next tick, so jobs must be safe to repeat.
+type Job func(ctx context.Context) error
+
+// Every runs job right away and then once per interval until ctx is done. It
+// blocks, so start it in its own goroutine.
+func Every(ctx context.Context, name string, interval time.Duration, job Job) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(ctx); err != nil {
+			log.Printf("[SCHEDULER ERROR] %s: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
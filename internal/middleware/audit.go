@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/kenziehh/cashflow-be/pkg/audit"
+)
+
+// AuditContext stores the caller's IP and user agent so audit entries
+// written further down the request can include them.
+func AuditContext() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(audit.ClientKey, audit.Client{
+			IP:        c.IP(),
+			UserAgent: c.Get(fiber.HeaderUserAgent),
+		})
+		return c.Next()
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"runtime/debug"
+	"strconv"
 
 	"github.com/kenziehh/cashflow-be/pkg/errx"
 	"github.com/kenziehh/cashflow-be/pkg/response"
@@ -25,6 +27,9 @@ func ErrorHandler(c *fiber.Ctx, err error) error {
 		code = appErr.Code
 		message = appErr.Message
 		log.Printf("[AppError] %s | Status: %d | Path: %s", appErr.Message, appErr.Code, c.Path())
+		if appErr.RetryAfter > 0 {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(appErr.RetryAfter.Seconds()))))
+		}
 		return c.Status(code).JSON(response.ErrorResponse(message))
 	}
 
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	auditService "github.com/kenziehh/cashflow-be/internal/domain/audit/service"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+)
+
+// AuditImpersonation records every request made with an impersonation token,
+// refused ones included, in the impersonated user's audit log. It must be
+// registered globally so it sees the Locals JWTAuth sets further down.
+func AuditImpersonation(audit auditService.AuditService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		if _, ok := c.Locals("actorID").(uuid.UUID); !ok {
+			return err
+		}
+
+		recordErr := audit.Record(c.Context(), auditService.Event{
+			Action: auditService.ActionImpersonatedRequest,
+			Metadata: map[string]any{
+				"method": c.Method(),
+				"path":   c.Path(),
+				"status": responseStatus(c, err),
+			},
+		})
+		if recordErr != nil {
+			log.Printf("[AUDIT ERROR] impersonated request %s %s: %v", c.Method(), c.Path(), recordErr)
+		}
+
+		return err
+	}
+}
+
+// responseStatus is the status ErrorHandler will send for err.
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	if appErr, ok := errx.IsAppError(err); ok {
+		return appErr.Code
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
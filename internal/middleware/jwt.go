@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"strings"
 
 	"github.com/google/uuid"
@@ -10,7 +11,25 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func JWTAuth() fiber.Handler {
+// TokenRevocationStore reports whether an otherwise valid access token has
+// been revoked, either individually, by bumping the user's token version or by
+// ending the session it belongs to.
+type TokenRevocationStore interface {
+	IsTokenBlacklisted(ctx context.Context, token string) (bool, error)
+	GetTokenVersion(ctx context.Context, userID uuid.UUID) (int64, error)
+	TouchSession(ctx context.Context, sessionID string, ip string) (bool, error)
+}
+
+// APIKeyAuthenticator resolves a personal access token to its owner and
+// granted scopes.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (uuid.UUID, []string, error)
+}
+
+// JWTAuth authenticates bearer access tokens. When apiKeys is non-nil it also
+// accepts API keys, which are told apart from JWTs by the missing dots; pass
+// nil for routes that must only be reachable from an interactive session.
+func JWTAuth(store TokenRevocationStore, apiKeys APIKeyAuthenticator) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -23,6 +42,22 @@ func JWTAuth() fiber.Handler {
 		}
 
 		token := parts[1]
+		if apiKeys != nil && !strings.Contains(token, ".") {
+			userUUID, scopes, err := apiKeys.AuthenticateAPIKey(c.Context(), token)
+			if err != nil {
+				return err
+			}
+
+			// Keys can only be created once the email policy allows writes, and
+			// they never carry a role, so admin routes stay out of reach.
+			c.Locals("userID", userUUID)
+			c.Locals("sessionID", "")
+			c.Locals("emailVerified", true)
+			c.Locals("role", "")
+			c.Locals("scopes", scopes)
+			return c.Next()
+		}
+
 		claims, err := jwt.ValidateToken(token)
 		if err != nil {
 			return errx.ErrInvalidBearerToken
@@ -33,7 +68,62 @@ func JWTAuth() fiber.Handler {
 			return errx.ErrInvalidUserIDFormat
 		}
 
+		blacklisted, err := store.IsTokenBlacklisted(c.Context(), token)
+		if err != nil {
+			return err
+		}
+		if blacklisted {
+			return errx.ErrTokenRevoked
+		}
+
+		version, err := store.GetTokenVersion(c.Context(), userUUID)
+		if err != nil {
+			return err
+		}
+		if claims.Generation < version {
+			return errx.ErrTokenRevoked
+		}
+
+		// Impersonation tokens end with the actor's tokens and can only read.
+		// actorID is set before the method check so refused writes are still
+		// audited.
+		if claims.Actor != nil {
+			actorUUID, err := uuid.Parse(claims.Actor.Subject)
+			if err != nil {
+				return errx.ErrInvalidBearerToken
+			}
+
+			actorVersion, err := store.GetTokenVersion(c.Context(), actorUUID)
+			if err != nil {
+				return err
+			}
+			if claims.Actor.Generation < actorVersion {
+				return errx.ErrTokenRevoked
+			}
+
+			c.Locals("userID", userUUID)
+			c.Locals("actorID", actorUUID)
+			switch c.Method() {
+			case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			default:
+				return errx.ErrImpersonationReadOnly
+			}
+		}
+
+		if claims.SessionID != "" {
+			active, err := store.TouchSession(c.Context(), claims.SessionID, c.IP())
+			if err != nil {
+				return err
+			}
+			if !active {
+				return errx.ErrTokenRevoked
+			}
+		}
+
 		c.Locals("userID", userUUID)
+		c.Locals("sessionID", claims.SessionID)
+		c.Locals("emailVerified", claims.EmailVerified)
+		c.Locals("role", claims.Role)
 		return c.Next()
 	}
 }
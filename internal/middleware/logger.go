@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 func Logger() fiber.Handler {
@@ -37,6 +38,11 @@ func Logger() fiber.Handler {
 			path,
 		)
 
+		// Tag requests made by an admin acting as another user
+		if actorID, ok := c.Locals("actorID").(uuid.UUID); ok {
+			logMsg += fmt.Sprintf(" | impersonated by %s as %v", actorID, c.Locals("userID"))
+		}
+
 		log.Println(logMsg)
 
 		return err
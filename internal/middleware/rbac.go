@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+	"github.com/kenziehh/cashflow-be/pkg/rbac"
+)
+
+// RequireRole allows the request when the user has one of roles. It must run
+// after JWTAuth.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		for _, r := range roles {
+			if role == r {
+				return c.Next()
+			}
+		}
+		return errx.ErrForbidden
+	}
+}
+
+// RequirePermission allows the request when the user's role grants perm in
+// the rbac permission matrix. It must run after JWTAuth.
+func RequirePermission(perm rbac.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		if !rbac.HasPermission(role, perm) {
+			return errx.ErrForbidden
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+)
+
+// RequireScope limits API key requests to the scopes granted to the key: safe
+// methods need read, everything else needs write. An empty scope denies that
+// kind of request to API keys altogether. Session tokens are not scoped.
+func RequireScope(read, write string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		scopes, ok := c.Locals("scopes").([]string)
+		if !ok {
+			return c.Next()
+		}
+
+		needed := write
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			needed = read
+		}
+
+		if needed != "" {
+			for _, s := range scopes {
+				if s == needed {
+					return c.Next()
+				}
+			}
+		}
+		return errx.ErrInsufficientScope
+	}
+}
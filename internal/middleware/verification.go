@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/kenziehh/cashflow-be/pkg/errx"
+)
+
+const (
+	UnverifiedAccessFull     = "full"
+	UnverifiedAccessReadOnly = "read_only"
+	UnverifiedAccessNone     = "none"
+)
+
+// EmailVerificationPolicy restricts users whose email is not verified yet.
+// It must run after JWTAuth. With "read_only" only safe methods are allowed,
+// with "none" every request is rejected.
+func EmailVerificationPolicy(policy string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if verified, _ := c.Locals("emailVerified").(bool); verified {
+			return c.Next()
+		}
+
+		switch policy {
+		case UnverifiedAccessFull:
+			return c.Next()
+		case UnverifiedAccessNone:
+			return errx.ErrEmailNotVerified
+		default:
+			switch c.Method() {
+			case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+				return c.Next()
+			}
+			return errx.ErrEmailNotVerified
+		}
+	}
+}
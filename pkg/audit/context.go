@@ -0,0 +1,49 @@
+// Package audit carries who made a request through to the code that writes
+// audit log entries.
+package audit
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type clientKey struct{}
+
+// Client is the device a request comes from.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// ClientKey is the fiber Locals key the client is stored under. Handlers pass
+// c.Context() to services, whose Value method reads Locals.
+var ClientKey = clientKey{}
+
+// WithClient attaches client to ctx, for callers outside a fiber request.
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, ClientKey, client)
+}
+
+// ClientFromContext returns the client set by the audit middleware.
+func ClientFromContext(ctx context.Context) Client {
+	client, _ := ctx.Value(ClientKey).(Client)
+	return client
+}
+
+// ActorFromContext returns who is making the request, as stored by JWTAuth:
+// the admin behind an impersonation token ("actorID" Locals key), otherwise
+// the authenticated user ("userID").
+func ActorFromContext(ctx context.Context) (uuid.UUID, bool) {
+	if actorID, ok := ctx.Value("actorID").(uuid.UUID); ok {
+		return actorID, true
+	}
+	return UserFromContext(ctx)
+}
+
+// UserFromContext returns the user the request acts on behalf of, which is
+// the impersonated user for impersonation tokens.
+func UserFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value("userID").(uuid.UUID)
+	return userID, ok
+}
@@ -3,6 +3,7 @@ package errx
 import (
 	"errors"
 	"net/http"
+	"time"
 )
 
 var (
@@ -14,21 +15,82 @@ var (
 	ErrInvalidBearerToken  = NewUnauthorizedError("Invalid bearer token")
 	ErrInvalidUserIDFormat  = NewUnauthorizedError("Invalid user ID format in token")
 	ErrUnauthorized        = NewUnauthorizedError("Unauthorized")
+	ErrInvalidRefreshToken = NewUnauthorizedError("Invalid refresh token")
+	ErrTokenRevoked        = NewUnauthorizedError("Token has been revoked")
+	ErrSessionNotFound     = NewNotFoundError("Session not found")
+	ErrIncorrectPassword   = NewBadRequestError("Current password is incorrect")
+	ErrPasswordUnchanged   = NewBadRequestError("New password must be different from the current password")
+	ErrInvalidResetToken   = NewBadRequestError("Invalid or expired reset token")
+	ErrInvalidMagicLink    = NewUnauthorizedError("Invalid or expired login link")
+	ErrInvalidVerifyToken  = NewBadRequestError("Invalid or expired verification token")
+	ErrEmailAlreadyVerified = NewConflictError("Email is already verified")
+	ErrEmailNotVerified    = NewForbiddenError("Please verify your email address first")
+	ErrTooManyRequests     = NewTooManyRequestsError("Too many requests, please try again later")
+	ErrTOTPNotEnrolled     = NewNotFoundError("Two-factor authentication is not set up")
+	ErrTOTPAlreadyEnabled  = NewConflictError("Two-factor authentication is already enabled")
+	ErrInvalidMFACode      = NewUnauthorizedError("Invalid two-factor authentication code")
+	ErrInvalidMFAToken     = NewUnauthorizedError("Invalid or expired two-factor challenge")
+	ErrAccountLocked       = NewTooManyRequestsError("Too many failed login attempts, please try again later")
+	ErrForbidden           = NewForbiddenError("Forbidden")
+	ErrRefreshTokenReused  = NewUnauthorizedError("Refresh token reuse detected, please login again")
+	ErrInvalidAPIKey       = NewUnauthorizedError("Invalid or expired API key")
+	ErrInsufficientScope   = NewForbiddenError("API key does not have the required scope")
+	ErrAPIKeyNotFound      = NewNotFoundError("API key not found")
+	ErrOIDCNotConfigured   = NewNotFoundError("OpenID Connect login is not configured")
+	ErrInvalidOIDCState    = NewBadRequestError("Invalid or expired login state")
+	ErrOIDCLoginFailed     = NewUnauthorizedError("OpenID Connect login failed")
+	ErrOIDCEmailNotVerified = NewForbiddenError("Your provider account has no verified email address")
+	ErrIdentityAlreadyLinked = NewConflictError("This account is already linked to another identity from this provider")
+	ErrPasskeysNotConfigured = NewNotFoundError("Passkeys are not configured")
+	ErrInvalidPasskeySession = NewBadRequestError("Passkey request expired, please try again")
+	ErrPasskeyVerificationFailed = NewUnauthorizedError("Passkey verification failed")
+	ErrPasskeyNotFound     = NewNotFoundError("Passkey not found")
+	ErrPasskeyAlreadyRegistered = NewConflictError("This passkey is already registered")
+	ErrNoLocalPassword     = NewBadRequestError("This account has no password, use password reset to set one")
+	ErrRecentLoginRequired = NewForbiddenError("Please sign in again to confirm this change")
+	ErrEmailConfirmationMismatch = NewBadRequestError("Email address does not match this account")
+	ErrAccountDeletionScheduled = NewConflictError("Account deletion is already scheduled")
+	ErrAccountDeletionNotScheduled = NewNotFoundError("Account deletion is not scheduled")
+	ErrImpersonationReadOnly = NewForbiddenError("Impersonation tokens are read-only")
 	ErrDatabaseError       = NewInternalServerError("Database error")
 	ErrRedisError          = NewInternalServerError("Redis error")
 	ErrInternalServer      = NewInternalServerError("Internal server error")
 	ErrTransactionNotFound = NewNotFoundError("Transaction not found")
+	ErrCategoryNotFound    = NewNotFoundError("Category not found")
+	ErrMaximumSpendNotFound = NewNotFoundError("User doesnt set maximum spend yet")
+	ErrAccountNotFound     = NewNotFoundError("Account not found")
+	ErrAccountInUse        = NewConflictError("Account still has transactions or recurring transactions, move or delete them first")
+	ErrSameTransferAccount = NewBadRequestError("Cannot transfer to the same account")
+	ErrTransferAmountRequired = NewBadRequestError("to_amount is required when the accounts have different currencies")
+	ErrTransferLegReadOnly = NewConflictError("Transfers cannot be edited, delete and recreate the transfer instead")
+	ErrRecurringTransactionNotFound = NewNotFoundError("Recurring transaction not found")
+	ErrRecurringTransactionEnded = NewConflictError("Recurring transaction has no more occurrences")
+	ErrRecurringTransactionChanged = NewConflictError("Recurring transaction was just updated, please try again")
+	ErrNotAnOccurrence     = NewBadRequestError("from_date is not an occurrence of this recurring transaction")
+	ErrInvalidDateRange    = NewBadRequestError("end_date must not be before start_date")
+	ErrStartDateOutOfRange = NewBadRequestError("start_date must be at most a year ago and at most ten years ahead")
+	ErrSplitAmountMismatch = NewBadRequestError("Splits must add up to the transaction amount")
 )
 
 type AppError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	// RetryAfter is sent as the Retry-After header when set
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (e *AppError) Error() string {
 	return e.Message
 }
 
+// WithRetryAfter returns a copy of the error that tells the client when to
+// retry. The shared error values are never modified.
+func (e *AppError) WithRetryAfter(d time.Duration) *AppError {
+	clone := *e
+	clone.RetryAfter = d
+	return &clone
+}
+
 func NewBadRequestError(message string) *AppError {
 	return &AppError{
 		Code:    http.StatusBadRequest,
@@ -43,6 +105,13 @@ func NewUnauthorizedError(message string) *AppError {
 	}
 }
 
+func NewForbiddenError(message string) *AppError {
+	return &AppError{
+		Code:    http.StatusForbidden,
+		Message: message,
+	}
+}
+
 func NewNotFoundError(message string) *AppError {
 	return &AppError{
 		Code:    http.StatusNotFound,
@@ -57,6 +126,13 @@ func NewConflictError(message string) *AppError {
 	}
 }
 
+func NewTooManyRequestsError(message string) *AppError {
+	return &AppError{
+		Code:    http.StatusTooManyRequests,
+		Message: message,
+	}
+}
+
 func NewInternalServerError(message string) *AppError {
 	return &AppError{
 		Code:    http.StatusInternalServerError,
@@ -1,52 +1,118 @@
 package jwt
 
 import (
-	"os"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+const AccessTokenTTL = 15 * time.Minute
+
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID        string `json:"user_id"`
+	SessionID     string `json:"sid,omitempty"`
+	Generation    int64  `json:"gen"`
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	// Actor is set on impersonation tokens and names who is really acting
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key"
+// Actor is the "act" claim of RFC 8693. Generation is the actor's own token
+// version, so revoking the actor's tokens also ends their impersonation.
+type Actor struct {
+	Subject    string `json:"sub"`
+	Generation int64  `json:"gen"`
+}
+
+// GenerateToken signs an access token for the given claims. Generation must be
+// the user's current token version; tokens with an older generation are
+// rejected by JWTAuth.
+func GenerateToken(claims *Claims) (string, error) {
+	return generateToken(claims, AccessTokenTTL)
+}
+
+// GenerateImpersonationToken signs an access token that lets claims.Actor act
+// as claims.UserID until ttl has passed.
+func GenerateImpersonationToken(claims *Claims, ttl time.Duration) (string, error) {
+	if claims.Actor == nil {
+		return "", errors.New("jwt: impersonation token without an actor")
+	}
+	return generateToken(claims, ttl)
+}
+
+func generateToken(claims *Claims, ttl time.Duration) (string, error) {
+	kr, err := currentKeyring()
+	if err != nil {
+		return "", err
 	}
 
-	claims := &Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return kr.sign(claims)
 }
 
 func ValidateToken(tokenString string) (*Claims, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key"
+	kr, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, kr.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Purpose tokens carry an audience and must never pass as access tokens
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && len(claims.Audience) == 0 {
+		return claims, nil
+	}
+
+	return nil, jwt.ErrSignatureInvalid
+}
+
+// GeneratePurposeToken signs a short-lived token that is only accepted for one
+// purpose (its audience), such as verifying an email address.
+func GeneratePurposeToken(purpose, subject string, ttl time.Duration) (string, error) {
+	kr, err := currentKeyring()
+	if err != nil {
+		return "", err
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+	now := time.Now()
+	claims := &jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		Subject:   subject,
+		Audience:  jwt.ClaimStrings{purpose},
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+
+	return kr.sign(claims)
+}
+
+func ValidatePurposeToken(tokenString, purpose string) (*jwt.RegisteredClaims, error) {
+	kr, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
 
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, kr.keyFunc, jwt.WithAudience(purpose))
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok && token.Valid {
 		return claims, nil
 	}
 
 	return nil, jwt.ErrSignatureInvalid
-}
\ No newline at end of file
+}
@@ -0,0 +1,258 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	// DefaultKeyID is the kid of the HS256 key built from JWT_SECRET. Tokens
+	// without a kid header are verified against it.
+	DefaultKeyID = "default"
+)
+
+var (
+	ErrUnknownKey      = errors.New("jwt: unknown signing key")
+	ErrKeyCannotSign   = errors.New("jwt: key has no private part")
+	ErrUnsupportedAlg  = errors.New("jwt: unsupported algorithm")
+	ErrKeyringNotReady = errors.New("jwt: keyring not initialized")
+)
+
+// Key is a single signing or verification key identified by its kid. Keys
+// that only have a public part can verify but never sign, which is how
+// retired asymmetric keys are kept around during rotation.
+type Key struct {
+	ID        string
+	Algorithm string
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Algorithm: AlgHS256, signKey: secret, verifyKey: secret}
+}
+
+// ParsePEMKey reads an RS256 or EdDSA key. A private key can sign and verify,
+// a public key can only verify.
+func ParsePEMKey(id, alg string, data []byte) (*Key, error) {
+	key := &Key{ID: id, Algorithm: alg}
+
+	switch alg {
+	case AlgRS256:
+		if priv, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			key.signKey, key.verifyKey = priv, &priv.PublicKey
+			return key, nil
+		}
+		pub, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %s: %w", id, err)
+		}
+		key.verifyKey = pub
+	case AlgEdDSA:
+		if priv, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			edPriv, ok := priv.(ed25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("jwt: key %s: not an Ed25519 key", id)
+			}
+			key.signKey, key.verifyKey = edPriv, edPriv.Public()
+			return key, nil
+		}
+		pub, err := jwt.ParseEdPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %s: %w", id, err)
+		}
+		key.verifyKey = pub
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, alg)
+	}
+
+	return key, nil
+}
+
+// Keyring signs with its active key and verifies with any key it holds.
+type Keyring struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+func NewKeyring(activeID string, keys ...*Key) (*Keyring, error) {
+	kr := &Keyring{keys: make(map[string]*Key, len(keys))}
+	for _, k := range keys {
+		kr.keys[k.ID] = k
+	}
+
+	active, ok := kr.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, activeID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeyCannotSign, activeID)
+	}
+	kr.active = active
+
+	return kr, nil
+}
+
+// LoadKeyring builds a keyring from configuration. secret becomes the HS256
+// key DefaultKeyID; spec is a comma separated list of "kid:alg:pem-path"
+// entries for additional keys.
+func LoadKeyring(secret, activeID, spec string) (*Keyring, error) {
+	var keys []*Key
+	if secret != "" {
+		keys = append(keys, NewHMACKey(DefaultKeyID, []byte(secret)))
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("jwt: invalid key entry %q, expected kid:alg:path", entry)
+		}
+
+		data, err := os.ReadFile(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %s: %w", parts[0], err)
+		}
+
+		var key *Key
+		if parts[1] == AlgHS256 {
+			key = NewHMACKey(parts[0], []byte(strings.TrimSpace(string(data))))
+		} else if key, err = ParsePEMKey(parts[0], parts[1], data); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeyring(activeID, keys...)
+}
+
+func (kr *Keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(kr.active.method(), claims)
+	token.Header["kid"] = kr.active.ID
+	return token.SignedString(kr.active.signKey)
+}
+
+// keyFunc resolves the verification key from the kid header and refuses any
+// algorithm other than the one registered for that key.
+func (kr *Keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = DefaultKeyID
+	}
+
+	key, ok := kr.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, ErrUnsupportedAlg
+	}
+	return key.verifyKey, nil
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public asymmetric keys. HMAC keys are never published.
+func (kr *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, k := range kr.keys {
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: k.ID,
+				Use: "sig",
+				Alg: AlgRS256,
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: k.ID,
+				Use: "sig",
+				Alg: AlgEdDSA,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	return set
+}
+
+var (
+	mu      sync.RWMutex
+	keyring *Keyring
+)
+
+// SetKeyring installs the keyring used by the package level helpers. It is
+// called once at startup.
+func SetKeyring(kr *Keyring) {
+	mu.Lock()
+	defer mu.Unlock()
+	keyring = kr
+}
+
+func currentKeyring() (*Keyring, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if keyring == nil {
+		return nil, ErrKeyringNotReady
+	}
+	return keyring, nil
+}
+
+// PublicJWKS returns the JWKS of the installed keyring.
+func PublicJWKS() JWKSet {
+	kr, err := currentKeyring()
+	if err != nil {
+		return JWKSet{Keys: []JWK{}}
+	}
+	return kr.JWKS()
+}
@@ -0,0 +1,149 @@
+// Package money represents amounts of money exactly, as integer minor units,
+// so that sums never drift the way float64 does.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of decimal places an Amount keeps, matching the
+// NUMERIC(18,2) columns amounts are stored in.
+const Scale = 2
+
+const unit = 100
+
+// Max is the largest amount a NUMERIC(18,2) column can hold.
+const Max Amount = 999_999_999_999_999_999
+
+var (
+	ErrSyntax    = errors.New("money: amount must be a plain decimal number")
+	ErrPrecision = errors.New("money: amount has more than 2 decimal places")
+	ErrRange     = errors.New("money: amount is too large")
+)
+
+// Amount is an amount of money in hundredths of the currency unit. It is
+// encoded in JSON as a number with two decimals ("amount": 150000.50) and
+// decoded from a number or a string without ever going through float64.
+type Amount int64
+
+// Parse reads a decimal such as "150000", "-12.5" or "0.05".
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" || !isDigits(whole) || hasFrac && (frac == "" || !isDigits(frac)) {
+		return 0, ErrSyntax
+	}
+	if len(frac) > Scale {
+		// Trailing zeros do not add precision
+		if strings.TrimRight(frac[Scale:], "0") != "" {
+			return 0, ErrPrecision
+		}
+		frac = frac[:Scale]
+	}
+
+	whole = strings.TrimLeft(whole, "0")
+	if len(whole) > 16 {
+		return 0, ErrRange
+	}
+	units, err := strconv.ParseInt("0"+whole+frac+strings.Repeat("0", Scale-len(frac)), 10, 64)
+	if err != nil || Amount(units) > Max {
+		return 0, ErrRange
+	}
+
+	if negative {
+		units = -units
+	}
+	return Amount(units), nil
+}
+
+// MustParse is Parse for constants; it panics on invalid input.
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the amount with exactly two decimals, e.g. "-12.50".
+func (a Amount) String() string {
+	sign := ""
+	units := int64(a)
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, units/unit, units%unit)
+}
+
+// Units returns the amount in minor units.
+func (a Amount) Units() int64 {
+	return int64(a)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	return a.UnmarshalText([]byte(strings.Trim(s, `"`)))
+}
+
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText also lets form and query decoders read amounts.
+func (a *Amount) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Scan reads a NUMERIC column, which lib/pq returns as text.
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return a.UnmarshalText(v)
+	case string:
+		return a.UnmarshalText([]byte(v))
+	case int64:
+		if v > int64(Max/unit) || v < -int64(Max/unit) {
+			return ErrRange
+		}
+		*a = Amount(v * unit)
+		return nil
+	case nil:
+		return errors.New("money: cannot scan NULL into Amount")
+	default:
+		return fmt.Errorf("money: cannot scan %T into Amount", src)
+	}
+}
+
+// Value sends the amount as decimal text so Postgres stores it exactly.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
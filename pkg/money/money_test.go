@@ -0,0 +1,190 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Amount
+		wantErr error
+	}{
+		{in: "150000", want: 15000000},
+		{in: "150000.5", want: 15000050},
+		{in: "150000.50", want: 15000050},
+		{in: "0.05", want: 5},
+		{in: "-12.5", want: -1250},
+		{in: "-0", want: 0},
+		{in: "007.10", want: 710},
+		{in: " 42 ", want: 4200},
+		{in: "1.2300", want: 123},
+		{in: "9999999999999999.99", want: Max},
+		{in: "-9999999999999999.99", want: -Max},
+
+		// Amounts are never rounded: a third decimal is an error
+		{in: "0.005", wantErr: ErrPrecision},
+		{in: "1.999", wantErr: ErrPrecision},
+		{in: "1.2301", wantErr: ErrPrecision},
+
+		{in: "10000000000000000", wantErr: ErrRange},
+		{in: "99999999999999999.99", wantErr: ErrRange},
+		{in: "9223372036854775807", wantErr: ErrRange},
+
+		{in: "", wantErr: ErrSyntax},
+		{in: "-", wantErr: ErrSyntax},
+		{in: ".5", wantErr: ErrSyntax},
+		{in: "5.", wantErr: ErrSyntax},
+		{in: "+5", wantErr: ErrSyntax},
+		{in: "--5", wantErr: ErrSyntax},
+		{in: "1e3", wantErr: ErrSyntax},
+		{in: "1,000", wantErr: ErrSyntax},
+		{in: "1.2.3", wantErr: ErrSyntax},
+		{in: "NaN", wantErr: ErrSyntax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   Amount
+		want string
+	}{
+		{in: 0, want: "0.00"},
+		{in: 5, want: "0.05"},
+		{in: -5, want: "-0.05"},
+		{in: 1250, want: "12.50"},
+		{in: -1250, want: "-12.50"},
+		{in: Max, want: "9999999999999999.99"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Amount(%d).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     any
+		want    Amount
+		wantErr bool
+	}{
+		{name: "numeric bytes", src: []byte("150000.50"), want: 15000050},
+		{name: "numeric string", src: "-12.50", want: -1250},
+		{name: "integer", src: int64(42), want: 4200},
+		{name: "negative integer", src: int64(-42), want: -4200},
+		{name: "integer out of range", src: int64(1) << 60, wantErr: true},
+		{name: "invalid text", src: []byte("abc"), wantErr: true},
+		{name: "null", src: nil, wantErr: true},
+		{name: "float", src: 1.5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Amount
+			err := got.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan(%v) error = %v, wantErr %v", tt.src, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Scan(%v) = %d, want %d", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	v, err := Amount(-1250).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "-12.50" {
+		t.Errorf("Value = %#v, want \"-12.50\"", v)
+	}
+
+	var back Amount
+	if err := back.Scan(v); err != nil || back != -1250 {
+		t.Errorf("Scan(Value) = %d, %v", back, err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type payload struct {
+		Amount Amount  `json:"amount"`
+		Limit  *Amount `json:"limit,omitempty"`
+	}
+
+	data, err := json.Marshal(payload{Amount: 15000050})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"amount":150000.50}` {
+		t.Errorf("Marshal = %s", data)
+	}
+
+	tests := []struct {
+		in      string
+		want    Amount
+		wantErr bool
+	}{
+		{in: `{"amount":150000.50}`, want: 15000050},
+		{in: `{"amount":"150000.50"}`, want: 15000050},
+		{in: `{"amount":0.1}`, want: 10},
+		{in: `{"amount":-3}`, want: -300},
+		{in: `{"amount":null}`, want: 0},
+		{in: `{"amount":0.001}`, wantErr: true},
+		{in: `{"amount":1e3}`, wantErr: true},
+		{in: `{"amount":"abc"}`, wantErr: true},
+		{in: `{"amount":true}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var got payload
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Amount != tt.want {
+				t.Errorf("Amount = %d, want %d", got.Amount, tt.want)
+			}
+		})
+	}
+
+	// 0.1 + 0.2 is exact, unlike with float64
+	var a, b payload
+	if err := json.Unmarshal([]byte(`{"amount":0.1}`), &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"amount":0.2}`), &b); err != nil {
+		t.Fatal(err)
+	}
+	if sum := a.Amount + b.Amount; sum.String() != "0.30" {
+		t.Errorf("0.1 + 0.2 = %s", sum)
+	}
+}
+
+func TestUnmarshalText(t *testing.T) {
+	var a Amount
+	if err := a.UnmarshalText([]byte("99.99")); err != nil || a != 9999 {
+		t.Errorf("UnmarshalText = %d, %v", a, err)
+	}
+	if err := a.UnmarshalText([]byte("9.999")); !errors.Is(err, ErrPrecision) {
+		t.Errorf("UnmarshalText error = %v, want %v", err, ErrPrecision)
+	}
+	if a != 9999 {
+		t.Error("a failed UnmarshalText changed the amount")
+	}
+}
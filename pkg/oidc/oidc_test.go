@@ -0,0 +1,214 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kenziehh/cashflow-be/pkg/oidc"
+	"github.com/kenziehh/cashflow-be/pkg/oidc/oidctest"
+)
+
+const (
+	clientID    = "cashflow"
+	redirectURL = "http://localhost:3000/auth/oidc/callback"
+	email       = "ada@example.com"
+)
+
+func newProvider(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	t.Helper()
+
+	mock, err := oidctest.NewProvider("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	mock.Issuer = server.URL
+
+	rp := oidc.NewProvider(oidc.Config{
+		Issuer:       server.URL,
+		ClientID:     clientID,
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+	}, server.Client())
+	return mock, rp
+}
+
+// login runs the code flow up to the token response and returns the raw ID
+// token. exchangeVerifier replaces the PKCE verifier when not empty.
+func login(t *testing.T, rp *oidc.Provider, nonce, exchangeVerifier string) (string, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := rp.AuthCodeURL(ctx, "state-1", nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	code, state, err := oidctest.Authorize(authURL, email)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if state != "state-1" {
+		t.Fatalf("state = %q, want state-1", state)
+	}
+
+	if exchangeVerifier != "" {
+		verifier = exchangeVerifier
+	}
+	return rp.Exchange(ctx, code, verifier)
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	_, rp := newProvider(t)
+
+	authURL, err := rp.AuthCodeURL(context.Background(), "s", "n", oidc.CodeChallengeS256("verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             clientID,
+		"redirect_uri":          redirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "s",
+		"nonce":                 "n",
+		"code_challenge":        oidc.CodeChallengeS256("verifier"),
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if got := q.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestCodeChallengeS256(t *testing.T) {
+	// RFC 7636 appendix B
+	got := oidc.CodeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("CodeChallengeS256 = %q, want %q", got, want)
+	}
+}
+
+func TestFlow(t *testing.T) {
+	_, rp := newProvider(t)
+
+	raw, err := login(t, rp, "nonce-1", "")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	idToken, err := rp.VerifyIDToken(context.Background(), raw, "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if idToken.Subject != oidctest.Subject(email) || idToken.Email != email || !idToken.EmailVerified {
+		t.Errorf("unexpected ID token %+v", idToken)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	_, rp := newProvider(t)
+
+	other, err := oidc.NewCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = login(t, rp, "nonce-1", other)
+	if !errors.Is(err, oidc.ErrExchange) {
+		t.Fatalf("Exchange error = %v, want %v", err, oidc.ErrExchange)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		nonce  string
+		claims func(jwt.MapClaims)
+		valid  bool
+	}{
+		{name: "valid", nonce: "nonce-1", valid: true},
+		{name: "nonce mismatch", nonce: "other"},
+		{name: "missing nonce", nonce: "nonce-1", claims: func(c jwt.MapClaims) { delete(c, "nonce") }},
+		{name: "wrong aud", nonce: "nonce-1", claims: func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{
+			name:   "several audiences without azp",
+			nonce:  "nonce-1",
+			claims: func(c jwt.MapClaims) { c["aud"] = []string{clientID, "someone-else"} },
+		},
+		{
+			name:  "several audiences with another azp",
+			nonce: "nonce-1",
+			claims: func(c jwt.MapClaims) {
+				c["aud"] = []string{clientID, "someone-else"}
+				c["azp"] = "someone-else"
+			},
+		},
+		{
+			name:  "several audiences with our azp",
+			nonce: "nonce-1",
+			claims: func(c jwt.MapClaims) {
+				c["aud"] = []string{clientID, "someone-else"}
+				c["azp"] = clientID
+			},
+			valid: true,
+		},
+		{name: "wrong issuer", nonce: "nonce-1", claims: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "expired", nonce: "nonce-1", claims: func(c jwt.MapClaims) { c["exp"] = int64(1) }},
+		{name: "missing sub", nonce: "nonce-1", claims: func(c jwt.MapClaims) { delete(c, "sub") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, rp := newProvider(t)
+			mock.EditClaims = tt.claims
+
+			raw, err := login(t, rp, "nonce-1", "")
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+
+			_, err = rp.VerifyIDToken(context.Background(), raw, tt.nonce)
+			if tt.valid && err != nil {
+				t.Fatalf("VerifyIDToken: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, oidc.ErrInvalidToken) {
+				t.Fatalf("VerifyIDToken error = %v, want %v", err, oidc.ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsForeignKey(t *testing.T) {
+	mock, rp := newProvider(t)
+	other, otherRP := newProvider(t)
+
+	// Another provider's token claiming our issuer, signed with a key that
+	// has the same kid as ours
+	other.EditClaims = func(c jwt.MapClaims) { c["iss"] = mock.Issuer }
+	raw, err := login(t, otherRP, "nonce-1", "")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	if _, err := rp.VerifyIDToken(context.Background(), raw, "nonce-1"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Fatalf("VerifyIDToken error = %v, want %v", err, oidc.ErrInvalidToken)
+	}
+}
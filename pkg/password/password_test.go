@@ -0,0 +1,131 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams keep the tests fast; only the encoding matters here.
+var testParams = Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestHashVerify(t *testing.T) {
+	h := NewArgon2idHasher(testParams)
+
+	hash, err := h.Hash("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("hash %q is not in the PHC format", hash)
+	}
+
+	other, err := h.Hash("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash == other {
+		t.Error("two hashes of the same password share a salt")
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+	}{
+		{name: "right password", password: "correct horse battery", hash: hash, want: true},
+		{name: "wrong password", password: "correct horse battery!", hash: hash},
+		{name: "empty password", password: "", hash: hash},
+		{name: "empty hash", password: "correct horse battery", hash: ""},
+		{name: "other algorithm", password: "correct horse battery", hash: strings.Replace(hash, "argon2id", "argon2i", 1)},
+		{name: "other version", password: "correct horse battery", hash: strings.Replace(hash, "v=19", "v=16", 1)},
+		{name: "zero memory", password: "correct horse battery", hash: strings.Replace(hash, "m=64", "m=0", 1)},
+		{name: "truncated", password: "correct horse battery", hash: hash[:strings.LastIndex(hash, "$")]},
+		{name: "bad salt", password: "correct horse battery", hash: strings.Replace(hash, "p=1$", "p=1$!", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.Verify(tt.password, tt.hash); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeArgon2id(t *testing.T) {
+	h := NewArgon2idHasher(Argon2idParams{Memory: 128, Iterations: 3, Parallelism: 2, SaltLength: 8, KeyLength: 24})
+	hash, err := h.Hash("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Argon2idParams{Memory: 128, Iterations: 3, Parallelism: 2, SaltLength: 8, KeyLength: 24}
+	if params != want {
+		t.Errorf("params = %+v, want %+v", params, want)
+	}
+	if len(salt) != 8 || len(key) != 24 {
+		t.Errorf("salt and key lengths = %d, %d, want 8, 24", len(salt), len(key))
+	}
+}
+
+func TestVerifyBcrypt(t *testing.T) {
+	h := NewArgon2idHasher(testParams)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse battery"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !h.Verify("correct horse battery", string(legacy)) {
+		t.Error("bcrypt hash was not accepted")
+	}
+	if h.Verify("wrong", string(legacy)) {
+		t.Error("bcrypt hash accepted a wrong password")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	h := NewArgon2idHasher(testParams)
+
+	current, err := h.Hash("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weaker, err := NewArgon2idHasher(Argon2idParams{Memory: 32, Iterations: 1, Parallelism: 1}).Hash("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortSalt, err := NewArgon2idHasher(Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 8}).Hash("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse battery"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{name: "current parameters", hash: current},
+		{name: "older parameters", hash: weaker, want: true},
+		{name: "shorter salt", hash: shortSalt, want: true},
+		{name: "bcrypt", hash: string(legacy), want: true},
+		{name: "garbage", hash: "not a hash", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
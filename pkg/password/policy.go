@@ -0,0 +1,83 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// commonPasswords holds the SHA-1 hashes of the most common passwords so the
+// breached check works without a downloaded list.
+//
+//go:embed common_passwords.txt
+var commonPasswords string
+
+var ErrBreached = errors.New("This password has appeared in a data breach, please choose a different one")
+
+// Policy decides whether a new password is acceptable.
+type Policy struct {
+	MinLength int
+	MaxLength int
+	breached  map[[sha1.Size]byte]struct{}
+}
+
+// NewPolicy returns a policy with the built-in common password list. Lengths
+// are counted in characters; a zero MaxLength means no upper bound.
+func NewPolicy(minLength, maxLength int) *Policy {
+	p := &Policy{
+		MinLength: minLength,
+		MaxLength: maxLength,
+		breached:  map[[sha1.Size]byte]struct{}{},
+	}
+	p.loadBreached(strings.NewReader(commonPasswords))
+	return p
+}
+
+// LoadBreachedFile adds the hashes from a file in the Have I Been Pwned
+// format: one uppercase or lowercase SHA-1 hex digest per line, optionally
+// followed by ":count".
+func (p *Policy) LoadBreachedFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return p.loadBreached(f)
+}
+
+func (p *Policy) loadBreached(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+
+		var sum [sha1.Size]byte
+		if n, err := hex.Decode(sum[:], []byte(line)); err != nil || n != sha1.Size {
+			continue
+		}
+		p.breached[sum] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// Validate returns a user facing error when password violates the policy.
+func (p *Policy) Validate(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("Password must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("Password must be at most %d characters long", p.MaxLength)
+	}
+	if _, ok := p.breached[sha1.Sum([]byte(password))]; ok {
+		return ErrBreached
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	p := NewPolicy(8, 16)
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  string
+	}{
+		{name: "acceptable", password: "plum-tiger-91"},
+		{name: "exactly minimum", password: "kq7#vz2m"},
+		{name: "exactly maximum", password: "kq7#vz2mkq7#vz2m"},
+		{name: "too short", password: "kq7#vz2", wantErr: "at least 8"},
+		{name: "too long", password: "kq7#vz2mkq7#vz2mx", wantErr: "at most 16"},
+		{name: "multibyte counted as characters", password: "ééééééé", wantErr: "at least 8"},
+		{name: "multibyte within limits", password: "éééééééé"},
+		{name: "common password", password: "password", wantErr: ErrBreached.Error()},
+		{name: "common password with digits", password: "12345678", wantErr: ErrBreached.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Validate(tt.password)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyWithoutMaximum(t *testing.T) {
+	p := NewPolicy(8, 0)
+	if err := p.Validate(strings.Repeat("kq7#vz2m", 100)); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func TestLoadBreachedFile(t *testing.T) {
+	// Digests in either case, with or without a count; other lines are skipped
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	list := "" +
+		"not a hash\n" +
+		"  " + strings.ToUpper(sha1Hex("hunter-two-22")) + "  \n" +
+		sha1Hex("plum-tiger-91") + ":3\n"
+	if err := os.WriteFile(path, []byte(list), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPolicy(8, 0)
+	if err := p.LoadBreachedFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, password := range []string{"plum-tiger-91", "hunter-two-22"} {
+		if err := p.Validate(password); !errors.Is(err, ErrBreached) {
+			t.Errorf("Validate(%q) = %v, want %v", password, err, ErrBreached)
+		}
+	}
+	// The built-in list still applies
+	if err := p.Validate("password"); !errors.Is(err, ErrBreached) {
+		t.Errorf("Validate(password) = %v, want %v", err, ErrBreached)
+	}
+	if err := p.Validate("an unlisted passphrase"); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	if err := p.LoadBreachedFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadBreachedFile accepted a missing file")
+	}
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
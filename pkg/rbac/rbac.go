@@ -0,0 +1,43 @@
+package rbac
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+type Permission string
+
+const (
+	PermManageCategories Permission = "categories:manage"
+	PermManageUsers      Permission = "users:manage"
+	PermUnlockAccounts   Permission = "accounts:unlock"
+	PermViewAuditLogs    Permission = "audit_logs:view"
+	PermImpersonateUsers Permission = "users:impersonate"
+)
+
+// matrix lists what each role may do beyond working with its own data,
+// which every authenticated user can.
+var matrix = map[string][]Permission{
+	RoleUser: {},
+	RoleAdmin: {
+		PermManageCategories,
+		PermManageUsers,
+		PermUnlockAccounts,
+		PermViewAuditLogs,
+		PermImpersonateUsers,
+	},
+}
+
+func IsValidRole(role string) bool {
+	_, ok := matrix[role]
+	return ok
+}
+
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range matrix[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
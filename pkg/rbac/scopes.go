@@ -0,0 +1,34 @@
+package rbac
+
+// API key scopes. A scope is "<resource>:<read|write>"; write does not imply
+// read.
+const (
+	ScopeTransactionsRead  = "transactions:read"
+	ScopeTransactionsWrite = "transactions:write"
+	ScopeReportsRead       = "reports:read"
+	ScopeCategoriesRead    = "categories:read"
+	ScopeLimitsRead        = "limits:read"
+	ScopeLimitsWrite       = "limits:write"
+	ScopeAccountsRead      = "accounts:read"
+	ScopeAccountsWrite     = "accounts:write"
+)
+
+var AllScopes = []string{
+	ScopeTransactionsRead,
+	ScopeTransactionsWrite,
+	ScopeReportsRead,
+	ScopeCategoriesRead,
+	ScopeLimitsRead,
+	ScopeLimitsWrite,
+	ScopeAccountsRead,
+	ScopeAccountsWrite,
+}
+
+func IsValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}